@@ -0,0 +1,92 @@
+package Preprocessor
+
+import "log"
+
+// BACKBONES
+// The backbone of a satisfiable problem is the set of lits that are true in all its models.
+// Each lit of the backbone is implied by the clauses, so it can be added as a unit, but finding them needs a complete solver:
+// l is in the backbone iff the problem is UNSAT under the assumption -l.
+// Candidates are the lits of a first model; each new model found removes the candidates it falsifies,
+// and candidates whose negation fails by propagation are kept without calling the solver.
+
+// A Solver is a complete SAT solver, e.g gini, used by Backbones.
+type Solver interface {
+	// Add adds a clause to the solver.
+	Add(lits []Lit)
+	// Solve returns whether the clauses added so far are satisfiable under the given assumptions.
+	Solve(assumptions []Lit) bool
+	// Value returns the value of lit in the model found by the last call to Solve, which must have returned true.
+	Value(lit Lit) bool
+}
+
+// Backbones adds every lit of the backbone of the problem as a unit. solver must not contain any clause yet.
+// If solver proves the problem is UNSAT, it becomes Unsat.
+// Backbones found by the solver have no LRAT justification, so nothing is done while an LRAT proof is written.
+func (pb *Problem) Backbones(solver Solver) {
+	if pb.Status != Undetermined || pb.proof() != nil || pb.Options.Interpolation || pb.skipped("backbones") {
+		return
+	}
+	log.Printf("Computing backbones... %d clauses currently", len(pb.Clauses))
+	for _, lit := range pb.Units {
+		solver.Add([]Lit{lit})
+	}
+	occurs := make([]bool, pb.NbVars)
+	for _, c := range pb.Clauses {
+		solver.Add(c.lits)
+		for _, lit := range c.lits {
+			occurs[lit.Var()] = true
+		}
+	}
+	if !solver.Solve(nil) {
+		log.Printf("Inferred UNSAT")
+		pb.derived(NewClause([]Lit{}), "backbone")
+		pb.Status = Unsat
+		return
+	}
+	// Vars that appear in no clause are free, so they are not in the backbone
+	candidates := make([]Lit, 0)
+	for v, ok := range occurs {
+		if !ok || pb.Model[v] != 0 {
+			continue
+		}
+		if lit := Var(v).Lit(); solver.Value(lit) {
+			candidates = append(candidates, lit)
+		} else {
+			candidates = append(candidates, lit.Negation())
+		}
+	}
+	dropped := make([]bool, len(candidates)) // Candidates falsified by a model
+	p := pb.propagator()
+	nbUnits, nbCalls := len(pb.Units), 0
+	for i, lit := range candidates {
+		if pb.Status != Undetermined {
+			break
+		}
+		if dropped[i] || pb.Model[lit.Var()] != 0 {
+			continue
+		}
+		p.sync(pb)
+		failed := !p.assume(lit.Negation()) || p.propagate() != noClause
+		p.backtrack()
+		if failed {
+			pb.derivedUnitRUP(lit, "backbone")
+			pb.addUnit(lit)
+			solver.Add([]Lit{lit})
+			continue
+		}
+		nbCalls++
+		if !solver.Solve([]Lit{lit.Negation()}) {
+			pb.derivedUnit(lit, "backbone")
+			pb.addUnit(lit)
+			solver.Add([]Lit{lit})
+			continue
+		}
+		for j := i + 1; j < len(candidates); j++ {
+			if !solver.Value(candidates[j]) {
+				dropped[j] = true
+			}
+		}
+	}
+	log.Printf("%d backbone lits found with %d solver calls", len(pb.Units)-nbUnits, nbCalls)
+	pb.Simplify2()
+}
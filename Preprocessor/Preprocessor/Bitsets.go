@@ -0,0 +1,97 @@
+package Preprocessor
+
+import "math/bits"
+
+// DENSE PROBLEMS
+// Problems with few vars but many clauses spend most of their subsumption time comparing short lists of lits.
+// For them, each clause also gets a bitset of its lits, and Subsumes and SelfSubsumes become a few word operations.
+// Bitsets are only built by the passes that check subsumption, and are dropped as soon as the lits of a clause change:
+// clauses without a bitset are compared through their lits, as usual.
+
+// denseMaxVars is the max number of vars of a problem whose clauses are given bitsets.
+const denseMaxVars = 256
+
+// evenBits has the bit of every positive lit set.
+const evenBits = 0x5555555555555555
+
+// dense is true iff the clauses of the problem are given bitsets.
+func (pb *Problem) dense() bool {
+	return pb.NbVars <= denseMaxVars
+}
+
+// useBitsets gives a bitset to the clauses of the problem that do not have one, if the problem is dense.
+func (pb *Problem) useBitsets() {
+	if !pb.dense() {
+		return
+	}
+	nbWords := (2*pb.NbVars + 63) / 64
+	for _, c := range pb.Clauses {
+		if c.bits == nil {
+			pb.memory().charge(8 * nbWords)
+			c.bits = litBits(c.lits, nbWords)
+		}
+	}
+}
+
+// litBits returns the bitset of the given lits, made of nbWords words.
+func litBits(lits []Lit, nbWords int) []uint64 {
+	res := make([]uint64, nbWords)
+	for _, lit := range lits {
+		res[lit/64] |= 1 << (uint(lit) % 64)
+	}
+	return res
+}
+
+// sameBits is true iff the bitset of c holds exactly the lits of c.
+func (c *Clause) sameBits() bool {
+	for _, lit := range c.lits {
+		if int(lit/64) >= len(c.bits) {
+			return false
+		}
+	}
+	expected := litBits(c.lits, len(c.bits))
+	for i, w := range c.bits {
+		if w != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// word returns the ith word of the bitset b, which is 0 past its end.
+func word(b []uint64, i int) uint64 {
+	if i >= len(b) {
+		return 0
+	}
+	return b[i]
+}
+
+// subsetBits is true iff every lit of a is in b.
+func subsetBits(a, b []uint64) bool {
+	for i, w := range a {
+		if w&^word(b, i) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// selfSubsetBits is true iff every lit of a but one is in b, and the negation of that lit is in b.
+func selfSubsetBits(a, b []uint64) bool {
+	missing := -1
+	for i, w := range a {
+		if w &^= word(b, i); w != 0 {
+			if missing != -1 || bits.OnesCount64(w) != 1 {
+				return false
+			}
+			missing = i
+		}
+	}
+	if missing == -1 {
+		return false
+	}
+	// A lit and its negation share a word, since words hold an even number of lits.
+	w := a[missing] &^ word(b, missing)
+	neg := (w&evenBits)<<1 | (w>>1)&evenBits
+	return neg&word(b, missing) != 0
+}
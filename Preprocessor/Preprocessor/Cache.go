@@ -0,0 +1,132 @@
+package Preprocessor
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// PREPROCESSING CACHE
+// Parameter sweeps run solvers many times on the same instances, and preprocess each of them again every time.
+// When pb.Options.CacheDir is set, Preprocess stores the simplified problem in that directory, encoded as by ToProto,
+// elimination stack included, and reads it back instead of running the passes when it meets the same input again.
+// The same input is the same fingerprint, see Fingerprint, the same cost function, lit weights and eliminated clauses,
+// and the same options and default passes, since all of them change the simplified problem.
+// Problems whose history is tracked are never cached, nor problems with protected clauses or interpolation problems:
+// neither the history of the cached clauses, nor their protection, nor their partition are stored, see ToProto.
+
+// hasProtected returns whether some clauses of the problem are protected, see ClauseOptions.
+func (pb *Problem) hasProtected() bool {
+	for _, c := range pb.Clauses {
+		if c.protected {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey returns the name of the file caching the result of Preprocess on the problem.
+func (pb *Problem) cacheKey() string {
+	h := sha256.New()
+	fp := pb.Fingerprint()
+	h.Write(fp[:])
+	var buf [binary.MaxVarintLen64]byte
+	writeInt := func(x int64) { h.Write(buf[:binary.PutVarint(buf[:], x)]) }
+	writeLits := func(lits []Lit) {
+		writeInt(int64(len(lits)))
+		for _, lit := range lits {
+			writeInt(int64(lit))
+		}
+	}
+	writeLits(pb.minLits)
+	writeInt(int64(len(pb.minWeights)))
+	for _, w := range pb.minWeights {
+		writeInt(int64(w))
+	}
+	lits := make([]Lit, 0, len(pb.LitWeights))
+	for lit := range pb.LitWeights {
+		lits = append(lits, lit)
+	}
+	sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+	for _, lit := range lits {
+		writeInt(int64(lit))
+		writeInt(int64(math.Float64bits(pb.LitWeights[lit])))
+	}
+	writeInt(int64(len(pb.eliminated)))
+	for _, e := range pb.eliminated {
+		writeLits(e.lits)
+		writeInt(int64(len(e.others)))
+		for _, lits := range e.others {
+			writeLits(lits)
+		}
+	}
+	opts := pb.Options
+	opts.LRAT, opts.DeltaWriter, opts.EventWriter, opts.CacheDir = nil, nil, nil, ""
+	fmt.Fprintf(h, "%+v", opts)
+	for _, pass := range DefaultPasses {
+		fmt.Fprintf(h, ";%s", pass.Name)
+	}
+	return hex.EncodeToString(h.Sum(nil)) + ".pb"
+}
+
+// readCache replaces the problem by its simplified version, if it is in pb.Options.CacheDir,
+// and returns whether it was. The problem is left as is if the cached file cannot be read.
+func (pb *Problem) readCache(key string) bool {
+	data, err := ioutil.ReadFile(filepath.Join(pb.Options.CacheDir, key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Could not read cache: %v", err)
+		}
+		return false
+	}
+	cached, err := FromProto(data)
+	if err == nil && cached.NbVars != pb.NbVars {
+		err = badInput("cached problem has %d vars, not %d", cached.NbVars, pb.NbVars)
+	}
+	if err != nil {
+		log.Printf("Could not read cache: %v", err)
+		return false
+	}
+	pb.Clauses, pb.Units, pb.Model, pb.Status = cached.Clauses, cached.Units, cached.Model, cached.Status
+	pb.eliminated = cached.eliminated
+	pb.cards, pb.amos = nil, nil // They refer to clauses that may be gone
+	pb.idx, pb.pending, pb.nbMarked = nil, nil, 0
+	for i := range pb.refs {
+		pb.refs[i] = nil
+	}
+	pb.nbSteps++
+	log.Printf("Read preprocessed problem from cache, %d clauses", len(pb.Clauses))
+	return true
+}
+
+// writeCache stores the problem in pb.Options.CacheDir. The file is written under another name first, then renamed,
+// so that concurrent runs never read a partial file. Errors are logged, since the cache is only an optimization.
+func (pb *Problem) writeCache(key string) {
+	if err := os.MkdirAll(pb.Options.CacheDir, 0755); err != nil {
+		log.Printf("Could not write cache: %v", err)
+		return
+	}
+	f, err := ioutil.TempFile(pb.Options.CacheDir, key+".tmp")
+	if err != nil {
+		log.Printf("Could not write cache: %v", err)
+		return
+	}
+	_, err = f.Write(pb.ToProto())
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(pb.Options.CacheDir, key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Printf("Could not write cache: %v", err)
+	}
+}
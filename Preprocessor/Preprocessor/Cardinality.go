@@ -0,0 +1,209 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+)
+
+// CARDINALITY CONSTRAINTS
+// Encodings often express "at most one of these lits is true" with the pairwise encoding: a binary clause
+// (-a | -b) for each pair of lits. DetectCardinalities finds them back as cliques of the exclusion graph,
+// whose edges are the binary clauses, greedily, as MineAtMostOnes does for cost lits.
+// Solvers that handle cardinality constraints natively read them in the KNF format, an extension of DIMACS,
+// or in the OPB format of the pseudo-Boolean competition: WriteKNF and WriteOPB write the constraints found,
+// alongside the clauses encoding them, or instead of them if pb.Options.ReplaceCardinalities is set.
+
+// minCardinalitySize is the min number of lits of the constraints found by DetectCardinalities:
+// an at-most-one constraint over two lits is just a binary clause.
+const minCardinalitySize = 3
+
+// A Cardinality is the constraint "at most AtMost lits of Lits are true".
+type Cardinality struct {
+	Lits    []Lit
+	AtMost  int
+	clauses []int // IDs of the clauses encoding the constraint, if they are known.
+}
+
+// DetectCardinalities looks for at-most-one constraints encoded pairwise in the binary clauses of the problem,
+// and records them, see Cardinalities. Each lit belongs to at most one constraint.
+// The problem is not modified: the constraints are implied by the clauses.
+func (pb *Problem) DetectCardinalities() {
+	pb.cards = nil
+	if pb.Status != Undetermined || pb.skipped("cardinality detection") {
+		return
+	}
+	exclusive := make(map[Lit]map[Lit]int) // For each lit, the lits it excludes, with the ID of the binary clause.
+	for _, c := range pb.Clauses {
+		if c.Len() != 2 {
+			continue
+		}
+		l1, l2 := c.Get(0).Negation(), c.Get(1).Negation()
+		for _, pair := range [][2]Lit{{l1, l2}, {l2, l1}} {
+			if exclusive[pair[0]] == nil {
+				exclusive[pair[0]] = make(map[Lit]int)
+			}
+			exclusive[pair[0]][pair[1]] = c.id
+		}
+	}
+	// Lits with many exclusive lits first, in increasing order otherwise
+	lits := make([]Lit, 0, len(exclusive))
+	for lit, excl := range exclusive {
+		if len(excl) >= minCardinalitySize-1 {
+			lits = append(lits, lit)
+		}
+	}
+	sort.Slice(lits, func(i, j int) bool {
+		if n1, n2 := len(exclusive[lits[i]]), len(exclusive[lits[j]]); n1 != n2 {
+			return n1 > n2
+		}
+		return lits[i] < lits[j]
+	})
+	used := make(map[Lit]bool, len(lits))
+	for _, lit := range lits {
+		if used[lit] {
+			continue
+		}
+		card := Cardinality{Lits: []Lit{lit}, AtMost: 1}
+		for _, lit2 := range lits {
+			if used[lit2] || lit2 == lit {
+				continue
+			}
+			ids := make([]int, 0, len(card.Lits))
+			for _, lit3 := range card.Lits {
+				if id, ok := exclusive[lit3][lit2]; ok {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == len(card.Lits) {
+				card.Lits = append(card.Lits, lit2)
+				card.clauses = append(card.clauses, ids...)
+			}
+		}
+		if len(card.Lits) >= minCardinalitySize {
+			for _, lit2 := range card.Lits {
+				used[lit2] = true
+			}
+			pb.cards = append(pb.cards, card)
+		}
+	}
+	log.Printf("%d cardinality constraints found", len(pb.cards))
+}
+
+// Cardinalities returns the constraints found by the last call to DetectCardinalities.
+func (pb *Problem) Cardinalities() []Cardinality {
+	res := make([]Cardinality, len(pb.cards))
+	for i, card := range pb.cards {
+		res[i] = Cardinality{Lits: append([]Lit(nil), card.Lits...), AtMost: card.AtMost}
+	}
+	return res
+}
+
+// nativeConstraints returns the constraints of the problem that can be written, i.e whose vars are not bound
+// nor eliminated, the IDs of the clauses that must not be written, since the constraints replace them,
+// and the number of constraints written in total, the empty clause of Unsat problems included.
+func (pb *Problem) nativeConstraints() (cards []Cardinality, replaced map[int]bool, nbConstraints int) {
+	eliminated := pb.eliminatedVars()
+	present := make(map[int]bool, len(pb.Clauses))
+	for _, c := range pb.Clauses {
+		present[c.id] = true
+	}
+	replaced = make(map[int]bool)
+	for _, card := range pb.cards {
+		ok := true
+		for _, lit := range card.Lits {
+			ok = ok && pb.Model[lit.Var()] == 0 && !eliminated[lit.Var()]
+		}
+		if !ok {
+			continue
+		}
+		cards = append(cards, card)
+		if !pb.Options.ReplaceCardinalities {
+			continue
+		}
+		complete := true
+		for _, id := range card.clauses {
+			complete = complete && present[id]
+		}
+		if complete { // Otherwise, the clauses are not the pairwise encoding of the constraint anymore
+			for _, id := range card.clauses {
+				replaced[id] = true
+			}
+		}
+	}
+	nbConstraints = len(pb.Units) + len(pb.Clauses) - len(replaced) + len(cards)
+	if pb.Status == Unsat {
+		nbConstraints++
+	}
+	return cards, replaced, nbConstraints
+}
+
+// WriteKNF writes the problem in the KNF format: the DIMACS CNF format, whose header is "p knf",
+// where "k 2 1 2 3 0" means that at least 2 lits of 1, 2 and 3 are true. At-most constraints are written
+// as at-least constraints on the negations of their lits.
+func (pb *Problem) WriteKNF(w io.Writer) error {
+	cards, replaced, nbConstraints := pb.nativeConstraints()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p knf %d %d\n", pb.NbVars, nbConstraints)
+	if pb.Status == Unsat {
+		fmt.Fprintln(bw, "0")
+	}
+	for _, lit := range pb.Units {
+		fmt.Fprintf(bw, "%d 0\n", lit.Int())
+	}
+	for _, c := range pb.Clauses {
+		if !replaced[c.id] {
+			fmt.Fprintln(bw, c.CNF())
+		}
+	}
+	for _, card := range cards {
+		fmt.Fprintf(bw, "k %d", len(card.Lits)-card.AtMost)
+		for _, lit := range card.Lits {
+			fmt.Fprintf(bw, " %d", lit.Negation().Int())
+		}
+		fmt.Fprintln(bw, " 0")
+	}
+	return bw.Flush()
+}
+
+// WriteOPB writes the problem in the OPB format of the pseudo-Boolean competition: each clause and each
+// cardinality constraint is a linear constraint. Negative lits are written as 1 minus their var,
+// so that only vars appear in the constraints.
+func (pb *Problem) WriteOPB(w io.Writer) error {
+	cards, replaced, nbConstraints := pb.nativeConstraints()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "* #variable= %d #constraint= %d\n", pb.NbVars, nbConstraints)
+	// atLeast writes "at least bound lits are true".
+	atLeast := func(lits []Lit, bound int) {
+		for _, lit := range lits {
+			if lit.IsPositive() {
+				fmt.Fprintf(bw, "+1 x%d ", lit.Var()+1)
+			} else {
+				fmt.Fprintf(bw, "-1 x%d ", lit.Var()+1)
+				bound--
+			}
+		}
+		fmt.Fprintf(bw, ">= %d ;\n", bound)
+	}
+	if pb.Status == Unsat {
+		atLeast(nil, 1)
+	}
+	for _, lit := range pb.Units {
+		atLeast([]Lit{lit}, 1)
+	}
+	for _, c := range pb.Clauses {
+		if !replaced[c.id] {
+			atLeast(c.lits, 1)
+		}
+	}
+	for _, card := range cards {
+		negs := make([]Lit, len(card.Lits))
+		for i, lit := range card.Lits {
+			negs[i] = lit.Negation()
+		}
+		atLeast(negs, len(card.Lits)-card.AtMost)
+	}
+	return bw.Flush()
+}
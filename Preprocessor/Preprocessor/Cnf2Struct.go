@@ -24,10 +24,19 @@ func parseHeader(r *bufio.Reader) (nbVars, nbClauses int, err error) {
 	if err != nil {
 		return 0, 0, fmt.Errorf("nbvars not an int : %q", fields[1])
 	}
+	if nbVars < 0 {
+		return 0, 0, fmt.Errorf("negative number of vars %d", nbVars)
+	}
+	if nbVars > MaxVar {
+		return 0, 0, fmt.Errorf("%d vars, but lits cannot have more than %d vars, see LitInt", nbVars, MaxVar)
+	}
 	nbClauses, err = strconv.Atoi(fields[2])
 	if err != nil {
 		return 0, 0, fmt.Errorf("nbClauses not an int : '%s'", fields[2])
 	}
+	if nbClauses < 0 {
+		return 0, 0, fmt.Errorf("negative number of clauses %d", nbClauses)
+	}
 	return nbVars, nbClauses, nil
 }
 
@@ -72,27 +81,43 @@ func readInt(b *byte, r *bufio.Reader) (res int, err error) {
 	return res, err
 }
 
+// readComment reads the rest of a comment line, whose leading 'c' was already read, and returns it.
+func readComment(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
 // ParseCNF parses a CNF file and returns the corresponding Problem.
 func ParseCNF(f io.Reader) (*Problem, error) {
+	return ParseCNFWithOptions(f, Options{})
+}
+
+// ParseCNFWithOptions parses a CNF file and returns the corresponding Problem, using the given options.
+// Options must be given at parse time when they affect parsing, e.g Provenance, since units are propagated while parsing.
+func ParseCNFWithOptions(f io.Reader, opts Options) (*Problem, error) {
 	r := bufio.NewReader(f)
 	var (
-		nbClauses int
-		pb        Problem
+		nbClauses     int
+		pb            Problem
+		headerWasRead bool
 	)
+	pb.Options = opts
 	b, err := r.ReadByte()
 	for err == nil {
-		if b == 'c' { // Ignore comment
-			b, err = r.ReadByte()
-			for err == nil && b != '\n' {
-				b, err = r.ReadByte()
+		if b == 'c' { // Comment
+			var comment string
+			comment, err = readComment(r)
+			if pb.Options.KeepComments && !headerWasRead {
+				pb.Comments = append(pb.Comments, comment)
 			}
 		} else if b == 'p' { // Parse header
 			pb.NbVars, nbClauses, err = parseHeader(r)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse CNF header: %v", err)
+				return nil, badInput("cannot parse CNF header: %v", err)
 			}
 			pb.Model = make([]decLevel, pb.NbVars)
 			pb.Clauses = make([]*Clause, 0, nbClauses)
+			headerWasRead = true
 		} else {
 			lits := make([]Lit, 0, 3) // Make room for some lits to improve performance
 			for {
@@ -100,21 +125,28 @@ func ParseCNF(f io.Reader) (*Problem, error) {
 				//fmt.Printf("Value: " + string(val))
 				if err == io.EOF {
 					if len(lits) != 0 { // This is not a trailing space at the end...
-						return nil, fmt.Errorf("unfinished clause while EOF found")
+						return nil, badInput("unfinished clause while EOF found")
 					}
 					break // When there are only several useless spaces at the end of the file, that is ok
 				}
 				if err != nil {
-					return nil, fmt.Errorf("cannot parse clause: %v", err)
+					return nil, badInput("cannot parse clause: %v", err)
 				}
 				if val == 0 {
-					pb.Clauses = append(pb.Clauses, NewClause(lits))
+					c := NewClause(lits)
+					c.id = pb.nextID()
+					// As with AddClause, tautologies are ignored and duplicate lits removed.
+					if c.Simplify() {
+						pb.deleted(c, "tautology")
+					} else {
+						pb.Clauses = append(pb.Clauses, c)
+					}
 					break
 				} else {
 					if val > pb.NbVars || -val > pb.NbVars {
-						return nil, fmt.Errorf("invalid literal %d for problem with %d vars only", val, pb.NbVars)
+						return nil, badInput("invalid literal %d for problem with %d vars only", val, pb.NbVars)
 					}
-					lits = append(lits, IntToLit(int32(val)))
+					lits = append(lits, IntToLit(LitInt(val)))
 				}
 			}
 		}
@@ -0,0 +1,146 @@
+package Preprocessor
+
+import "sort"
+
+// CONNECTED COMPONENTS
+// Two clauses are in the same connected component if they share a var, or are linked by a chain of clauses that do,
+// i.e if their vars are in the same connected component of the variable interaction graph, see VIG.go.
+// Components have no var in common, so a problem is Sat iff each of its components is, and its models are made of
+// a model of each component: Components splits a problem so that each part can be solved separately, and
+// CombineModels puts their models back together. Components are also preprocessed in parallel, see Parallel.go.
+
+// components returns the clauses of the problem grouped by connected component, in the order of pb.Clauses.
+// Components are in the order of their first clause.
+func (pb *Problem) components() [][]*Clause {
+	parent := make([]Var, pb.NbVars) // Union-find forest of the vars
+	for v := range parent {
+		parent[v] = Var(v)
+	}
+	var find func(v Var) Var
+	find = func(v Var) Var {
+		if parent[v] != v {
+			parent[v] = find(parent[v])
+		}
+		return parent[v]
+	}
+	for _, c := range pb.Clauses {
+		if c.Len() == 0 {
+			continue
+		}
+		root := find(c.lits[0].Var())
+		for _, lit := range c.lits[1:] {
+			if r := find(lit.Var()); r != root {
+				parent[r] = root
+			}
+		}
+	}
+	index := make(map[Var]int) // Index of the component of each root
+	var res [][]*Clause
+	for _, c := range pb.Clauses {
+		if c.Len() == 0 {
+			continue
+		}
+		root := find(c.lits[0].Var())
+		i, ok := index[root]
+		if !ok {
+			i = len(res)
+			index[root] = i
+			res = append(res, nil)
+		}
+		res[i] = append(res[i], c)
+	}
+	return res
+}
+
+// Components splits the clauses of the problem into var-disjoint problems, one per connected component.
+// The vars of each component are numbered from 1, in increasing order of their original number; the cost lits
+// and the lit weights of its vars are kept, as well as protected clauses, partitions and the options of pb,
+// but not its writers nor the history of clauses.
+// Units, eliminated vars and vars appearing in no clause do not belong to any component.
+// If the problem is Unsat, a single Unsat component without any var is returned.
+func (pb *Problem) Components() []*Problem {
+	if pb.Status == Unsat {
+		res := NewProblem(0)
+		res.AddClause(nil)
+		return []*Problem{res}
+	}
+	comps := pb.components()
+	res := make([]*Problem, len(comps))
+	compOf := make([]int, pb.NbVars) // For each var, 1 + the index of its component, or 0 if it has none.
+	localVar := make([]Var, pb.NbVars)
+	toLocal := func(lit Lit) Lit { return localVar[lit.Var()].Lit() | lit&1 }
+	for i, clauses := range comps {
+		var vars []Var
+		for _, c := range clauses {
+			for _, lit := range c.lits {
+				if compOf[lit.Var()] == 0 {
+					compOf[lit.Var()] = i + 1
+					vars = append(vars, lit.Var())
+				}
+			}
+		}
+		sort.Slice(vars, func(j, k int) bool { return vars[j] < vars[k] })
+		for j, v := range vars {
+			localVar[v] = Var(j)
+		}
+		res[i] = NewProblem(len(vars))
+		res[i].origVars = vars
+		res[i].Options = pb.Options
+		res[i].Options.LRAT, res[i].Options.DeltaWriter, res[i].Options.EventWriter = nil, nil, nil
+		for _, c := range clauses {
+			lits := make([]Lit, c.Len())
+			for j, lit := range c.lits {
+				lits[j] = toLocal(lit)
+			}
+			res[i].AddClauseWithOptions(lits, ClauseOptions{Protected: c.protected, Partition: c.partition})
+		}
+	}
+	costLits := make([][]Lit, len(comps))
+	costWeights := make([][]int, len(comps))
+	for i, lit := range pb.minLits {
+		if k := compOf[lit.Var()] - 1; k >= 0 {
+			costLits[k] = append(costLits[k], toLocal(lit))
+			if pb.minWeights != nil {
+				costWeights[k] = append(costWeights[k], pb.minWeights[i])
+			}
+		}
+	}
+	for k, lits := range costLits {
+		if len(lits) > 0 {
+			res[k].SetCostFunc(lits, costWeights[k])
+		}
+	}
+	for lit, w := range pb.LitWeights {
+		if k := compOf[lit.Var()] - 1; k >= 0 {
+			if res[k].LitWeights == nil {
+				res[k].LitWeights = make(map[Lit]float64)
+			}
+			res[k].LitWeights[toLocal(lit)] = w
+		}
+	}
+	return res
+}
+
+// CombineModels returns a model of the problem made of models of its components, as returned by Components:
+// models[i][v] is the value of var v of components[i], in a model where all its vars are bound, e.g by CompleteModel
+// if the component was preprocessed. Vars bound by units get their value, eliminated vars
+// the value their clauses need, see extendModel, and vars appearing in no clause are false.
+// The problem is not modified. If it is Unsat, nil is returned.
+func (pb *Problem) CombineModels(components []*Problem, models [][]bool) []bool {
+	if pb.Status == Unsat {
+		return nil
+	}
+	model := make([]bool, pb.NbVars)
+	for i, comp := range components {
+		for v, orig := range comp.origVars {
+			model[orig] = models[i][v]
+		}
+	}
+	// The bindings are completed on a problem without clauses, so that pb and its clauses are left as they are
+	res := &Problem{NbVars: pb.NbVars, Model: append([]decLevel(nil), pb.Model...), eliminated: pb.eliminated}
+	res.bindAll(model, "components")
+	for v, val := range res.Model {
+		model[v] = val == 1
+	}
+	return model
+}
@@ -0,0 +1,66 @@
+package Preprocessor
+
+// CONE OF INFLUENCE
+// When debugging an encoding, or in an abstraction-refinement loop, one is often only interested in the clauses
+// that can influence a few lits. The cone of influence of a set of lits is made of the clauses that contain their vars,
+// then of the clauses that share a var with those, and so on. Since it is a subset of the clauses,
+// a cone that is Unsat proves the problem Unsat, and a model of the problem is a model of the cone.
+
+// ConeOfInfluence returns a new problem, over the same vars, made of the clauses reachable from the vars of lits
+// through shared vars. Clauses containing one of these vars are at distance 0, clauses sharing a var with a clause
+// at distance d are at distance d+1. Only clauses at distance at most depth are kept; a negative depth means no limit.
+// Units binding a var of the cone are kept too. The cost function, gates, weights and history of clauses are not kept.
+func (pb *Problem) ConeOfInfluence(lits []Lit, depth int) *Problem {
+	res := NewProblem(pb.NbVars)
+	res.Options = pb.Options
+	res.Options.LRAT = nil
+	res.Options.Provenance = false
+	if pb.Status == Unsat {
+		res.Status = Unsat
+		return res
+	}
+	occurs := make([][]int, pb.NbVars) // For each var, the indices of the clauses it appears in.
+	for i, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			occurs[lit.Var()] = append(occurs[lit.Var()], i)
+		}
+	}
+	inCone := make([]bool, pb.NbVars)
+	var frontier []Var // Vars of the cone whose clauses were not visited yet.
+	for _, lit := range lits {
+		if v := lit.Var(); !inCone[v] {
+			inCone[v] = true
+			frontier = append(frontier, v)
+		}
+	}
+	kept := make([]bool, len(pb.Clauses))
+	for d := 0; len(frontier) > 0 && (depth < 0 || d <= depth); d++ {
+		var next []Var
+		for _, v := range frontier {
+			for _, i := range occurs[v] {
+				if kept[i] {
+					continue
+				}
+				kept[i] = true
+				for _, lit := range pb.Clauses[i].lits {
+					if w := lit.Var(); !inCone[w] {
+						inCone[w] = true
+						next = append(next, w)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	for _, lit := range pb.Units {
+		if inCone[lit.Var()] {
+			res.AddClause([]Lit{lit})
+		}
+	}
+	for i, c := range pb.Clauses {
+		if kept[i] {
+			res.AddClause(c.lits)
+		}
+	}
+	return res
+}
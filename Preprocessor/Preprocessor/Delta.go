@@ -0,0 +1,56 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DELTA LOG
+// When a constraint "disappears" from the simplified formula, it is not always clear which technique removed it, and why.
+// When pb.Options.DeltaWriter is set, each clause added, strengthened or deleted is written to it, one per line:
+//   a <id> <technique>: <lits> 0 from <premises>
+//   s <id> <technique>: <lits> 0 was <old id> from <premises>
+//   d <id> <technique>: <lits> 0 by <reasons>
+// Lits are given in DIMACS format; the lits of a deleted clause are omitted when they are unknown.
+// The "from" and "by" parts are omitted when there are no premises. The IDs are those of Provenance.go:
+// input clauses are numbered from 1, in the order they were parsed, so the log can be matched against the input file.
+// Unlike an LRAT proof, the log cannot be checked: it is only meant for debugging.
+
+// DeltaErr returns the first error met while writing to pb.Options.DeltaWriter, if any.
+// Nothing is written after an error.
+func (pb *Problem) DeltaErr() error {
+	return pb.deltaErr
+}
+
+// writeDelta writes a line of the delta log, if any. kind is 'a', 's' or 'd', see above.
+// oldID is the previous ID of a strengthened clause.
+func (pb *Problem) writeDelta(kind byte, id int, technique string, lits []Lit, premises []int, oldID int) {
+	w := pb.Options.DeltaWriter
+	if w == nil || pb.deltaErr != nil {
+		return
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%c %d %s:", kind, id, technique)
+	if lits != nil || kind != 'd' {
+		for _, lit := range lits {
+			fmt.Fprintf(&sb, " %d", lit.Int())
+		}
+		sb.WriteString(" 0")
+	}
+	if kind == 's' {
+		fmt.Fprintf(&sb, " was %d", oldID)
+	}
+	sep := " from"
+	if kind == 'd' {
+		sep = " by"
+	}
+	for _, premise := range premises {
+		if premise != 0 { // Unknown premise, e.g a unit whose clause was not recorded
+			fmt.Fprintf(&sb, "%s %d", sep, premise)
+			sep = ""
+		}
+	}
+	sb.WriteByte('\n')
+	_, pb.deltaErr = io.WriteString(w, sb.String())
+}
@@ -0,0 +1,136 @@
+package Preprocessor
+
+// DIFFING PROBLEMS
+// Checking that a change to a pass behaves as intended means comparing the formulas it gives before and after the change,
+// but textual diffs of CNF files are useless: passes reorder clauses and lits. Diff compares normalized formulas instead:
+// each clause is a set of lits, units are unit clauses, and an Unsat problem holds the empty clause. A clause of the second
+// problem that is a strict subset of a clause of the first one, which the second problem lacks, is reported as a
+// strengthening of it rather than as an added and a removed clause. Vars are compared by number, so both problems must
+// number them alike, as a problem and its simplified version do.
+
+// A Strengthening is a clause of a problem that was replaced by a strict subset of it, see Diff.
+type Strengthening struct {
+	From []Lit
+	To   []Lit
+}
+
+// A ProblemDiff lists the normalized clauses that differ between two problems, see Diff.
+// Clauses are in the order of their problem, units first, and their lits are sorted.
+type ProblemDiff struct {
+	Removed      [][]Lit         // Clauses of the first problem only, that were not strengthened.
+	Added        [][]Lit         // Clauses of the second problem only, that are no strengthening.
+	Strengthened []Strengthening // Clauses of the first problem only, and the clause of the second problem only replacing them.
+}
+
+// Empty returns whether both problems have the same normalized clauses.
+func (d ProblemDiff) Empty() bool {
+	return len(d.Removed) == 0 && len(d.Added) == 0 && len(d.Strengthened) == 0
+}
+
+// Diff returns the clauses that differ between a and b, see above. Duplicate clauses are only counted once.
+// Neither problem is modified.
+func Diff(a, b *Problem) ProblemDiff {
+	clausesA, clausesB := a.normalizedClauses(), b.normalizedClauses()
+	inA := make(map[[32]byte]bool, len(clausesA))
+	for _, lits := range clausesA {
+		inA[hashLits(lits)] = true
+	}
+	inB := make(map[[32]byte]bool, len(clausesB))
+	for _, lits := range clausesB {
+		inB[hashLits(lits)] = true
+	}
+	var removed, added [][]Lit
+	for _, lits := range clausesA {
+		if !inB[hashLits(lits)] {
+			removed = append(removed, lits)
+		}
+	}
+	for _, lits := range clausesB {
+		if !inA[hashLits(lits)] {
+			added = append(added, lits)
+		}
+	}
+	// Candidates for strengthening are looked for in the occurrences of a lit of the added clause
+	occurs := make(map[Lit][]int)
+	for i, lits := range removed {
+		for _, lit := range lits {
+			occurs[lit] = append(occurs[lit], i)
+		}
+	}
+	var res ProblemDiff
+	matched := make([]bool, len(removed))
+	for _, lits := range added {
+		from := -1
+		if len(lits) > 0 {
+			best := lits[0]
+			for _, lit := range lits[1:] {
+				if len(occurs[lit]) < len(occurs[best]) {
+					best = lit
+				}
+			}
+			for _, i := range occurs[best] {
+				if !matched[i] && len(removed[i]) > len(lits) && subsetLits(lits, removed[i]) {
+					from = i
+					break
+				}
+			}
+		} else {
+			for i := range removed {
+				if !matched[i] {
+					from = i
+					break
+				}
+			}
+		}
+		if from < 0 {
+			res.Added = append(res.Added, lits)
+			continue
+		}
+		matched[from] = true
+		res.Strengthened = append(res.Strengthened, Strengthening{From: removed[from], To: lits})
+	}
+	for i, lits := range removed {
+		if !matched[i] {
+			res.Removed = append(res.Removed, lits)
+		}
+	}
+	return res
+}
+
+// normalizedClauses returns the units of the problem, as unit clauses, then its clauses, lits sorted, without duplicate
+// lits nor tautologies, and the empty clause if the problem is Unsat.
+func (pb *Problem) normalizedClauses() [][]Lit {
+	res := make([][]Lit, 0, len(pb.Units)+len(pb.Clauses)+1)
+	for _, lit := range pb.Units {
+		res = append(res, []Lit{lit})
+	}
+	for _, c := range pb.Clauses {
+		if c.removed {
+			continue
+		}
+		c2 := NewClause(append([]Lit(nil), c.lits...))
+		if c2.Simplify() { // Also sorts its lits
+			continue
+		}
+		res = append(res, c2.lits)
+	}
+	if pb.Status == Unsat {
+		res = append(res, []Lit{})
+	}
+	return res
+}
+
+// subsetLits returns whether the sorted lits of sub all appear in the sorted lits of lits.
+func subsetLits(sub, lits []Lit) bool {
+	j := 0
+	for _, lit := range sub {
+		for j < len(lits) && lits[j] < lit {
+			j++
+		}
+		if j == len(lits) || lits[j] != lit {
+			return false
+		}
+		j++
+	}
+	return true
+}
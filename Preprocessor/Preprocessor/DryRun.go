@@ -0,0 +1,87 @@
+package Preprocessor
+
+import (
+	"log"
+	"unsafe"
+)
+
+// DRY RUNS
+// Preprocessing a huge formula can take longer than solving it. With pb.Options.DryRun set, Preprocess, Fixpoint
+// and PreprocessParallel leave the problem unchanged: each pass is run on a copy of the problem, taken by Clone,
+// and what it did to the copy is compared with the problem, see Diff, then reported in DryRunReports.
+// Since the problem is unchanged, each pass sees the problem as it was, not as the previous passes would have left it,
+// Fixpoint stops after a round, and neither the cache, see Cache.go, nor the pending clauses of incremental
+// preprocessing are touched. The copies count towards pb.Options.MaxMemoryMB.
+
+// dryRunExamples is the max number of clauses of each kind kept in DryRunReport.Examples.
+const dryRunExamples = 5
+
+// A DryRunReport describes what a pass would have done to the problem, see Options.DryRun.
+type DryRunReport struct {
+	Pass         string
+	Status       Status      // Status the pass would have given the problem.
+	Removed      int         // Clauses the pass would have removed, not counting strengthened ones.
+	Added        int         // Clauses, units included, the pass would have added, not counting strengthened ones.
+	Strengthened int         // Clauses the pass would have replaced by a subset of them.
+	NewUnits     int         // Units the pass would have found.
+	Eliminated   int         // Vars the pass would have eliminated.
+	RolledBack   bool        // Whether the pass would have been rolled back, see Options.MaxGrowth.
+	Examples     ProblemDiff // The first few clauses of each kind.
+}
+
+// DryRunReports returns the reports of the passes run since pb.Options.DryRun was set, in the order they were run.
+func (pb *Problem) DryRunReports() []DryRunReport {
+	return pb.dryRuns
+}
+
+// runPatterns runs DetectPatterns, or reports what it would do if pb.Options.DryRun is set.
+func (pb *Problem) runPatterns() {
+	if pb.Options.DryRun {
+		pb.dryRun(Pass{"patterns", (*Problem).DetectPatterns})
+	} else {
+		pb.DetectPatterns()
+	}
+}
+
+// dryRunCopy returns a copy of the problem for a dry run, charged to the memory budget.
+func (pb *Problem) dryRunCopy() *Problem {
+	nbClauses, nbLits, _ := pb.size()
+	pb.memory().charge(nbClauses*clauseSize(0) + nbLits*int(unsafe.Sizeof(Lit(0))))
+	return pb.Clone()
+}
+
+// dryRun runs the given pass on a copy of the problem, and records what it did to it in a report.
+func (pb *Problem) dryRun(pass Pass) {
+	pb2 := pb.dryRunCopy()
+	pb2.Options.DryRun = false
+	pb2.clean = nil
+	rolledBack := pb2.runTransaction(pass)
+	d := Diff(pb, pb2)
+	report := DryRunReport{
+		Pass:         pass.Name,
+		Status:       pb2.Status,
+		Removed:      len(d.Removed),
+		Added:        len(d.Added),
+		Strengthened: len(d.Strengthened),
+		NewUnits:     len(pb2.Units) - len(pb.Units),
+		RolledBack:   rolledBack,
+	}
+	eliminated := make(map[Var]bool)
+	for _, e := range pb2.eliminated[len(pb.eliminated):] {
+		eliminated[e.lits[0].Var()] = true
+	}
+	report.Eliminated = len(eliminated)
+	report.Examples = d
+	if len(d.Removed) > dryRunExamples {
+		report.Examples.Removed = d.Removed[:dryRunExamples]
+	}
+	if len(d.Added) > dryRunExamples {
+		report.Examples.Added = d.Added[:dryRunExamples]
+	}
+	if len(d.Strengthened) > dryRunExamples {
+		report.Examples.Strengthened = d.Strengthened[:dryRunExamples]
+	}
+	pb.dryRuns = append(pb.dryRuns, report)
+	log.Printf("Dry run of %s: %d clauses removed, %d added, %d strengthened, %d new units, %d vars eliminated",
+		pass.Name, report.Removed, report.Added, report.Strengthened, report.NewUnits, report.Eliminated)
+}
@@ -0,0 +1,428 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"log"
+)
+
+// VAR ELIMINATION
+// A var is eliminated by replacing the clauses it appears in by all their non-tautological resolvents on it.
+// When one of its lits appears in a single clause, there are no more resolvents than clauses containing its negation,
+// so the problem always gets smaller: EliminateSingles does just that, without the cost estimates a general
+// elimination would need, and is cheap enough to run before the other passes.
+// The problem stays equisatisfiable, but loses the models of the eliminated vars: the clauses holding the single lits
+// are kept, last eliminated last, so that models of the simplified problem can be extended, see extendModel.
+// The other removed clauses are kept too, so that the var can be added back when a clause containing it is added.
+// Users driving their own elimination schedule eliminate any var with EliminateVar, within the limits they choose,
+// picking the vars through EliminationScores. The lit of the var with the fewest occurrences is then the pivot
+// of several clauses, kept one after the other.
+// When the var is the output of an AND gate, only the resolvents of the clauses of the gate with the other clauses
+// are needed, as in SatELite: the resolvents of two clauses of the gate are tautologies, and the resolvents
+// of two other clauses are implied by the other resolvents.
+
+// An elimination is a clause removed along with the var of its first lit, the pivot.
+// The eliminations of a var are contiguous, and only the first one has others.
+type elimination struct {
+	lits      []Lit     // Lits of the clause, pivot first.
+	others    [][]Lit   // Lits of the removed clauses containing the negation of the pivot, see restore.
+	partition Partition // Partition of the removed clauses, which all are in the same one, see Interpolation.go.
+}
+
+// frozenVars returns, for each var, whether it must keep its models, since it appears in the cost function,
+// the lit weights or the gates of the problem. Such vars are never eliminated.
+func (pb *Problem) frozenVars() []bool {
+	res := make([]bool, pb.NbVars)
+	for _, lit := range pb.minLits {
+		res[lit.Var()] = true
+	}
+	for lit := range pb.LitWeights {
+		res[lit.Var()] = true
+	}
+	for _, g := range pb.Gates {
+		res[g.Out.Var()] = true
+		for _, lit := range g.In {
+			res[lit.Var()] = true
+		}
+	}
+	return res
+}
+
+// EliminateSingles eliminates the eliminable vars, see Options.EliminateOnly, one of whose lits appears in a single clause.
+// Resolvents are indexed as they are added, and the vars of the removed clauses are examined again,
+// since they may now have a single occurrence too. Vars with a resolvent of poor quality are kept,
+// see Options.MaxResolventScore.
+func (pb *Problem) EliminateSingles() {
+	if pb.Status != Undetermined || pb.skipped("singles") {
+		return
+	}
+	occurs := pb.index()
+	eliminable := pb.eliminable()
+	frozen := pb.frozenVars()
+	queue := make([]Var, pb.NbVars)
+	queued := make([]bool, pb.NbVars)
+	for i := range queue {
+		queue[i] = Var(i)
+		queued[i] = true
+	}
+	nbUnits := len(pb.Units)
+	nbElim := 0
+	for len(queue) > 0 && !pb.memory().exhausted() {
+		v := queue[0]
+		queue = queue[1:]
+		queued[v] = false
+		if !eliminable[v] || frozen[v] || pb.Model[v] != 0 {
+			continue
+		}
+		lit := v.Lit()
+		single, others := occurs.live(lit), occurs.live(lit.Negation())
+		if len(single) != 1 {
+			single, others = others, single
+			lit = lit.Negation()
+		}
+		if len(single) != 1 {
+			continue
+		}
+		c := pb.Clause(single[0])
+		removed := []*Clause{c}
+		var resolvents, premises []*Clause
+		kept := true
+		for _, ref := range others {
+			c2 := pb.Clause(ref)
+			removed = append(removed, c2)
+			pb.memory().charge(clauseSize(c.Len() + c2.Len()))
+			newC := c.Generate(c2, v)
+			if newC.Simplify() {
+				continue
+			}
+			if kept = pb.keepResolvent(newC, c, c2); !kept {
+				break
+			}
+			newC.lbd = resolventLBD(c, c2)
+			resolvents = append(resolvents, newC)
+			premises = append(premises, c2)
+		}
+		if !kept {
+			continue
+		}
+		for i, newC := range resolvents {
+			if pb.Status == Unsat {
+				break
+			}
+			pb.derived(newC, "singles", c.id, premises[i].id)
+			switch newC.Len() {
+			case 0:
+				pb.setPartition(nil, newC.partition)
+				pb.Status = Unsat
+			case 1:
+				lit2 := newC.First()
+				if pb.Model[lit2.Var()] == 0 || (pb.Model[lit2.Var()] == 1) != lit2.IsPositive() {
+					pb.setUnitID(lit2, newC.id)
+					pb.setPartition(newC.lits, newC.partition)
+					pb.addUnit(lit2)
+				}
+			default:
+				pb.Clauses = append(pb.Clauses, newC)
+				occurs.add(newC)
+			}
+		}
+		if pb.Status == Unsat {
+			log.Printf("Inferred UNSAT")
+			break
+		}
+		e := elimination{lits: []Lit{lit}, partition: c.partition}
+		for _, lit2 := range c.lits {
+			if lit2 != lit {
+				e.lits = append(e.lits, lit2)
+			}
+		}
+		for _, c2 := range removed[1:] {
+			pb.memory().charge(clauseSize(c2.Len()))
+			e.others = append(e.others, append([]Lit(nil), c2.lits...))
+		}
+		pb.eliminated = append(pb.eliminated, e)
+		nbElim++
+		for _, c2 := range removed {
+			pb.deleted(c2, "singles")
+			pb.markRemoved(c2)
+			for _, lit2 := range c2.lits {
+				if v2 := lit2.Var(); !queued[v2] {
+					queue = append(queue, v2)
+					queued[v2] = true
+				}
+			}
+		}
+	}
+	pb.sweep()
+	if nbElim > 0 {
+		log.Printf("Eliminated %d vars occurring once", nbElim)
+	}
+	if len(pb.Units) > nbUnits && pb.Status == Undetermined {
+		pb.Simplify2()
+	}
+	pb.updateStatus(len(pb.Clauses))
+}
+
+// eliminatedVars returns, for each var, whether it was eliminated.
+func (pb *Problem) eliminatedVars() []bool {
+	res := make([]bool, pb.NbVars)
+	for _, e := range pb.eliminated {
+		res[e.lits[0].Var()] = true
+	}
+	return res
+}
+
+// extendModel binds the eliminated vars that are not bound yet, from the last eliminated to the first one:
+// the pivot is set to true iff one of its clauses has no other true lit. The other vars must be bound already.
+// As with CompleteModel, the units it adds are choices, and are not recorded in the provenance nor the proof.
+func (pb *Problem) extendModel() {
+	isTrue := func(lit Lit) bool {
+		val := pb.Model[lit.Var()]
+		return val != 0 && (val == 1) == lit.IsPositive()
+	}
+	for i := len(pb.eliminated) - 1; i >= 0; i-- {
+		pivot := pb.eliminated[i].lits[0]
+		needed := false // Whether a clause of the pivot has no other true lit
+		for ; ; i-- {
+			sat := false
+			for _, lit := range pb.eliminated[i].lits[1:] {
+				sat = sat || isTrue(lit)
+			}
+			needed = needed || !sat
+			if i == 0 || pb.eliminated[i-1].lits[0] != pivot {
+				break
+			}
+		}
+		if pb.Model[pivot.Var()] != 0 {
+			continue
+		}
+		if needed {
+			pb.addUnit(pivot)
+		} else {
+			pb.addUnit(pivot.Negation())
+		}
+	}
+}
+
+// Restore adds back the clauses removed along with v, if it was eliminated, so that v is a var of the clauses again,
+// e.g before assumptions or clauses about it are given to an incremental solver. Models do not need to be extended to v
+// anymore. AddClause does it on its own for the vars of the added clause. Clauses that were removed along with other
+// vars and contain v are left aside: those vars were eliminated before v, and their models are still extended from v.
+func (pb *Problem) Restore(v Var) {
+	if v >= 0 && int(v) < pb.NbVars {
+		pb.restore([]Lit{v.Lit()})
+	}
+}
+
+// restore adds back the clauses removed along with the var of one of the given lits, so that clauses containing
+// it can be added to the problem again, see AddClause. Restored clauses are added as new input clauses,
+// and the resolvents that replaced them are kept, since they are implied by them.
+func (pb *Problem) restore(lits []Lit) {
+	for _, lit := range lits {
+		for i, e := range pb.eliminated {
+			if e.lits[0].Var() == lit.Var() { // A var is eliminated at most once until it is restored
+				j := i + 1
+				for j < len(pb.eliminated) && pb.eliminated[j].lits[0].Var() == lit.Var() {
+					j++
+				}
+				restored := append([]elimination(nil), pb.eliminated[i:j]...)
+				if eliminated := pb.eliminated; pb.nbMarks > 0 { // The later eliminations are moved in place
+					old := append([]elimination(nil), eliminated[i:]...)
+					pb.logUndo(func() { copy(eliminated[i:], old) })
+				}
+				pb.eliminated = append(pb.eliminated[:i], pb.eliminated[j:]...)
+				for _, e := range restored {
+					opts := ClauseOptions{Partition: e.partition}
+					pb.AddClauseWithOptions(e.lits, opts)
+					for _, lits2 := range e.others {
+						pb.AddClauseWithOptions(lits2, opts)
+					}
+				}
+				break
+			}
+		}
+	}
+}
+
+// andGate returns the clauses among pos, the clauses containing v, and neg, the clauses containing its negation,
+// that define v or its negation as an AND gate: a clause (out | -a1 | ... | -ak) and the binary clauses (-out | ai).
+// It returns nil if there is no such gate.
+func (pb *Problem) andGate(v Var, pos, neg []ClauseRef) map[ClauseRef]bool {
+	for _, out := range []Lit{v.Lit(), v.Lit().Negation()} {
+		long, binaries := pos, neg
+		if out != v.Lit() {
+			long, binaries = neg, pos
+		}
+		inputs := make(map[Lit]ClauseRef) // Clause (-out | a) for each input a
+		for _, ref := range binaries {
+			if c := pb.Clause(ref); c.Len() == 2 {
+				if c.lits[0] == out.Negation() {
+					inputs[c.lits[1]] = ref
+				} else {
+					inputs[c.lits[0]] = ref
+				}
+			}
+		}
+		for _, ref := range long {
+			c := pb.Clause(ref)
+			gate := map[ClauseRef]bool{ref: true}
+			for _, lit := range c.lits {
+				if lit == out {
+					continue
+				}
+				ref2, ok := inputs[lit.Negation()]
+				if !ok {
+					gate = nil
+					break
+				}
+				gate[ref2] = true
+			}
+			if gate != nil && c.Len() > 1 {
+				return gate
+			}
+		}
+	}
+	return nil
+}
+
+// EliminationScores returns, for each var, an estimate of the number of resolvents EliminateVar would add
+// if the var was eliminated, or -1 if the var cannot be eliminated, see ErrNotEliminable.
+// The estimate is the product of the numbers of occurrences of its lits or, if it is the output of an AND gate,
+// the number of resolvents of the clauses of the gate with the other clauses, tautologies included.
+// Vars with a low score are the best candidates; a var appearing in no clause scores 0, but EliminateVar leaves it as is.
+func (pb *Problem) EliminationScores() []int {
+	res := make([]int, pb.NbVars)
+	if pb.Status != Undetermined {
+		for v := range res {
+			res[v] = -1
+		}
+		return res
+	}
+	occurs := pb.index()
+	eliminable, frozen, eliminated := pb.eliminable(), pb.frozenVars(), pb.eliminatedVars()
+	for v := range res {
+		if pb.Model[v] != 0 || !eliminable[v] || frozen[v] || eliminated[v] {
+			res[v] = -1
+			continue
+		}
+		pos, neg := occurs.live(Var(v).Lit()), occurs.live(Var(v).Lit().Negation())
+		res[v] = len(pos) * len(neg)
+		if gate := pb.andGate(Var(v), pos, neg); gate != nil {
+			gatePos, gateNeg := 0, 0
+			for _, ref := range pos {
+				if gate[ref] {
+					gatePos++
+				}
+			}
+			gateNeg = len(gate) - gatePos
+			res[v] = gatePos*(len(neg)-gateNeg) + (len(pos)-gatePos)*gateNeg
+		}
+	}
+	return res
+}
+
+// EliminateVar eliminates v, replacing the clauses it appears in by their non-tautological resolvents on it,
+// unless there are more than maxResolvents of them, or one of them is of poor quality, see Options.MaxResolventScore,
+// or v appears in no clause: v is then kept, the problem is left as is, and false is returned. maxResolvents <= 0 means no limit.
+// Resolvents are simplified with the units found among them. Models of the problem can then be extended to v.
+// The returned error wraps ErrNotEliminable if v is not an unbound var of the problem, was already eliminated,
+// must be kept according to Options.EliminateOnly and Options.NeverEliminate, appears in a protected clause,
+// in both partitions of an interpolation problem, in the cost function, the lit weights or the gates of the problem, or if the problem is already solved.
+// The problem is then left as is.
+func (pb *Problem) EliminateVar(v Var, maxResolvents int) (bool, error) {
+	switch {
+	case v < 0 || int(v) >= pb.NbVars:
+		return false, fmt.Errorf("%w: %d is not a var of the problem", ErrNotEliminable, v+1)
+	case pb.Status != Undetermined:
+		return false, fmt.Errorf("%w: problem is already solved", ErrNotEliminable)
+	case pb.Model[v] != 0:
+		return false, fmt.Errorf("%w: var %d is bound", ErrNotEliminable, v+1)
+	case !pb.eliminable()[v]:
+		return false, fmt.Errorf("%w: var %d must be kept, see Options and ClauseOptions", ErrNotEliminable, v+1)
+	case pb.frozenVars()[v]:
+		return false, fmt.Errorf("%w: var %d appears in the cost function, the lit weights or the gates", ErrNotEliminable, v+1)
+	case pb.eliminatedVars()[v]:
+		return false, fmt.Errorf("%w: var %d is already eliminated", ErrNotEliminable, v+1)
+	}
+	occurs := pb.index()
+	pivot := v.Lit()
+	pos, neg := occurs.live(pivot), occurs.live(pivot.Negation())
+	if len(pos) == 0 && len(neg) == 0 { // Nothing would bind v when models are extended
+		return false, nil
+	}
+	gate := pb.andGate(v, pos, neg) // Before pos and neg are swapped: andGate needs the clauses of v in pos
+	// The clauses of the pivot are kept to extend models, so the fewest are chosen, unless there is none:
+	// the var would then be left free, although the clauses of its other lit need it.
+	if len(pos) == 0 || len(neg) > 0 && len(pos) > len(neg) {
+		pivot, pos, neg = pivot.Negation(), neg, pos
+	}
+	var resolvents []*Clause
+	var premises [][2]int
+	for _, ref := range pos {
+		c := pb.Clause(ref)
+		for _, ref2 := range neg {
+			if gate != nil && gate[ref] == gate[ref2] {
+				continue
+			}
+			c2 := pb.Clause(ref2)
+			newC := c.Generate(c2, v)
+			if newC.Simplify() {
+				continue
+			}
+			if !pb.keepResolvent(newC, c, c2) || maxResolvents > 0 && len(resolvents) == maxResolvents {
+				return false, nil
+			}
+			resolvents = append(resolvents, newC)
+			premises = append(premises, [2]int{c.id, c2.id})
+		}
+	}
+	nbUnits := len(pb.Units)
+	for i, newC := range resolvents {
+		if pb.Status == Unsat {
+			break
+		}
+		pb.memory().charge(clauseSize(newC.Len()))
+		pb.derived(newC, "elim", premises[i][0], premises[i][1])
+		switch newC.Len() {
+		case 0:
+			pb.setPartition(nil, newC.partition)
+			pb.Status = Unsat
+		case 1:
+			lit := newC.First()
+			if pb.Model[lit.Var()] == 0 || (pb.Model[lit.Var()] == 1) != lit.IsPositive() {
+				pb.setUnitID(lit, newC.id)
+				pb.setPartition(newC.lits, newC.partition)
+				pb.addUnit(lit)
+			}
+		default:
+			pb.Clauses = append(pb.Clauses, newC)
+			occurs.add(newC)
+		}
+	}
+	for i, ref := range pos {
+		c := pb.Clause(ref)
+		e := elimination{lits: []Lit{pivot}, partition: c.partition}
+		for _, lit := range c.lits {
+			if lit != pivot {
+				e.lits = append(e.lits, lit)
+			}
+		}
+		if i == 0 {
+			for _, ref2 := range neg {
+				e.others = append(e.others, append([]Lit(nil), pb.Clause(ref2).lits...))
+			}
+		}
+		pb.memory().charge(clauseSize(c.Len()))
+		pb.eliminated = append(pb.eliminated, e)
+	}
+	for _, ref := range append(pos, neg...) {
+		c := pb.Clause(ref)
+		pb.deleted(c, "elim")
+		pb.markRemoved(c)
+	}
+	pb.sweep()
+	if len(pb.Units) > nbUnits && pb.Status == Undetermined {
+		pb.Simplify2()
+	}
+	pb.updateStatus(len(pb.Clauses))
+	return true, nil
+}
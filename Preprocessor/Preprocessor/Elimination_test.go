@@ -0,0 +1,186 @@
+package Preprocessor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestEliminateVarFlippedGate(t *testing.T) {
+	// -2 occurs once, so it is the pivot, and 2 is defined as 1 by the gate (2 | -1), (-2 | 1).
+	// The gate must be found from the clauses of 2, not of the pivot, or the resolvent (1 | 3) of (2 | 3) and (-2 | 1)
+	// is taken for one of two clauses of the gate, and lost.
+	pb := parse(t, "p cnf 3 6\n2 3 0\n2 -1 0\n-2 1 0\n-1 3 0\n-1 -3 0\n1 -3 0\n")
+	if pb.bruteForce() {
+		t.Fatalf("expected the problem to be Unsat")
+	}
+	ok, err := pb.EliminateVar(1, 0)
+	if err != nil || !ok {
+		t.Fatalf("expected var 2 to be eliminated, got %t, %v", ok, err)
+	}
+	if err := pb.CheckInvariants(); err != nil {
+		t.Fatalf("invariant broken: %v", err)
+	}
+	if pb.bruteForce() {
+		t.Errorf("problem became satisfiable: %s", pb.CNF())
+	}
+}
+
+func TestEliminateVarLimit(t *testing.T) {
+	// Eliminating 1 yields the 4 resolvents (2 4), (2 5), (3 4), (3 5).
+	const cnf = "p cnf 5 4\n1 2 0\n1 3 0\n-1 4 0\n-1 5 0\n"
+	pb := parse(t, cnf)
+	before := pb.CNF()
+	ok, err := pb.EliminateVar(0, 3)
+	if err != nil || ok {
+		t.Fatalf("expected var 1 to be kept with 3 resolvents at most, got %t, %v", ok, err)
+	}
+	if got := pb.CNF(); got != before || len(pb.eliminated) != 0 {
+		t.Errorf("problem was modified: %s", got)
+	}
+	ok, err = pb.EliminateVar(0, 4)
+	if err != nil || !ok {
+		t.Fatalf("expected var 1 to be eliminated with 4 resolvents at most, got %t, %v", ok, err)
+	}
+	if err := pb.CheckInvariants(); err != nil {
+		t.Fatalf("invariant broken: %v", err)
+	}
+	if len(pb.Clauses) != 4 {
+		t.Errorf("expected 4 resolvents, got %s", pb.CNF())
+	}
+	if err := CheckEquisat(parse(t, cnf), pb, &bruteSolver{}); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestEliminateVarFlippedPivot(t *testing.T) {
+	// -1 occurs once, so its clause is the one kept to extend models.
+	const cnf = "p cnf 5 4\n1 2 0\n1 3 0\n1 4 0\n-1 5 0\n"
+	pb := parse(t, cnf)
+	ok, err := pb.EliminateVar(0, 0)
+	if err != nil || !ok {
+		t.Fatalf("expected var 1 to be eliminated, got %t, %v", ok, err)
+	}
+	if len(pb.eliminated) != 1 || pb.eliminated[0].lits[0] != IntToLit(-1) {
+		t.Fatalf("expected the clause of -1 to be kept, got %v", pb.eliminated)
+	}
+	if err := pb.CheckInvariants(); err != nil {
+		t.Fatalf("invariant broken: %v", err)
+	}
+	// Models where 5 is false must set 1 to false, and the others where 2, 3 or 4 is false to true.
+	if err := CheckEquisat(parse(t, cnf), pb, &bruteSolver{}); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestEliminateVarRefused(t *testing.T) {
+	pb := parse(t, "p cnf 3 2\n2 3 0\n-2 -3 0\n")
+	if ok, err := pb.EliminateVar(0, 0); err != nil || ok {
+		t.Errorf("expected var 1, which appears in no clause, to be kept, got %t, %v", ok, err)
+	}
+	if len(pb.eliminated) != 0 {
+		t.Errorf("var 1 should not be recorded as eliminated")
+	}
+	pb = parse(t, "p cnf 3 3\n1 0\n2 3 0\n-2 -3 0\n")
+	if _, err := pb.EliminateVar(0, 0); !errors.Is(err, ErrNotEliminable) {
+		t.Errorf("expected bound var 1 to be refused, got %v", err)
+	}
+	if _, err := pb.EliminateVar(3, 0); !errors.Is(err, ErrNotEliminable) {
+		t.Errorf("expected var 4 to be refused, got %v", err)
+	}
+}
+
+// sortedCNF returns the clauses of pb, each with its lits in increasing order, in increasing order.
+func sortedCNF(pb *Problem) []string {
+	var res []string
+	for _, c := range pb.Clauses {
+		lits := make([]int, c.Len())
+		for i, lit := range c.lits {
+			lits[i] = int(lit.Int())
+		}
+		sort.Ints(lits)
+		res = append(res, strings.Trim(fmt.Sprint(lits), "[]"))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestEliminateVarGates(t *testing.T) {
+	tests := []struct {
+		name   string
+		cnf    string
+		gate   []string // Clauses of the gate defining 1, as returned by sortedCNF, or nil if there is none.
+		remain []string // Clauses after the elimination of 1, as returned by sortedCNF.
+	}{
+		{
+			// 1 = 2 & 3: the resolvents of (1 4) and (-1 -4 5) would be a tautology anyway,
+			// and the ones of the gate with itself too.
+			name:   "and",
+			cnf:    "p cnf 5 6\n1 -2 -3 0\n-1 2 0\n-1 3 0\n1 4 0\n-1 -4 5 0\n-2 -5 0\n",
+			gate:   []string{"-1 2", "-1 3", "-3 -2 1"},
+			remain: []string{"-4 -3 -2 5", "-5 -2", "2 4", "3 4"},
+		},
+		{
+			// 1 = 2 | 3, that is -1 = -2 & -3: (1 5) and (-1 4) are not resolved together, though they would not be tautological.
+			name:   "or",
+			cnf:    "p cnf 5 5\n1 -2 0\n1 -3 0\n-1 2 3 0\n-1 4 0\n1 5 0\n",
+			gate:   []string{"-1 2 3", "-2 1", "-3 1"},
+			remain: []string{"-2 4", "-3 4", "2 3 5"},
+		},
+		{
+			// 1 = 2: an AND gate with a single input.
+			name:   "equivalence",
+			cnf:    "p cnf 4 4\n-1 2 0\n1 -2 0\n1 3 0\n-1 4 0\n",
+			gate:   []string{"-1 2", "-2 1"},
+			remain: []string{"-2 4", "2 3"},
+		},
+		{
+			// (-1 3) is missing, so all resolvents are needed.
+			name:   "partial",
+			cnf:    "p cnf 4 3\n1 -2 -3 0\n-1 2 0\n-1 4 0\n",
+			remain: []string{"-3 -2 4"},
+		},
+		{
+			name:   "none",
+			cnf:    "p cnf 5 4\n1 2 0\n1 3 0\n-1 4 0\n-1 5 0\n",
+			remain: []string{"2 4", "2 5", "3 4", "3 5"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pb := parse(t, test.cnf)
+			sat := pb.bruteForce()
+			occurs := pb.index()
+			pos, neg := occurs.live(IntToLit(1)), occurs.live(IntToLit(-1))
+			var gate []ClauseRef
+			for ref := range pb.andGate(0, pos, neg) {
+				gate = append(gate, ref)
+			}
+			gatePb := &Problem{}
+			for _, ref := range gate {
+				gatePb.Clauses = append(gatePb.Clauses, pb.Clause(ref))
+			}
+			if got := sortedCNF(gatePb); strings.Join(got, ",") != strings.Join(test.gate, ",") {
+				t.Errorf("expected gate %v, got %v", test.gate, got)
+			}
+			ok, err := pb.EliminateVar(0, 0)
+			if err != nil || !ok {
+				t.Fatalf("expected var 1 to be eliminated, got %t, %v", ok, err)
+			}
+			if err := pb.CheckInvariants(); err != nil {
+				t.Fatalf("invariant broken: %v", err)
+			}
+			if got := sortedCNF(pb); strings.Join(got, ",") != strings.Join(test.remain, ",") {
+				t.Errorf("expected clauses %v, got %v", test.remain, got)
+			}
+			if pb.bruteForce() != sat {
+				t.Errorf("expected satisfiability %t, got %t", sat, !sat)
+			}
+			if err := CheckEquisat(parse(t, test.cnf), pb, &bruteSolver{}); err != nil {
+				t.Errorf("%v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package Preprocessor
+
+import "fmt"
+
+// EQUISATISFIABILITY CHECKS
+// A preprocessor is sound iff the simplified problem is Sat exactly when the original one is, and the models
+// of the simplified problem, once extended to the removed vars, are models of the original one.
+// CheckEquisat checks both with a complete solver, so that users can gate their pipelines on it.
+// Both problems are loaded in the same solver, each clause guarded by a selector lit of its problem:
+// assuming a selector enables the clauses of its problem only.
+
+// CheckEquisat checks that simplified is a sound simplification of original, which must have been obtained from
+// a copy of it, see Clone: if simplified is Sat, its model, completed by the units and eliminated vars
+// of simplified, must satisfy original; if it is Unsat, original must be Unsat too.
+// solver must not contain any clause yet. The problems are not modified.
+// The returned error wraps ErrNotEquisat if the check fails.
+func CheckEquisat(original, simplified *Problem, solver Solver) error {
+	if original.NbVars > simplified.NbVars {
+		return fmt.Errorf("%w: original problem has %d vars, simplified one only %d",
+			ErrNotEquisat, original.NbVars, simplified.NbVars)
+	}
+	selOriginal, selSimplified := Var(simplified.NbVars).Lit(), Var(simplified.NbVars+1).Lit()
+	addGuarded(solver, original, selOriginal)
+	addGuarded(solver, simplified, selSimplified)
+	if !solver.Solve([]Lit{selSimplified}) {
+		if solver.Solve([]Lit{selOriginal}) {
+			return fmt.Errorf("%w: simplified problem is Unsat, but the original one is Sat", ErrNotEquisat)
+		}
+		return nil
+	}
+	pb := simplified.Clone() // Its units are choices, see extendModel
+	eliminated := pb.eliminatedVars()
+	for v := 0; v < pb.NbVars; v++ {
+		if pb.Model[v] != 0 || eliminated[v] {
+			continue
+		}
+		if lit := Var(v).Lit(); solver.Value(lit) {
+			pb.addUnit(lit)
+		} else {
+			pb.addUnit(lit.Negation())
+		}
+	}
+	pb.extendModel()
+	if pb.Status == Unsat {
+		return fmt.Errorf("%w: model of the simplified problem could not be extended", ErrNotEquisat)
+	}
+	satisfied := func(lits []Lit) bool {
+		for _, lit := range lits {
+			if val := pb.Model[lit.Var()]; val != 0 && (val == 1) == lit.IsPositive() {
+				return true
+			}
+		}
+		return false
+	}
+	if original.Status == Unsat {
+		return fmt.Errorf("%w: simplified problem is Sat, but the original one is Unsat", ErrNotEquisat)
+	}
+	for _, lit := range original.Units {
+		if !satisfied([]Lit{lit}) {
+			return fmt.Errorf("%w: extended model falsifies the unit %d of the original problem", ErrNotEquisat, lit.Int())
+		}
+	}
+	for _, c := range original.Clauses {
+		if !satisfied(c.lits) {
+			return fmt.Errorf("%w: extended model falsifies the clause %q of the original problem", ErrNotEquisat, c.CNF())
+		}
+	}
+	return nil
+}
+
+// addGuarded adds the units and clauses of pb to solver, each with the negation of sel,
+// so that they are only enabled when sel is assumed.
+func addGuarded(solver Solver, pb *Problem, sel Lit) {
+	if pb.Status == Unsat {
+		solver.Add([]Lit{sel.Negation()})
+	}
+	for _, lit := range pb.Units {
+		solver.Add([]Lit{lit, sel.Negation()})
+	}
+	for _, c := range pb.Clauses {
+		solver.Add(append(append([]Lit(nil), c.lits...), sel.Negation()))
+	}
+}
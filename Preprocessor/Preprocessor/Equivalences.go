@@ -0,0 +1,151 @@
+package Preprocessor
+
+import "log"
+
+// EQUIVALENT VARS
+// Encodings often state that a var is equal to another one, or to its negation, with two binary clauses:
+// (-x | y) and (x | -y) for x <-> y, (x | y) and (-x | -y) for x <-> -y. MergeEquivalences finds them in a single pass,
+// by looking up the complement of each binary clause in a hash map of the binary clauses, and substitutes one var
+// for the other at once. Substituting l for y, where y <-> l, is eliminating y through the gate y = l, see
+// Elimination.go: each clause containing y is replaced by its resolvent with the binary clause defining y,
+// and only the clause (y | -l) is kept to extend models, along with (-y | l) to restore y.
+// Binary clauses produced by the substitution are looked up too, so that chains of equivalences are merged in the same
+// pass. Equivalences spread over longer cycles of the binary implication graph are left to the techniques reasoning
+// on the whole graph, e.g Unhide, which MergeEquivalences is cheap enough to run before.
+// Vars that cannot be eliminated, see eliminable, are never substituted, but other vars can be substituted by them.
+
+// MergeEquivalences substitutes a var for each var found equivalent to it, or to its negation, see above.
+func (pb *Problem) MergeEquivalences() {
+	if pb.Status != Undetermined || pb.skipped("equivalences") {
+		return
+	}
+	occurs := pb.index()
+	eliminable := pb.eliminable()
+	frozen := pb.frozenVars()
+	for v, eliminated := range pb.eliminatedVars() {
+		eliminable[v] = eliminable[v] && !frozen[v] && !eliminated
+	}
+	binaries := make(map[[2]Lit]*Clause) // Binary clauses, by sorted lits
+	var queue [][2]*Clause               // Pairs of complementary binary clauses
+	// add adds a binary clause to binaries, and queues it if its complement is there too.
+	add := func(c *Clause) {
+		a, b := c.lits[0], c.lits[1]
+		if a > b {
+			a, b = b, a
+		}
+		binaries[[2]Lit{a, b}] = c
+		na, nb := a.Negation(), b.Negation()
+		if na > nb {
+			na, nb = nb, na
+		}
+		if c2 := binaries[[2]Lit{na, nb}]; c2 != nil && !c2.removed {
+			queue = append(queue, [2]*Clause{c, c2})
+		}
+	}
+	for _, c := range pb.Clauses {
+		if !c.removed && c.Len() == 2 {
+			add(c)
+		}
+	}
+	nbUnits := len(pb.Units)
+	nbMerged := 0
+	for len(queue) > 0 && pb.Status == Undetermined && !pb.memory().exhausted() {
+		c1, c2 := queue[0][0], queue[0][1]
+		queue = queue[1:]
+		if c1.removed || c2.removed {
+			continue
+		}
+		// c1 is (a | b) and c2 (-a | -b), so a <-> -b: the var of b is substituted if it can be, the var of a otherwise.
+		a, b := c1.lits[0], c1.lits[1]
+		if y := b.Var(); !eliminable[y] || pb.Model[y] != 0 {
+			a, b = b, a
+		}
+		y := b.Var()
+		if !eliminable[y] || pb.Model[y] != 0 || pb.Model[a.Var()] != 0 {
+			continue
+		}
+		pivot, l := y.Lit(), a.Negation() // y <-> l
+		if b != pivot {
+			l = a
+		}
+		pos, neg := c1, c2 // (y | -l) and (-y | l)
+		if !pos.contains(pivot) {
+			pos, neg = neg, pos
+		}
+		refs := append(append([]ClauseRef(nil), occurs.live(pivot)...), occurs.live(pivot.Negation())...)
+		var resolvents, premises []*Clause
+		var removed []*Clause
+		kept := true
+		for _, ref := range refs {
+			c := pb.Clause(ref)
+			removed = append(removed, c)
+			if c == pos || c == neg {
+				continue
+			}
+			def := neg
+			if c.contains(pivot.Negation()) {
+				def = pos
+			}
+			newC := c.Generate(def, y)
+			if newC.Simplify() {
+				continue
+			}
+			if kept = pb.keepResolvent(newC, c, def); !kept {
+				break
+			}
+			newC.lbd = c.lbd
+			resolvents = append(resolvents, newC)
+			premises = append(premises, def, c)
+		}
+		if !kept {
+			continue
+		}
+		for i, newC := range resolvents {
+			if pb.Status == Unsat {
+				break
+			}
+			pb.memory().charge(clauseSize(newC.Len()))
+			pb.derived(newC, "equiv", premises[2*i].id, premises[2*i+1].id)
+			switch newC.Len() {
+			case 1:
+				lit := newC.First()
+				if pb.Model[lit.Var()] == 0 || (pb.Model[lit.Var()] == 1) != lit.IsPositive() {
+					pb.setUnitID(lit, newC.id)
+					pb.setPartition(newC.lits, newC.partition)
+					pb.addUnit(lit)
+				}
+			default:
+				pb.Clauses = append(pb.Clauses, newC)
+				occurs.add(newC)
+				if newC.Len() == 2 {
+					add(newC)
+				}
+			}
+		}
+		if pb.Status == Unsat {
+			break
+		}
+		pb.memory().charge(clauseSize(pos.Len() + neg.Len()))
+		pb.eliminated = append(pb.eliminated, elimination{
+			lits:      []Lit{pivot, l.Negation()},
+			others:    [][]Lit{{pivot.Negation(), l}},
+			partition: pos.partition,
+		})
+		eliminable[y] = false
+		nbMerged++
+		for _, c := range removed {
+			pb.deleted(c, "equiv")
+			pb.markRemoved(c)
+		}
+	}
+	pb.sweep()
+	if pb.Status == Unsat {
+		log.Printf("Inferred UNSAT")
+		return
+	}
+	log.Printf("Merged %d equivalent vars, %d clauses now", nbMerged, len(pb.Clauses))
+	if len(pb.Units) > nbUnits {
+		pb.Simplify2()
+	}
+	pb.updateStatus(len(pb.Clauses))
+}
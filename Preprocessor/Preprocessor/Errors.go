@@ -0,0 +1,84 @@
+package Preprocessor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ERRORS AND RESULTS
+// Programmatic callers should not have to infer what happened from the logs and pb.Status alone.
+// Errors returned by the parsers wrap ErrBadInput when the input is malformed, so that they can be told apart
+// from I/O errors with errors.Is, and Preprocess returns a Result telling why it stopped.
+
+var (
+	// ErrBadInput is wrapped by the errors returned when the input is malformed.
+	ErrBadInput = errors.New("bad input")
+	// ErrTrivialUnsat is the error of the result of Preprocess when the problem was found Unsat.
+	ErrTrivialUnsat = errors.New("problem is trivially unsat")
+	// ErrResourceLimit is the error of the result of Preprocess when it stopped early because of pb.Options.MaxMemoryMB.
+	ErrResourceLimit = errors.New("resource limit reached")
+	// ErrNoSavepoint is wrapped by the error returned by Rollback when it is given an unknown savepoint.
+	ErrNoSavepoint = errors.New("no such savepoint")
+	// ErrIrreversible is returned by Rollback when the history of the problem is tracked, and cannot be undone.
+	ErrIrreversible = errors.New("history of the problem is tracked, it cannot be rolled back")
+	// ErrNotEliminable is wrapped by the error returned by EliminateVar when the var cannot be eliminated.
+	ErrNotEliminable = errors.New("var cannot be eliminated")
+	// ErrNotEquisat is wrapped by the error returned by CheckEquisat when a simplification is not sound.
+	ErrNotEquisat = errors.New("problems are not equisatisfiable")
+	// ErrNoProvenance is returned by Track and ExplainUnit when the provenance of clauses is not recorded, see Options.Provenance.
+	ErrNoProvenance = errors.New("provenance of clauses is not recorded")
+)
+
+// inputError is an error due to malformed input. Its message is kept as is, but it wraps ErrBadInput.
+type inputError struct {
+	msg string
+}
+
+func (e *inputError) Error() string { return e.msg }
+
+func (e *inputError) Unwrap() error { return ErrBadInput }
+
+// badInput returns an error wrapping ErrBadInput, with the given formatted message.
+func badInput(format string, args ...interface{}) error {
+	return &inputError{msg: fmt.Sprintf(format, args...)}
+}
+
+// A Termination tells why Preprocess stopped.
+type Termination byte
+
+const (
+	Finished        = Termination(iota) // All the passes were run.
+	Unchanged                           // Nothing was done, since the problem was not modified since it was preprocessed.
+	Solved                              // The problem was found Sat or Unsat.
+	MemoryExhausted                     // The memory budget was exhausted: passes were skipped or stopped early.
+)
+
+// A Result summarizes a call to Preprocess.
+type Result struct {
+	Status      Status
+	Termination Termination
+	Err         error // ErrTrivialUnsat if the problem is Unsat, ErrResourceLimit if the memory budget was exhausted.
+	Clauses     int   // Size of the problem once preprocessed.
+	Lits        int
+	Units       int
+	Time        time.Duration
+	Cached      bool // Whether the simplified problem was read from pb.Options.CacheDir, see Cache.go.
+}
+
+// result returns the result of a call to Preprocess that started at the given time.
+func (pb *Problem) result(start time.Time, unchanged bool) Result {
+	res := Result{Status: pb.Status, Time: time.Since(start)}
+	res.Clauses, res.Lits, res.Units = pb.size()
+	switch {
+	case pb.Status == Unsat:
+		res.Termination, res.Err = Solved, ErrTrivialUnsat
+	case pb.Status == Sat:
+		res.Termination = Solved
+	case pb.memory().exhausted():
+		res.Termination, res.Err = MemoryExhausted, ErrResourceLimit
+	case unchanged:
+		res.Termination = Unchanged
+	}
+	return res
+}
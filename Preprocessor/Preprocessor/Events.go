@@ -0,0 +1,95 @@
+package Preprocessor
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EVENT STREAM
+// The log messages are meant to be read by people. Experiment harnesses need the same information in a form they
+// can parse: when pb.Options.EventWriter is set, each step of preprocessing is also written to it as an Event,
+// one JSON object per line, e.g
+//   {"time":"2020-07-01T12:00:00.5Z","event":"pass_end","pass":"selfsub","status":"UNKNOWN","clauses":120,"lits":410,"units":3,"new_units":1,"modified":true,"ms":1.2}
+// Events are written when Preprocess and Fixpoint start and end, before and after each pass that is run,
+// and at the end of each round of Fixpoint. The sizes are those of the problem when the event is written.
+// Components preprocessed in parallel write their events to the same writer, tagged with the number of their worker.
+
+// Kinds of events.
+const (
+	EventStart     = "start"      // Preprocess, Fixpoint or PreprocessParallel was called.
+	EventPassStart = "pass_start" // A pass is about to run.
+	EventPassEnd   = "pass_end"   // A pass was run.
+	EventRoundEnd  = "round_end"  // A round of Fixpoint is over.
+	EventEnd       = "end"        // Preprocess, Fixpoint or PreprocessParallel returns.
+)
+
+// An Event is a line of the event stream.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Pass       string    `json:"pass,omitempty"`   // Name of the pass, or of the call for start and end events.
+	Round      int       `json:"round,omitempty"`  // Round of Fixpoint, numbered from 1.
+	Worker     int       `json:"worker,omitempty"` // Worker of PreprocessParallel, numbered from 1.
+	Status     string    `json:"status"`           // Status of the problem: SAT, UNSAT or UNKNOWN.
+	Clauses    int       `json:"clauses"`
+	Lits       int       `json:"lits"`
+	Units      int       `json:"units"`
+	NewUnits   int       `json:"new_units,omitempty"`   // Units found by the pass, or during the round or the call.
+	Modified   bool      `json:"modified,omitempty"`    // Whether the pass, round or call derived or deleted clauses.
+	RolledBack bool      `json:"rolled_back,omitempty"` // Whether the pass was rolled back, see Options.MaxGrowth.
+	Ms         float64   `json:"ms,omitempty"`          // Time spent by the pass, round or call, in milliseconds.
+}
+
+// EventErr returns the first error met while writing to pb.Options.EventWriter, if any.
+// Nothing is written after an error.
+func (pb *Problem) EventErr() error {
+	return pb.eventErr
+}
+
+// writeEvent fills the time, the worker, the status and the sizes of e, and writes it to the event stream, if any.
+func (pb *Problem) writeEvent(e Event) {
+	w := pb.Options.EventWriter
+	if w == nil || pb.eventErr != nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Worker = pb.worker
+	e.Status = statusName(pb.Status)
+	e.Clauses, e.Lits, e.Units = pb.size()
+	line, err := json.Marshal(e)
+	if err != nil {
+		pb.eventErr = err
+		return
+	}
+	_, pb.eventErr = w.Write(append(line, '\n')) // A single write, so that lines of parallel workers do not mix
+}
+
+// statusName returns the name of s, as in the results of the SAT competition.
+func statusName(s Status) string {
+	switch s {
+	case Sat:
+		return "SAT"
+	case Unsat:
+		return "UNSAT"
+	}
+	return "UNKNOWN"
+}
+
+// since returns the time elapsed since start, in milliseconds.
+func since(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+// A lockedWriter serializes the writes of parallel workers to a writer.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(b []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(b)
+}
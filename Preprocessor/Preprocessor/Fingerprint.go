@@ -0,0 +1,55 @@
+package Preprocessor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+)
+
+// FINGERPRINTS
+// Preprocessing the same formula twice gives the same result, so results can be cached, keyed by the formula.
+// The order of the clauses and of their lits depends on the tool that wrote the file, and passes reorder them,
+// so the fingerprint of a problem only depends on its number of vars and on its clauses as a multiset of sets of lits:
+// each clause is hashed on its own, lits sorted, and the sorted hashes of the clauses are hashed together.
+
+// Fingerprint returns a SHA-256 hash of the number of vars and of the clauses of the problem, units included,
+// and of the empty clause if the problem is Unsat. It does not depend on the order of the clauses nor of their lits,
+// but duplicate clauses change it. Cost lits, weights, eliminated clauses and the history of clauses are ignored.
+func (pb *Problem) Fingerprint() [32]byte {
+	hashes := make([][sha256.Size]byte, 0, len(pb.Clauses)+len(pb.Units)+1)
+	for _, c := range pb.Clauses {
+		if !c.removed {
+			hashes = append(hashes, hashLits(c.lits))
+		}
+	}
+	for _, lit := range pb.Units {
+		hashes = append(hashes, hashLits([]Lit{lit}))
+	}
+	if pb.Status == Unsat {
+		hashes = append(hashes, hashLits(nil))
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	h := sha256.New()
+	var buf [binary.MaxVarintLen64]byte
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(pb.NbVars))])
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(hashes)))])
+	for i := range hashes {
+		h.Write(hashes[i][:])
+	}
+	var res [32]byte
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
+// hashLits returns the SHA-256 hash of the given lits, in increasing order. lits is not modified.
+func hashLits(lits []Lit) [sha256.Size]byte {
+	sorted := append([]Lit(nil), lits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	buf := make([]byte, 0, len(sorted)*binary.MaxVarintLen64)
+	var tmp [binary.MaxVarintLen64]byte
+	for _, lit := range sorted {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(lit))]...)
+	}
+	return sha256.Sum256(buf)
+}
@@ -0,0 +1,181 @@
+package Preprocessor
+
+import (
+	"log"
+	"time"
+)
+
+// FIXPOINT
+// Passes enable each other: a resolvent found by SelfSub may subsume other clauses,
+// a unit found by probing may make new resolutions possible, and so on.
+// Fixpoint repeats a sequence of passes until a whole round leaves the problem unchanged,
+// i.e no clause was derived or deleted, or until the limits given in pb.Options are hit.
+// A pass that left the problem unchanged is not run again until another pass modifies the problem,
+// so calling Fixpoint again on an unmodified problem is almost free.
+
+// A Pass is a named preprocessing technique.
+type Pass struct {
+	Name string
+	Run  func(pb *Problem)
+}
+
+// DefaultPasses are the passes run by Preprocess.
+var DefaultPasses = []Pass{
+	{"equivalences", (*Problem).MergeEquivalences},
+	{"singles", (*Problem).EliminateSingles},
+	{"selfsub", (*Problem).SelfSub},
+	{"subsumption", (*Problem).Subsumption},
+}
+
+// PassStats describes the problem after a pass was run.
+type PassStats struct {
+	Pass     string
+	Modified bool // If false, the pass did not derive nor delete any clause.
+	Clauses  int
+	Lits     int
+	Units    int
+	Time     time.Duration
+}
+
+// RoundStats describes the problem after a round of Fixpoint.
+type RoundStats struct {
+	Round   int // Rounds are numbered from 1.
+	Passes  []PassStats
+	Clauses int
+	Lits    int
+	Units   int
+	Time    time.Duration
+}
+
+// Fixpoint runs the given passes, or DefaultPasses if none is given, until they do not modify the problem anymore,
+// the problem is solved, or pb.Options.FixpointRounds or pb.Options.FixpointTime is reached.
+// If pb.Options.DetectPatterns is set, DetectPatterns is run first.
+// The time limit is only checked between passes. It returns statistics about each round.
+func (pb *Problem) Fixpoint(passes ...Pass) []RoundStats {
+	return pb.fixpoint(passes, nil)
+}
+
+// fixpoint runs Fixpoint. If yield is not nil, it is called before each pass, and returns whether the problem
+// was modified meanwhile, see SafeProblem: the round is then not a fixpoint.
+func (pb *Problem) fixpoint(passes []Pass, yield func() bool) []RoundStats {
+	if len(passes) == 0 {
+		passes = DefaultPasses
+	}
+	start := time.Now()
+	timeout := func() bool {
+		return pb.Options.FixpointTime > 0 && time.Since(start) >= pb.Options.FixpointTime
+	}
+	if !pb.Options.DryRun {
+		pb.pending = nil // All clauses are examined anyway
+	}
+	nbSteps, nbUnits := pb.nbSteps, len(pb.Units)
+	pb.writeEvent(Event{Event: EventStart, Pass: "fixpoint"})
+	if pb.Options.DetectPatterns {
+		pb.runPatterns()
+	}
+	var stats []RoundStats
+	for round := 1; pb.Status == Undetermined; round++ {
+		if pb.Options.FixpointRounds > 0 && round > pb.Options.FixpointRounds {
+			log.Printf("Fixpoint not reached after %d rounds", pb.Options.FixpointRounds)
+			break
+		}
+		if timeout() {
+			log.Printf("Fixpoint not reached after %v", pb.Options.FixpointTime)
+			break
+		}
+		roundStart, roundSteps, roundUnits := time.Now(), pb.nbSteps, len(pb.Units)
+		rs := RoundStats{Round: round}
+		modified := false
+		if pb.Options.ShufflePasses {
+			passes = append([]Pass(nil), passes...)
+			pb.random().Shuffle(len(passes), func(i, j int) { passes[i], passes[j] = passes[j], passes[i] })
+		}
+		for _, pass := range passes {
+			if yield != nil && yield() {
+				modified = true
+			}
+			if pb.Status != Undetermined || timeout() {
+				break
+			}
+			passStart := time.Now()
+			ps := PassStats{Pass: pass.Name, Modified: pb.runPass(pass), Time: time.Since(passStart)}
+			ps.Clauses, ps.Lits, ps.Units = pb.size()
+			modified = modified || ps.Modified
+			rs.Passes = append(rs.Passes, ps)
+		}
+		rs.Clauses, rs.Lits, rs.Units = pb.size()
+		rs.Time = time.Since(roundStart)
+		stats = append(stats, rs)
+		pb.writeEvent(Event{Event: EventRoundEnd, Round: round, NewUnits: len(pb.Units) - roundUnits, Modified: pb.nbSteps != roundSteps, Ms: since(roundStart)})
+		log.Printf("Round %d: %d clauses, %d lits, %d units", round, rs.Clauses, rs.Lits, rs.Units)
+		if !modified {
+			log.Printf("Fixpoint reached after %d rounds", round)
+			if !pb.Options.DryRun {
+				pb.setClean("")
+			}
+			break
+		}
+	}
+	pb.writeEvent(Event{Event: EventEnd, Pass: "fixpoint", NewUnits: len(pb.Units) - nbUnits, Modified: pb.nbSteps != nbSteps, Ms: since(start)})
+	return stats
+}
+
+// runPass runs the given pass, unless it left the problem unchanged the last time it was run and the problem
+// was not modified since. It returns whether the pass modified the problem: passes that were rolled back did not,
+// see Options.MaxGrowth.
+func (pb *Problem) runPass(pass Pass) (modified bool) {
+	if pb.isClean(pass.Name) {
+		return false
+	}
+	if pb.Options.DryRun {
+		pb.writeEvent(Event{Event: EventPassStart, Pass: pass.Name})
+		start := time.Now()
+		pb.dryRun(pass)
+		pb.writeEvent(Event{Event: EventPassEnd, Pass: pass.Name, Ms: since(start)})
+		return false
+	}
+	start, nbSteps, nbUnits := time.Now(), pb.nbSteps, len(pb.Units)
+	pb.recordSelectors()
+	pb.writeEvent(Event{Event: EventPassStart, Pass: pass.Name})
+	rolledBack := pb.runTransaction(pass)
+	modified = !rolledBack && pb.nbSteps != nbSteps
+	if !modified {
+		pb.setClean(pass.Name)
+	}
+	pb.writeEvent(Event{Event: EventPassEnd, Pass: pass.Name, NewUnits: len(pb.Units) - nbUnits, Modified: modified, RolledBack: rolledBack, Ms: since(start)})
+	return modified
+}
+
+// isClean returns whether the pass with the given name left the problem unchanged the last time it was run,
+// and the problem was not modified since. The "" name stands for Preprocess and Fixpoint.
+func (pb *Problem) isClean(name string) bool {
+	nbSteps, ok := pb.clean[name]
+	return ok && nbSteps == pb.nbSteps
+}
+
+// setClean records that the pass with the given name left the problem unchanged.
+// Nothing is recorded when the memory budget is exhausted, since passes may have been skipped.
+func (pb *Problem) setClean(name string) {
+	if pb.memory().exhausted() {
+		return
+	}
+	if pb.clean == nil {
+		pb.clean = make(map[string]int)
+	}
+	pb.clean[name] = pb.nbSteps
+}
+
+// Dirty returns whether preprocessing the problem again could simplify it, i.e whether the problem is undecided and
+// was modified, e.g by AddClause, since Preprocess last ran or Fixpoint last reached a fixpoint.
+// Clauses modified directly through pb.Clauses, and changes of pb.Options, are not noticed.
+func (pb *Problem) Dirty() bool {
+	return pb.Status == Undetermined && !pb.isClean("")
+}
+
+// size returns the number of clauses, lits in clauses and units of the problem.
+func (pb *Problem) size() (nbClauses, nbLits, nbUnits int) {
+	for _, c := range pb.Clauses {
+		nbLits += c.Len()
+	}
+	return len(pb.Clauses), nbLits, len(pb.Units)
+}
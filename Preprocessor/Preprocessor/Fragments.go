@@ -0,0 +1,218 @@
+package Preprocessor
+
+import "log"
+
+// TRACTABLE FRAGMENTS
+// Horn, renamable Horn and 2-SAT problems are decided in polynomial time, so they do not need a SAT solver.
+// 2-SAT problems are decided with the strongly connected components of their binary implication graph (BIG):
+// the problem is UNSAT iff a lit and its negation are in the same component.
+// A problem is renamable Horn iff flipping some vars leaves at most one positive lit per clause,
+// i.e iff, for each pair of lits (l1, l2) of a clause, l1 is negative or l2 is negative once renamed.
+// Writing r(v) for "v is flipped", "l is negative once renamed" is encoded as the lit l itself over the r vars,
+// so the renaming is a model of the 2-SAT problem made of the clauses (l1 | l2).
+// Once renamed, a Horn problem without units is satisfied by setting all vars to false,
+// i.e by setting the flipped vars to true, so this model of the renaming is also a model of the problem.
+
+// A Fragment describes the tractable classes the clauses of a problem belong to. Units are not taken into account.
+type Fragment struct {
+	Horn          bool // Each clause has at most one positive lit.
+	RenamableHorn bool // The problem becomes Horn once some vars are flipped. Horn problems are renamable Horn.
+	TwoSAT        bool // Each clause has at most two lits.
+}
+
+// Classify returns the tractable classes the clauses of the problem belong to.
+func (pb *Problem) Classify() Fragment {
+	f := Fragment{Horn: true, TwoSAT: true}
+	for _, c := range pb.Clauses {
+		if c.Len() > 2 {
+			f.TwoSAT = false
+		}
+		nbPos := 0
+		for _, lit := range c.lits {
+			if lit.IsPositive() {
+				nbPos++
+			}
+		}
+		if nbPos > 1 {
+			f.Horn = false
+		}
+	}
+	f.RenamableHorn = f.Horn || pb.hornRenaming() != nil
+	return f
+}
+
+// SolveFragment decides the problem if it is Horn, renamable Horn or 2-SAT, and returns whether the problem is decided.
+// If the problem is Sat, all its vars are bound by units, as with CompleteModel: only one of its models is kept,
+// so the problem is not equivalent to the original one anymore. Optimisation problems are not solved,
+// since the model found is not optimal, nor are interpolation problems, see Interpolation.go.
+func (pb *Problem) SolveFragment() bool {
+	if pb.Status == Undetermined {
+		if len(pb.minLits) > 0 || pb.Options.Interpolation || pb.skipped("fragment solving") {
+			return false
+		}
+		// Clauses may contain lits bound by units
+		pb.Simplify2()
+	}
+	if pb.Status != Undetermined {
+		pb.CompleteModel()
+		return true
+	}
+	f := pb.Classify()
+	switch {
+	case f.RenamableHorn:
+		model := make([]bool, pb.NbVars)
+		if !f.Horn {
+			model = pb.hornRenaming()
+		}
+		log.Printf("Problem is renamable Horn, solved")
+		pb.bindAll(model, "horn")
+	case f.TwoSAT:
+		model, conflict, ok := solve2SAT(pb.NbVars, pb.big())
+		if !ok {
+			log.Printf("Problem is 2-SAT, inferred UNSAT")
+			// conflict implies its negation, which implies conflict
+			pb.derivedUnitRUP(conflict.Negation(), "2-sat")
+			pb.addUnit(conflict.Negation())
+			pb.derivedRUP(NewClause([]Lit{}), "2-sat", nil)
+			pb.Status = Unsat
+			return true
+		}
+		log.Printf("Problem is 2-SAT, solved")
+		pb.bindAll(model, "2-sat")
+	default:
+		return false
+	}
+	return true
+}
+
+// bindAll binds each unbound var to its value in model, which must satisfy all clauses, and removes the clauses,
+// protected ones excepted.
+// Eliminated vars are bound last, see extendModel.
+// The units are choices, not consequences of the clauses, so they are not recorded in the provenance nor the proof.
+func (pb *Problem) bindAll(model []bool, technique string) {
+	eliminated := pb.eliminatedVars()
+	for v, val := range model {
+		if pb.Model[v] != 0 || eliminated[v] {
+			continue
+		}
+		if val {
+			pb.addUnit(Var(v).Lit())
+		} else {
+			pb.addUnit(Var(v).Lit().Negation())
+		}
+	}
+	pb.extendModel()
+	for _, c := range pb.Clauses {
+		if !c.protected { // Protected clauses are kept, satisfied by the model
+			pb.markRemoved(c)
+			pb.deleted(c, technique)
+		}
+	}
+	pb.sweep()
+	if pb.Status == Undetermined {
+		pb.Status = Sat
+	}
+}
+
+// hornRenaming returns, for each var, whether it must be flipped to make the problem Horn,
+// or nil if the problem is not renamable Horn. The number of pairs of lits is quadratic in the length of clauses.
+func (pb *Problem) hornRenaming() []bool {
+	nbPairs := 0
+	for _, c := range pb.Clauses {
+		nbPairs += c.Len() * (c.Len() - 1) / 2
+	}
+	pb.memory().charge(occurrencesSize(pb.NbVars, 2*nbPairs))
+	graph := make([][]Lit, pb.NbVars*2)
+	for _, c := range pb.Clauses {
+		for i, l1 := range c.lits {
+			for _, l2 := range c.lits[i+1:] {
+				graph[l1.Negation()] = append(graph[l1.Negation()], l2)
+				graph[l2.Negation()] = append(graph[l2.Negation()], l1)
+			}
+		}
+	}
+	flipped, _, ok := solve2SAT(pb.NbVars, graph)
+	if !ok {
+		return nil
+	}
+	return flipped
+}
+
+// solve2SAT returns a model of the 2-SAT problem over nbVars vars whose implication graph is given.
+// If there is none, it returns a lit that implies its negation, and conversely.
+func solve2SAT(nbVars int, graph [][]Lit) (model []bool, conflict Lit, ok bool) {
+	comps := components(graph)
+	model = make([]bool, nbVars)
+	for v := range model {
+		pos, neg := Var(v).Lit(), Var(v).Lit().Negation()
+		if comps[pos] == comps[neg] {
+			return nil, pos, false
+		}
+		// Components are numbered in reverse topological order: the lit closer to the sinks is true
+		model[v] = comps[pos] < comps[neg]
+	}
+	return model, 0, true
+}
+
+// components returns, for each lit of the graph, the number of its strongly connected component.
+// Components are found with Tarjan's algorithm, so they are numbered in reverse topological order.
+func components(graph [][]Lit) []int {
+	nbLits := len(graph)
+	index := make([]int, nbLits) // Discovery time of each lit, 0 if it was not visited yet
+	low := make([]int, nbLits)
+	comps := make([]int, nbLits)
+	onStack := make([]bool, nbLits)
+	stack := make([]Lit, 0)
+	type frame struct {
+		lit Lit
+		idx int // Index of the next child to visit
+	}
+	calls := make([]frame, 0)
+	time, nbComps := 0, 0
+	visit := func(lit Lit) {
+		time++
+		index[lit], low[lit] = time, time
+		stack = append(stack, lit)
+		onStack[lit] = true
+		calls = append(calls, frame{lit: lit})
+	}
+	for root := range graph {
+		if index[root] != 0 {
+			continue
+		}
+		visit(Lit(root))
+		for len(calls) > 0 {
+			top := &calls[len(calls)-1]
+			lit := top.lit
+			if top.idx < len(graph[lit]) {
+				child := graph[lit][top.idx]
+				top.idx++
+				if index[child] == 0 {
+					visit(child)
+				} else if onStack[child] && index[child] < low[lit] {
+					low[lit] = index[child]
+				}
+				continue
+			}
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				if parent := calls[len(calls)-1].lit; low[lit] < low[parent] {
+					low[parent] = low[lit]
+				}
+			}
+			if low[lit] == index[lit] {
+				for {
+					lit2 := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[lit2] = false
+					comps[lit2] = nbComps
+					if lit2 == lit {
+						break
+					}
+				}
+				nbComps++
+			}
+		}
+	}
+	return comps
+}
@@ -0,0 +1,207 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"testing"
+)
+
+// FUZZING
+// FuzzPreprocess is run with the tests on its seed corpus, and fuzzed with e.g:
+//   go test -fuzz FuzzPreprocess GiniBench/Preprocessor/Preprocessor
+// Each input is parsed as a CNF and every pass is run on it. After each pass, the invariants of the problem are checked,
+// status transitions must be legal, and small problems must stay equisatisfiable, as checked by bruteForce.
+// Models of the simplified small problems must then extend to models of the original ones, see CheckEquisat.
+
+const (
+	fuzzMaxVars       = 1000 // Larger problems are ignored.
+	fuzzBruteForceVar = 12   // Satisfiability is checked by brute force on problems with at most that many vars.
+)
+
+var fuzzPasses = []Pass{
+	{"equivalences", (*Problem).MergeEquivalences},
+	{"singles", (*Problem).EliminateSingles},
+	{"elimvar", fuzzEliminateVars},
+	{"selfsub", (*Problem).SelfSub},
+	{"selfsubbinary", (*Problem).SelfSubBinary},
+	{"subsumption", (*Problem).Subsumption},
+	{"vivify", (*Problem).Vivify},
+	{"probe", (*Problem).Probe},
+	{"unhide", (*Problem).Unhide},
+	{"shrinkbig", (*Problem).ShrinkClausesViaBIG},
+	{"simplify", (*Problem).Simplify2},
+	{"applyunits", func(pb *Problem) { pb.ApplyUnits() }},
+}
+
+// fuzzSeeds are small problems exercising each pass.
+var fuzzSeeds = []string{
+	"p cnf 3 3\n1 0\n-1 2 0\n-2 3 0\n",                                                         // Units
+	"p cnf 2 4\n1 2 0\n-1 2 0\n1 -2 0\n-1 -2 0\n",                                              // Unsat
+	"p cnf 4 5\n1 -2 0\n-1 2 0\n2 -3 0\n-2 3 0\n3 4 -1 0\n",                                    // Equivalences
+	"p cnf 4 4\n1 2 3 0\n1 2 0\n-1 3 4 0\n-1 -3 4 0\n",                                         // Subsumption and self-subsumption
+	"p cnf 4 5\n-3 1 0\n-3 2 0\n3 -1 -2 0\n3 4 0\n-3 -4 0\n",                                   // AND gate
+	"p cnf 6 9\n1 2 0\n1 3 0\n2 3 0\n-1 -4 0\n-2 -4 0\n-3 -4 0\n4 5 6 0\n-5 -6 0\n-4 5 -6 0\n", // Probing
+	"p cnf 5 6\n-1 2 0\n-2 3 0\n-3 4 0\n-4 5 0\n-1 3 5 0\n1 -5 2 0\n",                          // Implication chain
+}
+
+// FuzzPreprocess fails if a pass misbehaves on a valid CNF.
+func FuzzPreprocess(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	prev := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(prev)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzCheck(t, data)
+	})
+}
+
+// fuzzCheck runs every pass on data, if it is a valid CNF, and reports the first misbehavior to t.
+// It returns whether data was valid.
+func fuzzCheck(t testing.TB, data []byte) bool {
+	if nbVars, ok := fuzzHeader(data); !ok || nbVars > fuzzMaxVars {
+		return false
+	}
+	pb, err := ParseCNFWithOptions(bytes.NewReader(data), Options{ProbeBinaries: true, UnhideRounds: 2})
+	if err != nil {
+		return false
+	}
+	if err := pb.CheckInvariants(); err != nil {
+		t.Fatalf("after parsing: %v", err)
+	}
+	bruteForce := pb.NbVars <= fuzzBruteForceVar
+	var sat bool
+	var original *Problem
+	if bruteForce {
+		sat = pb.bruteForce()
+		original = pb.Clone()
+	}
+	for _, pass := range fuzzPasses {
+		status := pb.Status
+		pass.Run(pb)
+		if err := pb.CheckInvariants(); err != nil {
+			t.Fatalf("after %s: %v", pass.Name, err)
+		}
+		if status != Undetermined && pb.Status != status {
+			t.Fatalf("after %s: status went from %d to %d", pass.Name, status, pb.Status)
+		}
+		if bruteForce && pb.bruteForce() != sat {
+			t.Fatalf("after %s: problem is not equisatisfiable anymore", pass.Name)
+		}
+	}
+	if bruteForce {
+		if err := CheckEquisat(original, pb, &bruteSolver{}); err != nil { // Models must extend to removed vars
+			t.Fatalf("after all passes: %v", err)
+		}
+	}
+	return true
+}
+
+// fuzzEliminateVars eliminates vars through EliminateVar, by increasing score, with a small limit on resolvents.
+// Scores are not updated as vars are eliminated: vars that cannot be eliminated anymore are refused by EliminateVar.
+func fuzzEliminateVars(pb *Problem) {
+	scores := pb.EliminationScores()
+	vars := make([]Var, 0, len(scores))
+	for v, score := range scores {
+		if score >= 0 {
+			vars = append(vars, Var(v))
+		}
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return scores[vars[i]] < scores[vars[j]] })
+	for _, v := range vars {
+		pb.EliminateVar(v, 8)
+	}
+}
+
+// fuzzHeader returns the number of vars declared in the header of the CNF, if any,
+// so that huge problems are not allocated.
+func fuzzHeader(data []byte) (nbVars int, ok bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var nbClauses int
+		if n, _ := fmt.Sscanf(sc.Text(), "p cnf %d %d", &nbVars, &nbClauses); n == 2 {
+			return nbVars, true
+		}
+	}
+	return 0, false
+}
+
+// bruteForce is true iff the problem is satisfiable, trying each assignment in turn.
+func (pb *Problem) bruteForce() bool {
+	if pb.Status != Undetermined {
+		return pb.Status == Sat
+	}
+	for m := 0; m < 1<<uint(pb.NbVars); m++ {
+		isTrue := func(lit Lit) bool { return (m>>uint(lit.Var())&1 == 1) == lit.IsPositive() }
+		ok := true
+		for _, lit := range pb.Units {
+			ok = ok && isTrue(lit)
+		}
+		for _, c := range pb.Clauses {
+			if !ok {
+				break
+			}
+			sat := false
+			for _, lit := range c.lits {
+				sat = sat || isTrue(lit)
+			}
+			ok = sat
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// A bruteSolver is a Solver for tiny problems, trying each assignment in turn.
+type bruteSolver struct {
+	clauses [][]Lit
+	nbVars  int
+	model   int // Values of the vars, as bits, in the model found by the last call to Solve.
+}
+
+func (s *bruteSolver) Add(lits []Lit) {
+	s.clauses = append(s.clauses, append([]Lit(nil), lits...))
+	for _, lit := range lits {
+		if int(lit.Var()) >= s.nbVars {
+			s.nbVars = int(lit.Var()) + 1
+		}
+	}
+}
+
+func (s *bruteSolver) Solve(assumptions []Lit) bool {
+	clauses := append([][]Lit(nil), s.clauses...)
+	nbVars := s.nbVars
+	for _, lit := range assumptions {
+		clauses = append(clauses, []Lit{lit})
+		if int(lit.Var()) >= nbVars {
+			nbVars = int(lit.Var()) + 1
+		}
+	}
+	for s.model = 0; s.model < 1<<uint(nbVars); s.model++ {
+		ok := true
+		for _, c := range clauses {
+			sat := false
+			for _, lit := range c {
+				sat = sat || s.Value(lit)
+			}
+			if ok = sat; !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *bruteSolver) Value(lit Lit) bool {
+	return (s.model>>uint(lit.Var())&1 == 1) == lit.IsPositive()
+}
@@ -0,0 +1,196 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GROUP-ORIENTED CNF
+// Group-MUS extractors read GCNF files, whose header is "p gcnf nbVars nbClauses lastGroup", and whose clauses
+// are prefixed by their group, e.g "{2} 1 -3 0". Group 0 holds the hard clauses, the others are the groups a MUS is
+// made of. As in MUS extractors, each group g > 0 gets a selector var s, and its clauses are added with s as an
+// extra lit: the group is enabled by assuming -s. Selectors are declared in Options.Selectors, see Selectors.go,
+// so passes keep the group of each clause in its lits: subsuming, strengthening and propagating clauses never adds
+// a selector to a clause, but resolving two clauses does, so resolvents mixing the selectors of several groups,
+// that no GCNF group can hold, are not kept, and their pivot is not eliminated, see keepResolvent.
+// A unit s means that its group is inconsistent with the hard clauses: it is written as the empty clause of the group.
+
+// ParseGCNF parses a GCNF file, and returns the corresponding Problem. The selector of group g is var nbVars+g-1,
+// and selectors are added to opts.Selectors. Options are otherwise used as in ParseCNFWithOptions.
+func ParseGCNF(f io.Reader, opts Options) (*Problem, error) {
+	var (
+		pb            *Problem
+		nbVars        int
+		lastGroup     int
+		group         = -1  // Group of the current clause, -1 between clauses.
+		lits          []Lit // Lits of the current clause.
+		headerWasRead bool
+		comments      []string
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<30) // Clauses can be very long
+	for lineNb := 1; sc.Scan(); lineNb++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0][0] == 'c':
+			if opts.KeepComments && !headerWasRead {
+				comments = append(comments, strings.TrimLeft(sc.Text(), " \t")[1:])
+			}
+			continue
+		case fields[0] == "p":
+			if headerWasRead || len(fields) != 5 || fields[1] != "gcnf" {
+				return nil, badInput("line %d: invalid GCNF header %q", lineNb, sc.Text())
+			}
+			var errs [3]error
+			nbVars, errs[0] = strconv.Atoi(fields[2])
+			_, errs[1] = strconv.Atoi(fields[3])
+			lastGroup, errs[2] = strconv.Atoi(fields[4])
+			if errs[0] != nil || errs[1] != nil || errs[2] != nil || nbVars < 0 || lastGroup < 0 || nbVars+lastGroup > MaxVar {
+				return nil, badInput("line %d: invalid GCNF header %q", lineNb, sc.Text())
+			}
+			pb = NewProblem(nbVars + lastGroup)
+			pb.Options = opts
+			pb.Options.Selectors = append([]Var(nil), opts.Selectors...) // Selectors of groups are added to a copy
+			pb.Comments = comments
+			pb.groupOf = make([]int, pb.NbVars)
+			for g := 1; g <= lastGroup; g++ {
+				pb.groupOf[nbVars+g-1] = g
+				pb.Options.Selectors = append(pb.Options.Selectors, Var(nbVars+g-1))
+			}
+			headerWasRead = true
+			continue
+		case !headerWasRead:
+			return nil, badInput("line %d: missing \"p gcnf\" header", lineNb)
+		}
+		for _, field := range fields {
+			if group < 0 {
+				g, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(field, "{"), "}"))
+				if err != nil || !strings.HasPrefix(field, "{") || !strings.HasSuffix(field, "}") || g < 0 || g > lastGroup {
+					return nil, badInput("line %d: invalid group %q", lineNb, field)
+				}
+				group = g
+				continue
+			}
+			val, err := strconv.Atoi(field)
+			if err != nil || val > nbVars || val < -nbVars {
+				return nil, badInput("line %d: invalid literal %q", lineNb, field)
+			}
+			if val != 0 {
+				lits = append(lits, IntToLit(LitInt(val)))
+				continue
+			}
+			if group > 0 {
+				lits = append(lits, Var(nbVars+group-1).Lit())
+			}
+			pb.AddClause(lits)
+			lits, group = nil, -1
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if group >= 0 {
+		return nil, badInput("unfinished clause while EOF found")
+	}
+	if !headerWasRead {
+		return nil, badInput("missing \"p gcnf\" header")
+	}
+	pb.Simplify2()
+	return pb, nil
+}
+
+// Group returns the group of c, a clause of a problem parsed by ParseGCNF: the group of its selector,
+// or 0 if it has none. Clauses of other problems are in group 0.
+func (pb *Problem) Group(c *Clause) int {
+	for _, lit := range c.lits {
+		if g := pb.groupOfVar(lit.Var()); g > 0 {
+			return g
+		}
+	}
+	return 0
+}
+
+// groupOfVar returns the group v is the selector of, or 0 if it is not a selector.
+func (pb *Problem) groupOfVar(v Var) int {
+	if int(v) < len(pb.groupOf) {
+		return pb.groupOf[v]
+	}
+	return 0
+}
+
+// mixesGroups returns whether c contains the selectors of several groups.
+func (pb *Problem) mixesGroups(c *Clause) bool {
+	group := 0
+	for _, lit := range c.lits {
+		if g := pb.groupOfVar(lit.Var()); g > 0 {
+			if group > 0 && g != group {
+				return true
+			}
+			group = g
+		}
+	}
+	return false
+}
+
+// WriteGCNF writes the problem, parsed by ParseGCNF, in GCNF format: each clause is written in its group,
+// without its selector. Units are hard clauses, but for units binding a selector to true, that are written as
+// the empty clause of their group. Selectors are not vars of the GCNF file: the vars after them are renumbered,
+// so that parsing the file again gives the same selectors as the original one. An error is returned if a selector was bound to false, since the clauses of its group then became hard clauses.
+func (pb *Problem) WriteGCNF(w io.Writer) error {
+	lastGroup := 0
+	num := make([]int, pb.NbVars) // DIMACS number of each var, selectors excluded
+	nbVars := 0
+	for v := range num {
+		if g := pb.groupOfVar(Var(v)); g > 0 {
+			if g > lastGroup {
+				lastGroup = g
+			}
+			continue
+		}
+		nbVars++
+		num[v] = nbVars
+	}
+	dimacs := func(lit Lit) int {
+		if lit.IsPositive() {
+			return num[lit.Var()]
+		}
+		return -num[lit.Var()]
+	}
+	for _, lit := range pb.Units {
+		if pb.groupOfVar(lit.Var()) > 0 && !lit.IsPositive() {
+			return fmt.Errorf("selector %d of group %d is false, its clauses are hard clauses", lit.Var().Lit().Int(), pb.groupOfVar(lit.Var()))
+		}
+	}
+	bw := bufio.NewWriter(w)
+	for _, comment := range pb.Comments {
+		fmt.Fprintf(bw, "c%s\n", comment)
+	}
+	if pb.Status == Unsat {
+		fmt.Fprintf(bw, "p gcnf %d 1 %d\n{0} 0\n", nbVars, lastGroup)
+		return bw.Flush()
+	}
+	fmt.Fprintf(bw, "p gcnf %d %d %d\n", nbVars, len(pb.Units)+len(pb.Clauses), lastGroup)
+	for _, lit := range pb.Units {
+		if g := pb.groupOfVar(lit.Var()); g > 0 {
+			fmt.Fprintf(bw, "{%d} 0\n", g)
+		} else {
+			fmt.Fprintf(bw, "{0} %d 0\n", dimacs(lit))
+		}
+	}
+	for _, c := range pb.Clauses {
+		fmt.Fprintf(bw, "{%d}", pb.Group(c))
+		for _, lit := range c.lits {
+			if pb.groupOfVar(lit.Var()) == 0 {
+				fmt.Fprintf(bw, " %d", dimacs(lit))
+			}
+		}
+		fmt.Fprintln(bw, " 0")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,74 @@
+package Preprocessor
+
+// GATE DEFINITIONS
+// Problems built from circuits (AIGER files, Boolean formulas) know which clauses define which var.
+// Those definitions are kept here so that gate-aware techniques don't have to re-discover them.
+
+type GateKind byte
+
+const (
+	// AndGate means Out <-> In[0] & In[1] & ... & In[n-1].
+	AndGate = GateKind(iota)
+	// XorGate means Out <-> In[0] xor In[1].
+	XorGate
+	// IteGate means Out <-> (In[0] ? In[1] : In[2]), i.e if In[0] then In[1] else In[2].
+	IteGate
+)
+
+// A Gate is the definition of the literal Out as a function of the literals In.
+type Gate struct {
+	Kind GateKind
+	Out  Lit
+	In   []Lit
+}
+
+// Clauses returns the Tseitin clauses encoding the gate.
+func (g Gate) Clauses() [][]Lit {
+	switch g.Kind {
+	case AndGate:
+		res := make([][]Lit, 0, len(g.In)+1)
+		long := make([]Lit, 0, len(g.In)+1)
+		long = append(long, g.Out)
+		for _, in := range g.In {
+			res = append(res, []Lit{g.Out.Negation(), in})
+			long = append(long, in.Negation())
+		}
+		return append(res, long)
+	case XorGate:
+		out, a, b := g.Out, g.In[0], g.In[1]
+		return [][]Lit{
+			{out.Negation(), a, b},
+			{out.Negation(), a.Negation(), b.Negation()},
+			{out, a.Negation(), b},
+			{out, a, b.Negation()},
+		}
+	case IteGate:
+		out, c, t, e := g.Out, g.In[0], g.In[1], g.In[2]
+		return [][]Lit{
+			{out.Negation(), c.Negation(), t},
+			{out.Negation(), c, e},
+			{out, c.Negation(), t.Negation()},
+			{out, c, e.Negation()},
+		}
+	}
+	return nil
+}
+
+// AddGate adds the clauses defining the given gate to the problem and records the gate definition.
+func (pb *Problem) AddGate(g Gate) {
+	for _, lits := range g.Clauses() {
+		pb.AddClause(lits)
+	}
+	pb.Gates = append(pb.Gates, g)
+}
+
+// Definition returns the gate defining v, if any.
+// The output of the returned gate is either v's positive or negative literal.
+func (pb *Problem) Definition(v Var) (Gate, bool) {
+	for _, g := range pb.Gates {
+		if g.Out.Var() == v {
+			return g, true
+		}
+	}
+	return Gate{}, false
+}
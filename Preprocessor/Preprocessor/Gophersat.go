@@ -0,0 +1,62 @@
+package Preprocessor
+
+import "github.com/crillab/gophersat/solver"
+
+// GOPHERSAT CONVERSIONS
+// The structures of this package are derived from gophersat's, and lits are encoded the same way,
+// so problems are converted clause by clause, without going through DIMACS text.
+
+// ToGophersat returns a gophersat problem equivalent to pb, optimisation function included.
+// gophersat deduces the number of vars from the clauses, so vars that appear nowhere may be dropped from it.
+func (pb *Problem) ToGophersat() *solver.Problem {
+	if pb.Status == Unsat {
+		return solver.ParseSlice([][]int{{}})
+	}
+	cnf := make([][]int, 0, len(pb.Units)+len(pb.Clauses))
+	for _, lit := range pb.Units {
+		cnf = append(cnf, []int{int(lit.Int())})
+	}
+	for _, c := range pb.Clauses {
+		clause := make([]int, c.Len())
+		for i, lit := range c.lits {
+			clause[i] = int(lit.Int())
+		}
+		cnf = append(cnf, clause)
+	}
+	res := solver.ParseSlice(cnf)
+	if len(pb.minLits) > 0 {
+		lits := make([]solver.Lit, len(pb.minLits))
+		for i, lit := range pb.minLits {
+			lits[i] = solver.Lit(lit)
+		}
+		res.SetCostFunc(lits, pb.minWeights)
+	}
+	return res
+}
+
+// FromGophersat returns a problem equivalent to the given gophersat problem.
+// Units are propagated, as ParseCNF does. Cardinality and pseudo-boolean constraints are not supported.
+// gophersat does not export the optimisation function of its problems, so it is not converted.
+func FromGophersat(p *solver.Problem) (*Problem, error) {
+	pb := NewProblem(p.NbVars)
+	if p.Status == solver.Unsat {
+		pb.AddClause(nil)
+		return pb, nil
+	}
+	for _, lit := range p.Units {
+		pb.AddClause([]Lit{Lit(lit)})
+	}
+	lits := make([]Lit, 0)
+	for i, c := range p.Clauses {
+		if c.PseudoBoolean() || c.Cardinality() != 1 {
+			return nil, badInput("constraint #%d is not a clause", i)
+		}
+		lits = lits[:0]
+		for j := 0; j < c.Len(); j++ {
+			lits = append(lits, Lit(c.Get(j)))
+		}
+		pb.AddClause(lits)
+	}
+	pb.Simplify2()
+	return pb, nil
+}
@@ -0,0 +1,51 @@
+package Preprocessor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crillab/gophersat/solver"
+)
+
+func TestGophersatRoundTrip(t *testing.T) {
+	for _, cnf := range []string{
+		"p cnf 4 4\n1 2 0\n-1 3 0\n-2 -3 4 0\n-4 -1 0\n",
+		"p cnf 3 4\n3 0\n1 2 -3 0\n-1 2 0\n1 -2 0\n",
+		"p cnf 2 4\n1 2 0\n1 -2 0\n-1 2 0\n-1 -2 0\n",
+		"p cnf 2 2\n1 0\n-1 0\n",
+	} {
+		pb := parse(t, cnf)
+		p := pb.ToGophersat()
+		back, err := FromGophersat(p)
+		if err != nil {
+			t.Fatalf("could not convert %q back: %v", cnf, err)
+		}
+		if sat := solver.New(p).Solve() == solver.Sat; sat != pb.bruteForce() {
+			t.Errorf("%q: gophersat found satisfiability %t, expected %t", cnf, sat, !sat)
+		}
+		if back.Status != pb.Status {
+			t.Errorf("%q: expected status %v, got %v", cnf, pb.Status, back.Status)
+		}
+		if pb.Status == Unsat { // Clauses and units do not matter anymore
+			continue
+		}
+		if got, want := strings.Join(sortedCNF(back), ","), strings.Join(sortedCNF(pb), ","); got != want {
+			t.Errorf("%q: expected clauses %s, got %s", cnf, want, got)
+		}
+		if len(back.Units) != len(pb.Units) {
+			t.Errorf("%q: expected units %v, got %v", cnf, pb.Units, back.Units)
+		}
+		for _, lit := range pb.Units {
+			if back.Model[lit.Var()] == 0 || (back.Model[lit.Var()] == 1) != lit.IsPositive() {
+				t.Errorf("%q: unit %d was lost", cnf, lit.Int())
+			}
+		}
+	}
+}
+
+func TestFromGophersatCardinality(t *testing.T) {
+	p := solver.ParseCardConstrs([]solver.CardConstr{solver.AtLeast1(1, 2), solver.AtMost1(1, 2, 3)})
+	if _, err := FromGophersat(p); err == nil {
+		t.Errorf("expected an error for a cardinality constraint")
+	}
+}
@@ -0,0 +1,122 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// INCREMENTAL CNF
+// The iCNF format of incremental SAT tracks is a CNF whose header is "p inccnf", without counts, followed by
+// clauses and by cubes, i.e sets of assumptions, written "a 1 -2 0". Each cube is a query: is the problem Sat
+// under these assumptions? Preprocessing must keep the answer to every query, so the vars of cubes are never
+// eliminated, and cubes are written back with their lits mapped to the simplified problem, see LitMap.
+
+// ParseICNF parses an iCNF file, and returns the corresponding Problem and its cubes, in order.
+// The vars of the cubes are added to opts.NeverEliminate. Options are otherwise used as in ParseCNFWithOptions.
+func ParseICNF(f io.Reader, opts Options) (*Problem, [][]Lit, error) {
+	pb := NewProblem(0)
+	pb.Options = opts
+	pb.Options.NeverEliminate = append([]Var(nil), opts.NeverEliminate...) // Cube vars are added to a copy
+	var (
+		cubes         [][]Lit
+		lits          []Lit // Lits of the current clause or cube.
+		inCube        bool
+		headerWasRead bool
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<30) // Clauses can be very long
+	for lineNb := 1; sc.Scan(); lineNb++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0][0] == 'c':
+			if pb.Options.KeepComments && !headerWasRead {
+				pb.Comments = append(pb.Comments, strings.TrimLeft(sc.Text(), " \t")[1:])
+			}
+			continue
+		case fields[0] == "p":
+			if headerWasRead || len(fields) != 2 || fields[1] != "inccnf" {
+				return nil, nil, badInput("line %d: invalid iCNF header %q", lineNb, sc.Text())
+			}
+			headerWasRead = true
+			continue
+		case !headerWasRead:
+			return nil, nil, badInput("line %d: missing \"p inccnf\" header", lineNb)
+		case fields[0] == "a":
+			if len(lits) > 0 || inCube {
+				return nil, nil, badInput("line %d: cube in an unfinished clause or cube", lineNb)
+			}
+			inCube = true
+			fields = fields[1:]
+		}
+		for _, field := range fields {
+			val, err := strconv.Atoi(field)
+			if err != nil || val > MaxVar || val < -MaxVar {
+				return nil, nil, badInput("line %d: invalid literal %q", lineNb, field)
+			}
+			if val != 0 {
+				lits = append(lits, IntToLit(LitInt(val)))
+				continue
+			}
+			if inCube {
+				pb.addVars(lits)
+				for _, lit := range lits {
+					pb.Options.NeverEliminate = append(pb.Options.NeverEliminate, lit.Var())
+				}
+				cubes = append(cubes, lits)
+			} else {
+				pb.AddClause(lits)
+			}
+			lits, inCube = nil, false
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(lits) > 0 || inCube {
+		return nil, nil, badInput("unfinished clause or cube while EOF found")
+	}
+	if !headerWasRead {
+		return nil, nil, badInput("missing \"p inccnf\" header")
+	}
+	pb.Simplify2()
+	return pb, cubes, nil
+}
+
+// WriteICNF writes the problem and the given cubes, whose lits are lits of the original problem, in iCNF format.
+// Lits of cubes are mapped to the simplified problem. Lits bound by units are dropped when they are true,
+// and kept when they are false, so that the query stays Unsat.
+func (pb *Problem) WriteICNF(w io.Writer, cubes [][]Lit) error {
+	bw := bufio.NewWriter(w)
+	for _, comment := range pb.Comments {
+		fmt.Fprintf(bw, "c%s\n", comment)
+	}
+	fmt.Fprintln(bw, "p inccnf")
+	if pb.Status == Unsat {
+		fmt.Fprintln(bw, "0")
+	}
+	for _, lit := range pb.Units {
+		fmt.Fprintf(bw, "%d 0\n", lit.Int())
+	}
+	for _, c := range pb.Clauses {
+		fmt.Fprintln(bw, c.CNF())
+	}
+	m := pb.LitMap()
+	for _, cube := range cubes {
+		fmt.Fprint(bw, "a")
+		for _, lit := range cube {
+			if lit2, ok := m.ToSimplified(lit); ok {
+				fmt.Fprintf(bw, " %d", lit2.Int())
+			} else if val := pb.Model[lit.Var()]; val == 0 || (val == 1) != lit.IsPositive() {
+				fmt.Fprintf(bw, " %d", lit.Int())
+			}
+		}
+		fmt.Fprintln(bw, " 0")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,68 @@
+package Preprocessor
+
+import "log"
+
+// INCREMENTAL PREPROCESSING
+// Solvers used incrementally add clauses to a problem that was already preprocessed. Preprocessing the whole formula
+// again would redo all the work for a few clauses, so once a problem was preprocessed, AddClause simplifies each
+// new clause with the known units and schedules it. The next call to Preprocess then only:
+//  - propagates the units found in the new clauses,
+//  - removes the new clauses subsumed by the other ones,
+//  - removes or strengthens the clauses subsumed or self-subsumed by the new ones, through backward subsumption,
+//  - runs self-subsuming resolution on the vars of the new clauses.
+// Only the occurrences of these vars are examined, so the cost depends on the neighborhood of the new clauses
+// rather than on the size of the formula, apart from the occurrence lists, built once.
+
+// resimplify simplifies the problem around the pending clauses, as described above, then forgets them.
+func (pb *Problem) resimplify() {
+	pending := pb.pending
+	pb.pending = nil
+	log.Printf("Simplifying %d added clauses", len(pending))
+	pb.Simplify2()
+	if pb.Status != Undetermined || pb.skipped("resimplify") {
+		return
+	}
+	occurs := pb.index()
+	pb.useBitsets()
+	eliminable := pb.eliminable()
+	touched := make([]bool, pb.NbVars)
+	for _, c := range pending {
+		if c.removed || c.Len() < 2 {
+			continue
+		}
+		if c2 := pb.subsumer(c, occurs); c2 != nil {
+			pb.deleted(c, "subsumption", c2.id)
+			pb.removeClauses(c)
+			continue
+		}
+		for _, lit := range c.lits {
+			touched[lit.Var()] = eliminable[lit.Var()]
+		}
+		pb.backwardSubsume(c, occurs)
+		if pb.Status == Unsat {
+			return
+		}
+	}
+	pb.selfSub(touched)
+}
+
+// subsumer returns a clause of the problem, other than c, that subsumes c, or nil if there is none.
+// Candidates are only looked for in the occurrences of the lit of c that occurs the least. Long clauses are not candidates, see Options.MaxClauseLen.
+func (pb *Problem) subsumer(c *Clause, occurs *Index) *Clause {
+	best, nbBest := c.First(), 0
+	for i, lit := range c.lits {
+		if nb := len(occurs.live(lit)); i == 0 || nb < nbBest {
+			best, nbBest = lit, nb
+		}
+	}
+	for _, ref := range occurs.subsets(best, c.signature()) {
+		c2 := pb.Clause(ref)
+		if c2 == c || c2.Len() > c.Len() || pb.isLong(c2) {
+			continue
+		}
+		if c2.Subsumes(c) {
+			return c2
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package Preprocessor
+
+// SUBSUMPTION INDEX
+// Subsumption, self-subsuming resolution and the simplification of added clauses all look for the clauses
+// containing a given lit, and filter them through their signature. Rather than each pass building its own
+// occurrence lists, the problem keeps a single Index, built on first use and then kept up to date lazily:
+// clauses added to the problem are indexed the next time the index is asked for, and clauses that were removed,
+// or strengthened so that they do not contain a lit anymore, are dropped from its list the next time it is scanned.
+
+// An Index is, for each lit, the list of the clauses of a problem it appears in, with their signatures.
+type Index struct {
+	pb   *Problem
+	refs [][]ClauseRef // For each lit, the clauses it appears in. Some of them may be stale, see live.
+	sigs [][]uint64    // For each lit, the signatures of the clauses of refs, in the same order.
+}
+
+// index returns the index of the problem, after indexing the clauses that were added since it was last used.
+func (pb *Problem) index() *Index {
+	if pb.idx == nil {
+		nbLits := 0
+		for _, c := range pb.Clauses {
+			nbLits += c.Len()
+		}
+		pb.memory().charge(occurrencesSize(pb.NbVars, nbLits))
+		pb.idx = &Index{pb: pb}
+	}
+	idx := pb.idx
+	for len(idx.refs) < 2*pb.NbVars { // Vars may have been added
+		idx.refs = append(idx.refs, nil)
+		idx.sigs = append(idx.sigs, nil)
+	}
+	for _, c := range pb.Clauses {
+		if !c.indexed && !c.removed {
+			idx.add(c)
+		}
+	}
+	return idx
+}
+
+// Occurrences returns the clauses of the problem containing l, in the order they were indexed.
+// Units are not clauses of the problem, and are not returned. The refs stay valid until the clauses are swept,
+// see Refs.go, but the returned slice is a copy, which is not updated as the problem is modified.
+// The first call builds the index, which is then kept up to date as any other use of the index.
+func (pb *Problem) Occurrences(l Lit) []ClauseRef {
+	if int(l.Var()) >= pb.NbVars {
+		return nil
+	}
+	return append([]ClauseRef(nil), pb.index().live(l)...)
+}
+
+// add adds c, a clause of the problem, to the lists of its lits.
+func (idx *Index) add(c *Clause) {
+	ref, sig := idx.pb.Ref(c), c.signature()
+	for _, lit := range c.lits {
+		idx.refs[lit] = append(idx.refs[lit], ref)
+		idx.sigs[lit] = append(idx.sigs[lit], sig)
+	}
+	c.indexed = true
+}
+
+// live removes from the list of lit the clauses that were removed from the problem,
+// or that do not contain lit anymore since they were strengthened, and returns the updated list.
+// The signatures of the clauses that were strengthened are updated.
+func (idx *Index) live(lit Lit) []ClauseRef {
+	refs, sigs := idx.refs[lit][:0], idx.sigs[lit][:0]
+	for _, ref := range idx.refs[lit] {
+		if c := idx.pb.Clause(ref); c != nil && !c.removed && c.contains(lit) {
+			refs = append(refs, ref)
+			sigs = append(sigs, c.signature())
+		}
+	}
+	idx.refs[lit], idx.sigs[lit] = refs, sigs
+	return refs
+}
+
+// supersets returns the clauses of the list of lit whose signature includes sig, i.e the candidates for
+// being subsumed or strengthened by a clause containing lit, or its negation, whose signature is sig.
+// The list must have been updated by live first.
+func (idx *Index) supersets(lit Lit, sig uint64) []ClauseRef {
+	var res []ClauseRef
+	for i, sig2 := range idx.sigs[lit] {
+		if sig&^sig2 == 0 {
+			res = append(res, idx.refs[lit][i])
+		}
+	}
+	return res
+}
+
+// subsets returns the clauses of the list of lit whose signature is included in sig, i.e the candidates for
+// subsuming a clause containing lit whose signature is sig. The list must have been updated by live first.
+func (idx *Index) subsets(lit Lit, sig uint64) []ClauseRef {
+	var res []ClauseRef
+	for i, sig2 := range idx.sigs[lit] {
+		if sig2&^sig == 0 {
+			res = append(res, idx.refs[lit][i])
+		}
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package Preprocessor
+
+import (
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, cnf string) *Problem {
+	pb, err := ParseCNF(strings.NewReader(cnf))
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", cnf, err)
+	}
+	return pb
+}
+
+// lits returns the clauses of the given refs, in DIMACS format.
+func lits(pb *Problem, refs []ClauseRef) []string {
+	var res []string
+	for _, ref := range refs {
+		res = append(res, pb.Clause(ref).CNF())
+	}
+	return res
+}
+
+func TestIndexLists(t *testing.T) {
+	pb := parse(t, "p cnf 3 3\n1 2 0\n-1 3 0\n1 2 3 0\n")
+	idx := pb.index()
+	if got := lits(pb, idx.live(IntToLit(1))); strings.Join(got, ",") != "1 2 0,1 2 3 0" {
+		t.Errorf("invalid list for 1: %v", got)
+	}
+	if got := lits(pb, idx.live(IntToLit(-1))); strings.Join(got, ",") != "-1 3 0" {
+		t.Errorf("invalid list for -1: %v", got)
+	}
+	if got := idx.live(IntToLit(-2)); len(got) != 0 {
+		t.Errorf("expected no clause for -2, got %v", lits(pb, got))
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	pb := parse(t, "p cnf 3 3\n1 2 0\n-1 3 0\n1 2 3 0\n")
+	idx := pb.index()
+	// Added clauses are indexed the next time the index is used
+	pb.AddClause([]Lit{IntToLit(-2), IntToLit(-3)})
+	if pb.index() != idx {
+		t.Fatalf("index was built again")
+	}
+	if got := lits(pb, idx.live(IntToLit(-3))); strings.Join(got, ",") != "-2 -3 0" {
+		t.Errorf("added clause not indexed: %v", got)
+	}
+	// Removed clauses are dropped
+	pb.markRemoved(pb.Clauses[0])
+	pb.sweep()
+	if got := lits(pb, idx.live(IntToLit(2))); strings.Join(got, ",") != "1 2 3 0" {
+		t.Errorf("removed clause still indexed: %v", got)
+	}
+	// Strengthened clauses are dropped from the lists of their removed lits, and their signature is updated
+	c := pb.Clauses[1] // 1 2 3
+	c.setLits([]Lit{IntToLit(1), IntToLit(3)})
+	if got := idx.live(IntToLit(2)); len(got) != 0 {
+		t.Errorf("strengthened clause still indexed by its removed lit: %v", lits(pb, got))
+	}
+	idx.live(IntToLit(1))
+	if got := lits(pb, idx.subsets(IntToLit(1), c.signature())); strings.Join(got, ",") != "1 3 0" {
+		t.Errorf("signature of strengthened clause not updated: %v", got)
+	}
+}
+
+func TestIndexCandidates(t *testing.T) {
+	pb := parse(t, "p cnf 4 3\n1 2 0\n1 2 3 0\n1 4 0\n")
+	idx := pb.index()
+	idx.live(IntToLit(1))
+	sig := NewClause([]Lit{IntToLit(1), IntToLit(2)}).signature()
+	if got := lits(pb, idx.supersets(IntToLit(1), sig)); strings.Join(got, ",") != "1 2 0,1 2 3 0" {
+		t.Errorf("invalid supersets of 1 2: %v", got)
+	}
+	sig = NewClause([]Lit{IntToLit(1), IntToLit(2), IntToLit(3)}).signature()
+	if got := lits(pb, idx.subsets(IntToLit(1), sig)); strings.Join(got, ",") != "1 2 0,1 2 3 0" {
+		t.Errorf("invalid subsets of 1 2 3: %v", got)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	pb := parse(t, "p cnf 4 4\n1 2 0\n-1 3 0\n1 2 3 0\n-2 -3 4 0\n")
+	if got := lits(pb, pb.Occurrences(IntToLit(1))); strings.Join(got, ",") != "1 2 0,1 2 3 0" {
+		t.Errorf("invalid occurrences of 1: %v", got)
+	}
+	// Occurrences follow the preprocessing of the problem
+	pb.Preprocess()
+	for lit := Lit(0); lit < Lit(2*pb.NbVars); lit++ {
+		refs := pb.Occurrences(lit)
+		for _, ref := range refs {
+			if c := pb.Clause(ref); c == nil || c.removed || !c.contains(lit) {
+				t.Errorf("invalid occurrence of %d: %v", lit.Int(), c)
+			}
+		}
+		nb := 0
+		for _, c := range pb.Clauses {
+			if c.contains(lit) {
+				nb++
+			}
+		}
+		if nb != len(refs) {
+			t.Errorf("expected %d occurrences of %d, got %d", nb, lit.Int(), len(refs))
+		}
+	}
+	if got := pb.Occurrences(IntToLit(5)); got != nil {
+		t.Errorf("expected no occurrence of a lit out of the problem, got %v", got)
+	}
+}
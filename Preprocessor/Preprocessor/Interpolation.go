@@ -0,0 +1,159 @@
+package Preprocessor
+
+// CRAIG INTERPOLATION
+// Model checkers split an Unsat problem into two sets of clauses, A and B, and compute a Craig interpolant from its proof:
+// a formula I over the vars shared by A and B, such that A implies I and I & B is Unsat. When pb.Options.Interpolation
+// is set, each clause is in partition A or B, see ClauseOptions.Partition, and passes keep an interpolant of the
+// simplified partitions valid for the original ones:
+//  - a derived clause is in the partition of the clauses it comes from, and no clause is derived from both partitions:
+//    vars occurring in both are never eliminated, see eliminable, and clauses only strengthen the clauses of their partition,
+//  - subsumed clauses are removed whatever their partition, since the clause subsuming them is kept,
+//  - techniques that cannot tell where their inferences come from are skipped: probing, vivification, unhiding,
+//    shrinking through the BIG, backbones, pattern detection, fragment solving and polarity canonicalization.
+//    PreprocessParallel runs Fixpoint.
+// Units are shared by both partitions: a unit found in a partition, i.e from clauses of this partition simplified
+// by the previous units, simplifies the clauses of both. An interpolant I of the simplified partitions, units propagated,
+// is completed by going through InterpolationUnits from last to first: I becomes (u & I) for a unit u found in A,
+// and (-u | I) for a unit u found in B. Once the problem is Unsat, I starts as False if the empty clause was found in A,
+// and True if it was found in B, see EmptyPartition.
+
+// A Partition is one of the two sets of clauses of an interpolation problem.
+type Partition uint8
+
+const (
+	PartitionA Partition = iota // Clauses are in A unless stated otherwise.
+	PartitionB
+)
+
+// An InterpolationUnit is a unit of an interpolation problem, and the partition it was found in.
+type InterpolationUnit struct {
+	Lit       Lit
+	Partition Partition
+}
+
+// Partition returns the partition of c, see ClauseOptions.
+func (c *Clause) Partition() Partition {
+	return c.partition
+}
+
+// InterpolationUnits returns the units whose var occurs in both partitions, in the order they were found,
+// along with the partition they were found in. Other units do not appear in interpolants, nor do the units
+// added once the problem is Unsat.
+// Vars occurring in both partitions are those of the clauses added since pb.Options.Interpolation was set,
+// including the ones that were since removed, and of the clauses of the problem at that time.
+// It returns nil unless pb.Options.Interpolation is set.
+func (pb *Problem) InterpolationUnits() []InterpolationUnit {
+	if !pb.Options.Interpolation {
+		return nil
+	}
+	pb.recordPartitionVars(nil, PartitionA)
+	units := pb.Units
+	if pb.Status == Unsat {
+		units = units[:pb.emptyUnits]
+	}
+	var res []InterpolationUnit
+	for _, lit := range units {
+		if v := lit.Var(); pb.partitionVars[PartitionA][v] && pb.partitionVars[PartitionB][v] {
+			res = append(res, InterpolationUnit{Lit: lit, Partition: pb.unitPartition(v)})
+		}
+	}
+	return res
+}
+
+// EmptyPartition returns the partition the empty clause was first found in. It is only meaningful once the problem is Unsat.
+func (pb *Problem) EmptyPartition() Partition {
+	return pb.emptyPartition
+}
+
+// unitPartition returns the partition the unit binding v was found in.
+func (pb *Problem) unitPartition(v Var) Partition {
+	if int(v) < len(pb.unitPartitions) {
+		return pb.unitPartitions[v]
+	}
+	return PartitionA
+}
+
+// setPartition records that the unit or the empty clause made of lits was found in partition p.
+// A unit contradicting a previous one stands for the empty clause. It does nothing unless pb.Options.Interpolation is set.
+func (pb *Problem) setPartition(lits []Lit, p Partition) {
+	if !pb.Options.Interpolation || pb.Status == Unsat {
+		return
+	}
+	if len(lits) == 0 {
+		pb.emptyPartition, pb.emptyUnits = p, len(pb.Units)
+		return
+	}
+	lit := lits[0]
+	switch val := pb.Model[lit.Var()]; {
+	case val == 0:
+		if pb.unitPartitions == nil {
+			pb.unitPartitions = make([]Partition, pb.NbVars)
+		}
+		if partitions, v := pb.unitPartitions, lit.Var(); pb.nbMarks > 0 {
+			old := partitions[v]
+			pb.logUndo(func() { partitions[v] = old })
+		}
+		pb.unitPartitions[lit.Var()] = p
+	case (val == 1) != lit.IsPositive():
+		pb.emptyPartition, pb.emptyUnits = p, len(pb.Units)
+	}
+}
+
+// crossesPartitions returns whether strengthening c2 with c would derive a clause from both partitions.
+func (pb *Problem) crossesPartitions(c, c2 *Clause) bool {
+	return pb.Options.Interpolation && c.partition != c2.partition
+}
+
+// sharedVars returns, for each var, whether it occurs in clauses of both partitions.
+func (pb *Problem) sharedVars() []bool {
+	var occurs [2][]bool
+	for p := range occurs {
+		occurs[p] = make([]bool, pb.NbVars)
+	}
+	res := make([]bool, pb.NbVars)
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			v := lit.Var()
+			occurs[c.partition][v] = true
+			res[v] = occurs[PartitionA][v] && occurs[PartitionB][v]
+		}
+	}
+	return res
+}
+
+// recordPartitionVars records that the vars of lits occur in partition p. The first time it is called, the vars
+// of the clauses, units and eliminated clauses of the problem are recorded too. It does nothing unless
+// pb.Options.Interpolation is set.
+func (pb *Problem) recordPartitionVars(lits []Lit, p Partition) {
+	if !pb.Options.Interpolation {
+		return
+	}
+	if pb.partitionVars[PartitionA] == nil {
+		for q := range pb.partitionVars {
+			pb.partitionVars[q] = make([]bool, pb.NbVars)
+		}
+		for _, c := range pb.Clauses {
+			pb.recordPartitionVars(c.lits, c.partition)
+		}
+		for _, lit := range pb.Units {
+			pb.setPartitionVar(pb.unitPartition(lit.Var()), lit.Var())
+		}
+		for _, e := range pb.eliminated {
+			pb.recordPartitionVars(e.lits, e.partition)
+			for _, lits2 := range e.others {
+				pb.recordPartitionVars(lits2, e.partition)
+			}
+		}
+	}
+	for _, lit := range lits {
+		pb.setPartitionVar(p, lit.Var())
+	}
+}
+
+// setPartitionVar records that v occurs in partition p.
+func (pb *Problem) setPartitionVar(p Partition, v Var) {
+	if vars := pb.partitionVars[p]; !vars[v] {
+		pb.logUndo(func() { vars[v] = false })
+		vars[v] = true
+	}
+}
@@ -0,0 +1,99 @@
+package Preprocessor
+
+import "fmt"
+
+// CheckInvariants returns an error describing the first broken invariant of the problem, or nil if there is none.
+// It scans the whole problem, and is meant to be called from tests, between two passes.
+// Once the problem is Unsat, passes stop as soon as possible, so only units are checked.
+// Clauses may contain lits bound by units, since units are only propagated by Simplify2.
+func (pb *Problem) CheckInvariants() error {
+	if len(pb.Model) != pb.NbVars {
+		return fmt.Errorf("model has %d vars, problem has %d", len(pb.Model), pb.NbVars)
+	}
+	isUnit := make([]bool, pb.NbVars)
+	for _, lit := range pb.Units {
+		v := lit.Var()
+		if int(v) >= pb.NbVars {
+			return fmt.Errorf("unit %d is not a lit of the problem", lit.Int())
+		}
+		if isUnit[v] {
+			return fmt.Errorf("var %d appears in several units", v.Lit().Int())
+		}
+		isUnit[v] = true
+		if pb.Model[v] == 0 || (pb.Model[v] == 1) != lit.IsPositive() {
+			return fmt.Errorf("unit %d is not reflected in the model", lit.Int())
+		}
+	}
+	for v, val := range pb.Model {
+		if val != 0 && !isUnit[v] {
+			return fmt.Errorf("var %d is bound in the model but is not a unit", Var(v).Lit().Int())
+		}
+	}
+	if pb.Status == Unsat {
+		return nil
+	}
+	eliminated := make([]bool, pb.NbVars)
+	for i, e := range pb.eliminated {
+		v := e.lits[0].Var()
+		if int(v) >= pb.NbVars {
+			return fmt.Errorf("eliminated var %d is not a var of the problem", v.Lit().Int())
+		}
+		sameVar := i > 0 && pb.eliminated[i-1].lits[0].Var() == v // Eliminations of a var are contiguous
+		if eliminated[v] && !sameVar {
+			return fmt.Errorf("var %d was eliminated several times", v.Lit().Int())
+		}
+		if sameVar && (pb.eliminated[i-1].lits[0] != e.lits[0] || e.others != nil) {
+			return fmt.Errorf("eliminations of var %d are inconsistent", v.Lit().Int())
+		}
+		eliminated[v] = true
+	}
+	ids := make(map[int]bool, len(pb.Clauses))
+	for i, c := range pb.Clauses {
+		switch c.Len() {
+		case 0:
+			return fmt.Errorf("clause #%d is empty", i)
+		case 1:
+			return fmt.Errorf("clause #%d is the unit %d", i, c.First().Int())
+		}
+		if c.removed {
+			return fmt.Errorf("clause #%d is marked as removed but was not swept", i)
+		}
+		if c.id != 0 && ids[c.id] {
+			return fmt.Errorf("clause #%d has the same ID as another clause, %d", i, c.id)
+		}
+		ids[c.id] = true
+		seen := make(map[Lit]bool, c.Len())
+		sat := false
+		for j, lit := range c.lits {
+			if int(lit.Var()) >= pb.NbVars {
+				return fmt.Errorf("clause #%d contains %d, which is not a lit of the problem", i, lit.Int())
+			}
+			if eliminated[lit.Var()] {
+				return fmt.Errorf("clause #%d contains the eliminated var %d", i, lit.Var().Lit().Int())
+			}
+			if seen[lit] {
+				return fmt.Errorf("clause #%d contains %d twice", i, lit.Int())
+			}
+			if seen[lit.Negation()] {
+				return fmt.Errorf("clause #%d is a tautology on var %d", i, lit.Var().Lit().Int())
+			}
+			if j > 0 && lit < c.lits[j-1] {
+				return fmt.Errorf("clause #%d is not in canonical form", i)
+			}
+			seen[lit] = true
+			if val := pb.Model[lit.Var()]; val != 0 && (val == 1) == lit.IsPositive() {
+				sat = true
+			}
+		}
+		if c.abstraction != 0 && c.abstraction != computeSignature(c.lits) {
+			return fmt.Errorf("clause #%d has a stale signature", i)
+		}
+		if c.bits != nil && !c.sameBits() {
+			return fmt.Errorf("clause #%d has a stale bitset", i)
+		}
+		if pb.Status == Sat && !sat {
+			return fmt.Errorf("problem is Sat but clause #%d is not satisfied by the model", i)
+		}
+	}
+	return nil
+}
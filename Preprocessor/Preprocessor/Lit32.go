@@ -0,0 +1,15 @@
+//go:build !lit64
+// +build !lit64
+
+package Preprocessor
+
+// LITS WIDTH
+// Lits and vars are 32-bit integers by default, which caps problems to 2^30 vars, since each var has two lits.
+// Extreme instances, e.g from model checking, can have more of them: building with the lit64 tag,
+// e.g "go build -tags lit64", makes them 64-bit integers, see Lit64.go, at the cost of twice as much memory for lits.
+
+// LitInt is the integer type of Lit and Var, and of the DIMACS lits given to IntToLit and returned by Lit.Int.
+type LitInt = int32
+
+// MaxVar is the largest number of vars of a problem: the lits of larger vars do not fit in a Lit.
+const MaxVar = 1 << 30
@@ -0,0 +1,11 @@
+//go:build lit64
+// +build lit64
+
+package Preprocessor
+
+// LitInt is the integer type of Lit and Var, and of the DIMACS lits given to IntToLit and returned by Lit.Int.
+// This file is built with the lit64 tag only, see Lit32.go.
+type LitInt = int64
+
+// MaxVar is the largest number of vars of a problem: the lits of larger vars do not fit in a Lit.
+const MaxVar = 1 << 62
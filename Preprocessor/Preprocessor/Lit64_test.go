@@ -0,0 +1,48 @@
+//go:build lit64
+// +build lit64
+
+package Preprocessor
+
+import "testing"
+
+// big is a var number whose lits do not fit in 32 bits, nor in the 5 bytes of a 32-bit varint.
+const big = LitInt(1<<34 + 5)
+
+func TestLit64Conversions(t *testing.T) {
+	for _, val := range []LitInt{big, -big, MaxVar, -MaxVar} {
+		lit := IntToLit(val)
+		if lit.Int() != val {
+			t.Errorf("%d converted to %d", val, lit.Int())
+		}
+		if got := lit.Var().Lit().Int(); got != val && got != -val {
+			t.Errorf("var of %d is %d", val, got)
+		}
+	}
+}
+
+func TestLit64Fingerprint(t *testing.T) {
+	lit := IntToLit(-big)
+	pb := &Problem{NbVars: int(big), Clauses: []*Clause{NewClause([]Lit{IntToLit(1), lit})}}
+	pb2 := &Problem{NbVars: int(big), Clauses: []*Clause{NewClause([]Lit{IntToLit(1), lit.Negation()})}}
+	if pb.Fingerprint() == pb2.Fingerprint() {
+		t.Errorf("clauses with opposite lits have the same fingerprint")
+	}
+	if pb.cacheKey() == pb2.cacheKey() {
+		t.Errorf("clauses with opposite lits have the same cache key")
+	}
+}
+
+func TestLit64Proto(t *testing.T) {
+	lits := []Lit{IntToLit(big), IntToLit(-big), IntToLit(MaxVar)}
+	data := appendLits(nil, 1, lits)
+	var got []Lit
+	err := readFields(data, func(field, wireType int, r *protoReader) error {
+		return r.lits(wireType, &got)
+	})
+	if err != nil {
+		t.Fatalf("could not read lits: %v", err)
+	}
+	if len(got) != len(lits) || got[0] != lits[0] || got[1] != lits[1] || got[2] != lits[2] {
+		t.Errorf("expected %v, got %v", lits, got)
+	}
+}
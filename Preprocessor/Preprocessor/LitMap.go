@@ -0,0 +1,47 @@
+package Preprocessor
+
+// LIT MAPPING
+// Assumptions and queries of users are expressed on the original problem, and must be translated
+// before being given to a solver of the simplified problem, and back for its answers.
+// Vars bound by units or eliminated do not appear in the simplified problem anymore; the other ones keep their number
+// for now, but users should not rely on it, since passes renumbering vars will update the mapping.
+
+// A LitMap translates lits between the original problem and the simplified one.
+// It describes the problem as it was when LitMap was called.
+type LitMap struct {
+	toSimplified []Var // For each original var, its var in the simplified problem, or -1 if it was removed.
+	toOriginal   []Var // For each var of the simplified problem, its original var.
+}
+
+// LitMap returns the mapping between the lits of the original problem and the lits of the simplified one.
+func (pb *Problem) LitMap() *LitMap {
+	m := &LitMap{
+		toSimplified: make([]Var, pb.NbVars),
+		toOriginal:   make([]Var, pb.NbVars),
+	}
+	eliminated := pb.eliminatedVars()
+	for i := range m.toSimplified {
+		if pb.Model[i] != 0 || eliminated[i] {
+			m.toSimplified[i] = -1
+		} else {
+			m.toSimplified[i] = Var(i)
+		}
+		m.toOriginal[i] = Var(i)
+	}
+	return m
+}
+
+// ToSimplified returns the lit of the simplified problem equivalent to the given original lit.
+// If the var of lit was removed, because it is bound by a unit or was eliminated, false is returned:
+// the value of lit is then given by the units of the problem, once its model is complete, see CompleteModel.
+func (m *LitMap) ToSimplified(lit Lit) (Lit, bool) {
+	if int(lit.Var()) >= len(m.toSimplified) || m.toSimplified[lit.Var()] == -1 {
+		return lit, false
+	}
+	return m.toSimplified[lit.Var()].Lit() | lit&1, true
+}
+
+// ToOriginal returns the original lit equivalent to the given lit of the simplified problem.
+func (m *LitMap) ToOriginal(lit Lit) Lit {
+	return m.toOriginal[lit.Var()].Lit() | lit&1
+}
@@ -0,0 +1,150 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"unsafe"
+)
+
+// MEMORY-MAPPED CLAUSES
+// Instances larger than RAM cannot have their lits in the heap. ParseCNFMapped writes
+// the lits of the clauses to a temporary file while it reads them, and maps the file in memory as pb.lits:
+// the OS loads pages of lits when their clauses are visited, and evicts them when memory is short,
+// so that only the clauses themselves stay in RAM. Clauses are put in canonical form before they are written,
+// so that parsing does not touch the mapping afterwards.
+// The mapping is private: a pass strengthening a clause in place writes to a copy of its page, kept apart by the OS,
+// and the file is never modified. Clauses added by passes, and copies of clauses, see Clone, have their own lits, as usual.
+// The file is removed once it is mapped.
+// On systems without mmap, the file is read back in memory instead.
+
+// ParseCNFMapped is like ParseCNFWithOptions, but the lits of the clauses are stored in a memory-mapped file,
+// created in dir, or in the default directory for temporary files if dir is "".
+// The mapping is released by Close.
+func ParseCNFMapped(f io.Reader, dir string, opts Options) (*Problem, error) {
+	tmp, err := ioutil.TempFile(dir, "lits-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	pb, starts, err := parseToFile(f, tmp, opts)
+	if err != nil {
+		return nil, err
+	}
+	nbLits := starts[len(starts)-1]
+	if nbLits > 0 {
+		if pb.mapping, err = mapFile(tmp, nbLits*int(unsafe.Sizeof(Lit(0)))); err != nil {
+			return nil, err
+		}
+		h := (*reflect.SliceHeader)(unsafe.Pointer(&pb.lits))
+		h.Data, h.Len, h.Cap = uintptr(unsafe.Pointer(&pb.mapping[0])), nbLits, nbLits
+	}
+	for i, c := range pb.Clauses {
+		c.lits = pb.lits[starts[i]:starts[i+1]:starts[i+1]]
+	}
+	pb.Simplify2()
+	return pb, nil
+}
+
+// parseToFile parses a CNF file, and writes the lits of its clauses to w, in canonical form.
+// Tautologies are not written. The clauses of the returned problem have no lits yet: the lits of the ith one
+// are those from starts[i] to starts[i+1] in w.
+func parseToFile(f io.Reader, w io.Writer, opts Options) (*Problem, []int, error) {
+	r := bufio.NewReader(f)
+	bw := bufio.NewWriter(w)
+	pb := &Problem{Options: opts, Clauses: make([]*Clause, 0)}
+	starts := []int{0}
+	var (
+		lits          []Lit // Lits of the clause being read.
+		headerWasRead bool
+	)
+	b, err := r.ReadByte()
+	for err == nil {
+		if b == 'c' { // Comment
+			var comment string
+			comment, err = readComment(r)
+			if pb.Options.KeepComments && !headerWasRead {
+				pb.Comments = append(pb.Comments, comment)
+			}
+		} else if b == 'p' { // Parse header
+			var nbClauses int
+			pb.NbVars, nbClauses, err = parseHeader(r)
+			if err != nil {
+				return nil, nil, badInput("cannot parse CNF header: %v", err)
+			}
+			pb.Model = make([]decLevel, pb.NbVars)
+			pb.Clauses = make([]*Clause, 0, nbClauses)
+			starts = make([]int, 1, nbClauses+1)
+			headerWasRead = true
+		} else {
+			lits = lits[:0]
+			for {
+				val, err := readInt(&b, r)
+				if err == io.EOF {
+					if len(lits) != 0 { // This is not a trailing space at the end...
+						return nil, nil, badInput("unfinished clause while EOF found")
+					}
+					break // When there are only several useless spaces at the end of the file, that is ok
+				}
+				if err != nil {
+					return nil, nil, badInput("cannot parse clause: %v", err)
+				}
+				if val != 0 {
+					if val > pb.NbVars || -val > pb.NbVars {
+						return nil, nil, badInput("invalid literal %d for problem with %d vars only", val, pb.NbVars)
+					}
+					lits = append(lits, IntToLit(LitInt(val)))
+					continue
+				}
+				if !headerWasRead {
+					return nil, nil, badInput("clause found before the header")
+				}
+				c := NewClause(lits)
+				c.id = pb.nextID()
+				if c.Simplify() {
+					pb.deleted(c, "tautology")
+					break
+				}
+				if _, err := bw.Write(litBytes(c.lits)); err != nil {
+					return nil, nil, err
+				}
+				pb.Clauses = append(pb.Clauses, &Clause{id: c.id})
+				starts = append(starts, starts[len(starts)-1]+c.Len())
+				break
+			}
+		}
+		b, err = r.ReadByte()
+	}
+	if err != io.EOF {
+		return nil, nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, nil, err
+	}
+	return pb, starts, nil
+}
+
+// litBytes returns the memory holding the given lits, as bytes.
+func litBytes(lits []Lit) []byte {
+	var res []byte
+	if len(lits) > 0 {
+		h := (*reflect.SliceHeader)(unsafe.Pointer(&res))
+		n := len(lits) * int(unsafe.Sizeof(Lit(0)))
+		h.Data, h.Len, h.Cap = uintptr(unsafe.Pointer(&lits[0])), n, n
+	}
+	return res
+}
+
+// Close releases the memory mapping holding the lits of the problem, if it was parsed by ParseCNFMapped.
+// The problem must not be used afterwards; copies made by Clone can.
+func (pb *Problem) Close() error {
+	if pb.mapping == nil {
+		return nil
+	}
+	mapping := pb.mapping
+	pb.mapping, pb.lits, pb.Clauses = nil, nil, nil
+	return unmapFile(mapping)
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package Preprocessor
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the first size bytes of f, since memory mappings are not supported on this system.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	res := make([]byte, size)
+	if _, err := f.ReadAt(res, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return res, nil
+}
+
+// unmapFile releases a buffer returned by mapFile.
+func unmapFile(mapping []byte) error {
+	return nil
+}
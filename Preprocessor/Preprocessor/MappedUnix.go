@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package Preprocessor
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of f in memory, privately: writes to the mapping do not reach the file.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+}
+
+// unmapFile releases a mapping returned by mapFile.
+func unmapFile(mapping []byte) error {
+	return syscall.Munmap(mapping)
+}
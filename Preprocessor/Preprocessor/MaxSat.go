@@ -0,0 +1,143 @@
+package Preprocessor
+
+import (
+	"log"
+	"sort"
+)
+
+// OPTIMISATION PROBLEMS
+// As in gophersat, an optimisation problem minimizes the sum of the weights of the cost lits that are true.
+// A soft clause C is relaxed into the hard clause (C | b), where the cost lit b is a fresh var:
+// b is true when C is violated. All passes preserve the models of the problem, so they preserve its optimum.
+// In particular, a hard clause can subsume a soft clause (C | b), but a soft clause only subsumes clauses that contain b,
+// i.e other soft clauses.
+// Once a soft clause is removed, because a hard clause subsumes it, its cost lit appears in no clause anymore:
+// it is set to false, so that the soft clause is credited nothing.
+
+// SetCostFunc sets the function to minimize: the sum of the weights of the given lits that are true.
+// If all weights are 1, weights can be nil. Otherwise, len(weights) must be len(lits).
+func (pb *Problem) SetCostFunc(lits []Lit, weights []int) {
+	pb.minLits = append([]Lit(nil), lits...)
+	pb.minWeights = append([]int(nil), weights...)
+}
+
+// CostLits returns the lits whose sum must be minimized, and their weights, or nil if the problem is not an optimisation problem.
+func (pb *Problem) CostLits() (lits []Lit, weights []int) {
+	return pb.minLits, pb.minWeights
+}
+
+// clearUnusedCostLits sets to false the unbound cost lits with a positive weight that appear in no clause:
+// setting them to true cannot satisfy any clause, and only costs more.
+// The resulting units are not implied by the clauses, so nothing is done while an LRAT proof is written.
+func (pb *Problem) clearUnusedCostLits() {
+	if len(pb.minLits) == 0 || pb.Status != Undetermined || pb.proof() != nil {
+		return
+	}
+	used := make([]bool, pb.NbVars*2)
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			used[lit] = true
+		}
+	}
+	nbCleared := 0
+	for i, lit := range pb.minLits {
+		if used[lit] || pb.Model[lit.Var()] != 0 || (pb.minWeights != nil && pb.minWeights[i] <= 0) {
+			continue
+		}
+		pb.derivedUnit(lit.Negation(), "cost")
+		pb.addUnit(lit.Negation())
+		nbCleared++
+	}
+	if nbCleared > 0 {
+		log.Printf("%d unused cost lits set to false", nbCleared)
+	}
+}
+
+// MineAtMostOnes looks for sets of cost lits among which at most one can be true, i.e at most one of the corresponding
+// soft clauses can be violated, and records them, see AtMostOnes. Core-guided MaxSAT solvers can use them to
+// relax many soft clauses at once. Two cost lits are exclusive if propagating one of them falsifies the other;
+// sets are cliques of exclusive cost lits, found greedily. Each cost lit belongs to at most one set.
+func (pb *Problem) MineAtMostOnes() {
+	if len(pb.minLits) == 0 || pb.Status != Undetermined || pb.skipped("AMO mining") {
+		return
+	}
+	isCost := make(map[Lit]bool, len(pb.minLits))
+	for _, lit := range pb.minLits {
+		if pb.Model[lit.Var()] == 0 {
+			isCost[lit] = true
+		}
+	}
+	exclusive := make(map[Lit]map[Lit]bool, len(isCost))
+	addEdge := func(l1, l2 Lit) {
+		if exclusive[l1] == nil {
+			exclusive[l1] = make(map[Lit]bool)
+		}
+		exclusive[l1][l2] = true
+	}
+	p := pb.propagator()
+	for _, lit := range pb.minLits {
+		if !isCost[lit] || !p.assume(lit) {
+			continue
+		}
+		if p.propagate() == noClause {
+			for _, lit2 := range p.trail[p.level0:] {
+				if isCost[lit2.Negation()] {
+					// Exclusion is symmetric, even if propagation does not find it from lit2
+					addEdge(lit, lit2.Negation())
+					addEdge(lit2.Negation(), lit)
+				}
+			}
+		}
+		p.backtrack()
+	}
+	// Lits with many exclusive lits first, in the order of minLits otherwise
+	lits := make([]Lit, 0, len(exclusive))
+	listed := make(map[Lit]bool, len(exclusive))
+	for _, lit := range pb.minLits {
+		if exclusive[lit] != nil && !listed[lit] {
+			lits = append(lits, lit)
+			listed[lit] = true
+		}
+	}
+	sort.SliceStable(lits, func(i, j int) bool { return len(exclusive[lits[i]]) > len(exclusive[lits[j]]) })
+	used := make(map[Lit]bool, len(lits))
+	pb.amos = nil
+	for _, lit := range lits {
+		if used[lit] {
+			continue
+		}
+		amo := []Lit{lit}
+		for _, lit2 := range lits {
+			if used[lit2] || !exclusive[lit][lit2] {
+				continue
+			}
+			inClique := true
+			for _, lit3 := range amo[1:] {
+				if !exclusive[lit3][lit2] {
+					inClique = false
+					break
+				}
+			}
+			if inClique {
+				amo = append(amo, lit2)
+			}
+		}
+		if len(amo) >= 2 {
+			for _, lit2 := range amo {
+				used[lit2] = true
+			}
+			pb.amos = append(pb.amos, amo)
+		}
+	}
+	log.Printf("%d at-most-one constraints found among %d cost lits", len(pb.amos), len(isCost))
+}
+
+// AtMostOnes returns the at-most-one constraints found by the last call to MineAtMostOnes:
+// at most one lit of each returned set can be true.
+func (pb *Problem) AtMostOnes() [][]Lit {
+	res := make([][]Lit, len(pb.amos))
+	for i, amo := range pb.amos {
+		res[i] = append([]Lit(nil), amo...)
+	}
+	return res
+}
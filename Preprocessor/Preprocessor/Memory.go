@@ -0,0 +1,105 @@
+package Preprocessor
+
+import (
+	"log"
+	"runtime"
+	"unsafe"
+)
+
+// MEMORY BUDGET
+// On huge instances, occurrence lists, resolvents or binary implication graphs can exhaust the memory of the machine.
+// When pb.Options.MaxMemoryMB is set, passes charge their big allocations to a memory accountant before doing them,
+// and stop early, or skip their work entirely, once the budget is exhausted, leaving the problem simplified but sound.
+// Charges are estimations: the actual size of the heap is measured once every measureEvery charged bytes.
+
+// measureEvery is the number of bytes that can be charged before the heap is measured again.
+const measureEvery = 1 << 20
+
+// A memAccountant estimates the size of the heap.
+type memAccountant struct {
+	limit   uint64 // Max size of the heap, in bytes.
+	heap    uint64 // Size of the heap at the last measure.
+	charged uint64 // Bytes charged since the last measure.
+	// collected is true iff the heap was collected since the last charge, so that its size does not include garbage.
+	collected bool
+}
+
+// memory returns the memory accountant of the problem, or nil if pb.Options.MaxMemoryMB is not set.
+// All methods of memAccountant accept a nil receiver.
+func (pb *Problem) memory() *memAccountant {
+	if pb.Options.MaxMemoryMB <= 0 {
+		return nil
+	}
+	if pb.mem == nil {
+		pb.mem = &memAccountant{}
+		pb.mem.measure()
+	}
+	pb.mem.limit = uint64(pb.Options.MaxMemoryMB) << 20
+	return pb.mem
+}
+
+// measure updates the size of the heap.
+func (m *memAccountant) measure() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	m.heap = stats.HeapAlloc
+	m.charged = 0
+}
+
+// charge records that the given number of bytes is about to be allocated.
+func (m *memAccountant) charge(bytes int) {
+	if m == nil || bytes <= 0 {
+		return
+	}
+	if m.charged+uint64(bytes) > measureEvery {
+		m.measure()
+	}
+	m.charged += uint64(bytes)
+	m.collected = false
+}
+
+// exhausted is true iff the memory budget was exceeded.
+func (m *memAccountant) exhausted() bool {
+	if m == nil || m.heap+m.charged <= m.limit {
+		return false
+	}
+	if !m.collected { // Only give up if the budget is still exceeded once garbage is collected
+		runtime.GC()
+		m.measure()
+		m.collected = true
+	}
+	return m.heap+m.charged > m.limit
+}
+
+// skipped logs that a pass is skipped because the memory budget is exhausted, and returns true, if so.
+// Memory may have been freed since the previous pass, so the heap is measured again.
+func (pb *Problem) skipped(pass string) bool {
+	m := pb.memory()
+	if m == nil {
+		return false
+	}
+	m.measure()
+	m.collected = false
+	if !m.exhausted() {
+		return false
+	}
+	log.Printf("Memory budget exhausted, %s skipped", pass)
+	return true
+}
+
+// clauseSize is the estimated size of a clause with nbLits lits.
+func clauseSize(nbLits int) int {
+	return int(unsafe.Sizeof(Clause{})) + nbLits*int(unsafe.Sizeof(Lit(0)))
+}
+
+// propagatorSize is the estimated size of a propagator, for nbVars vars and nbClauses clauses.
+func propagatorSize(nbVars, nbClauses int) int {
+	perVar := 2*int(unsafe.Sizeof([]int(nil))) + int(unsafe.Sizeof(decLevel(0))) + int(unsafe.Sizeof(0)) + int(unsafe.Sizeof(Lit(0)))
+	perClause := int(unsafe.Sizeof(&Clause{})) + int(unsafe.Sizeof(true)) + 4*int(unsafe.Sizeof(0))
+	return nbVars*perVar + nbClauses*perClause
+}
+
+// occurrencesSize is the estimated size of occurrence lists, for nbVars vars and nbLits lits in clauses.
+func occurrencesSize(nbVars, nbLits int) int {
+	return 2*nbVars*int(unsafe.Sizeof([]int(nil))) + nbLits*int(unsafe.Sizeof(0))
+}
@@ -0,0 +1,75 @@
+package Preprocessor
+
+// MERGING PROBLEMS
+// Encodings are often built from several CNF fragments, each numbering its vars from 1.
+// Merge puts them side by side: the vars of each problem are shifted by the number of vars of the problems before it,
+// so that fragments never share vars. Fragments that must share vars should be given the same numbering instead,
+// and their clauses added to a single problem.
+
+// Merge returns a new problem made of the units, clauses, eliminated clauses, gates, cost functions and lit weights
+// of the given problems.
+// Var v of problems[i] becomes var v + problems[0].NbVars + ... + problems[i-1].NbVars.
+// The given problems are not modified. The options and the history of clauses are not kept:
+// clauses of the merged problem are numbered from 1, in order. Units are propagated, as ParseCNF does.
+func Merge(problems ...*Problem) *Problem {
+	nbVars := 0
+	for _, pb := range problems {
+		nbVars += pb.NbVars
+	}
+	res := NewProblem(nbVars)
+	offset := Lit(0)
+	shift := func(lits []Lit) []Lit {
+		shifted := make([]Lit, len(lits))
+		for i, lit := range lits {
+			shifted[i] = lit + offset
+		}
+		return shifted
+	}
+	weighted := false
+	for _, pb := range problems {
+		weighted = weighted || pb.minWeights != nil
+	}
+	for _, pb := range problems {
+		if pb.Status == Unsat {
+			res.AddClause(nil)
+		}
+		for _, lit := range pb.Units {
+			res.AddClause([]Lit{lit + offset})
+		}
+		for _, c := range pb.Clauses {
+			res.AddClause(shift(c.lits))
+		}
+		for _, e := range pb.eliminated {
+			e2 := elimination{lits: shift(e.lits)}
+			for _, lits := range e.others {
+				e2.others = append(e2.others, shift(lits))
+			}
+			res.eliminated = append(res.eliminated, e2)
+		}
+		for _, g := range pb.Gates {
+			res.Gates = append(res.Gates, Gate{Kind: g.Kind, Out: g.Out + offset, In: shift(g.In)})
+		}
+		res.minLits = append(res.minLits, shift(pb.minLits)...)
+		if weighted {
+			for i := range pb.minLits {
+				w := 1
+				if pb.minWeights != nil {
+					w = pb.minWeights[i]
+				}
+				res.minWeights = append(res.minWeights, w)
+			}
+		}
+		for lit, w := range pb.LitWeights {
+			if res.LitWeights == nil {
+				res.LitWeights = make(map[Lit]float64)
+			}
+			res.LitWeights[lit+offset] = w
+		}
+		offset += Lit(2 * pb.NbVars)
+	}
+	if len(res.minLits) == 0 {
+		res.minLits = nil
+	}
+	res.Simplify2()
+	return res
+}
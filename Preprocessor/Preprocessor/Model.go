@@ -0,0 +1,123 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// MODELS
+// When preprocessing alone decides the problem, the units it found are the model,
+// once the eliminated vars are bound too, see extendModel.
+// They are written in the format of the SAT competition, so that the preprocessor can be used as a solver.
+
+// vLineWidth is the max length of a "v" line, as required by the SAT competition.
+const vLineWidth = 78
+
+// WriteModel writes the values of the vars bound by units, in the "v 1 -2 3 ... 0" format of the SAT competition.
+// Long models are split on several "v" lines. Vars that are not bound are omitted.
+// Vars flipped by CanonicalizePolarity are written with their original polarity.
+func (pb *Problem) WriteModel(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := "v"
+	for v, val := range pb.Model {
+		if val == 0 {
+			continue
+		}
+		if pb.Flipped(Var(v)) {
+			val = -val
+		}
+		lit := Var(v).Lit()
+		if val == -1 {
+			lit = lit.Negation()
+		}
+		s := fmt.Sprintf(" %d", lit.Int())
+		if len(line)+len(s) > vLineWidth {
+			fmt.Fprintln(bw, line)
+			line = "v"
+		}
+		line += s
+	}
+	fmt.Fprintln(bw, line+" 0")
+	return bw.Flush()
+}
+
+// CompleteModel binds every unbound var once the problem is Sat, so that the units of the problem are a full model.
+// No clause is left, so any value works: cost lits with a positive weight are set to false, so that the model is optimal,
+// other vars get their phase hint, or false if there is none, and eliminated vars get the value their clauses need.
+// Nothing is done, and false is returned, unless the problem is Sat.
+// The units it adds are choices, not consequences of the clauses, so they are not recorded in the provenance nor the proof.
+func (pb *Problem) CompleteModel() bool {
+	if pb.Status != Sat {
+		return false
+	}
+	for i, lit := range pb.minLits {
+		if pb.Model[lit.Var()] == 0 && (pb.minWeights == nil || pb.minWeights[i] > 0) {
+			pb.addUnit(lit.Negation())
+		}
+	}
+	eliminated := pb.eliminatedVars()
+	for v, hint := range pb.PhaseHints() {
+		if pb.Model[v] != 0 || eliminated[v] {
+			continue
+		}
+		if hint == 1 {
+			pb.addUnit(Var(v).Lit())
+		} else {
+			pb.addUnit(Var(v).Lit().Negation())
+		}
+	}
+	pb.extendModel()
+	return true
+}
+
+// WriteElimMap writes the elimination map of the problem, in the style of the files SatELite and MiniSat use to extend
+// the models of a simplified CNF: one removed clause per line, pivot lit first, in DIMACS format.
+// A model of the simplified problem is extended by reading the clauses from last to first,
+// and setting the pivot to true whenever the clause is not satisfied.
+// Vars are never renamed: they leave the problem when they are eliminated, and their clauses are written first,
+// in the order they were removed, or when they are bound, and each unit is then written as a clause of its own.
+func (pb *Problem) WriteElimMap(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c elimination map: %d vars, %d clauses\n", pb.NbVars, len(pb.eliminated)+len(pb.Units))
+	for _, e := range pb.eliminated {
+		fmt.Fprintf(bw, "%s\n", NewClause(e.lits).CNF())
+	}
+	for _, lit := range pb.Units {
+		fmt.Fprintf(bw, "%d 0\n", lit.Int())
+	}
+	return bw.Flush()
+}
+
+// WriteExtensionStack writes the reconstruction information of the problem as the extension stack of CaDiCaL and Kissat:
+// a sequence of pairs of a witness and a clause, one pair per line, as "<witness lits> 0 <clause lits> 0" in DIMACS format.
+// A model of the simplified CNF is extended by reading the pairs from last to first, and flipping the lits of the witness
+// to true whenever the clause is not satisfied. Each clause removed along with its var is written with its pivot as witness,
+// in the order they were removed, and the clauses of each pivot are followed by its negation, witnessed by itself:
+// as in extendModel, the pivot is thus first set to false, then to true iff a clause needs it. Each unit is then
+// written as a clause of its own, witnessed by itself, so that models of the CNF written with Options.OmitUnits
+// are extended too.
+func (pb *Problem) WriteExtensionStack(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	last := func(i int) bool { // Whether the ith elimination is the last one of its pivot
+		return i == len(pb.eliminated)-1 || pb.eliminated[i+1].lits[0] != pb.eliminated[i].lits[0]
+	}
+	nbPairs := len(pb.eliminated) + len(pb.Units)
+	for i := range pb.eliminated {
+		if last(i) {
+			nbPairs++
+		}
+	}
+	fmt.Fprintf(bw, "c extension stack: %d vars, %d clauses\n", pb.NbVars, nbPairs)
+	for i, e := range pb.eliminated {
+		pivot := e.lits[0]
+		fmt.Fprintf(bw, "%d 0 %s\n", pivot.Int(), NewClause(e.lits).CNF())
+		if last(i) {
+			fmt.Fprintf(bw, "%d 0 %d 0\n", pivot.Negation().Int(), pivot.Negation().Int())
+		}
+	}
+	for _, lit := range pb.Units {
+		fmt.Fprintf(bw, "%d 0 %d 0\n", lit.Int(), lit.Int())
+	}
+	return bw.Flush()
+}
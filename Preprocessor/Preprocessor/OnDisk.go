@@ -0,0 +1,537 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// ON-DISK REWRITING
+// ParseCNFMapped keeps the lits of huge formulas out of the heap, but still needs a Clause per clause in RAM.
+// RewriteCNFOnDisk does not build a problem at all: it rewrites a CNF file through temporary files, and only keeps
+// in RAM the value of each var, one byte per var, and a bounded batch of clauses. The clauses are first copied to a
+// temporary file, in canonical form and without tautologies. Each round then reads the clauses of the last file,
+// drops those satisfied by a unit, strips them of their false lits, and writes the others to a new file.
+// Units found during a round only apply to the clauses read after them, so rounds go on until one finds no new unit.
+// Clauses are then sorted by an external merge sort, in runs of at most opts.MaxMemoryMB, so that duplicate clauses
+// are next to each other and are written once. The result is equivalent to the input: it can be preprocessed
+// further, e.g by ParseCNFMapped and Preprocess, once it fits in RAM.
+// Temporary files hold each clause as its number of lits followed by its lits, as varints.
+
+// defaultDiskMemoryMB is the memory used by the runs of the sort, in MB, if DiskOptions.MaxMemoryMB is 0.
+const defaultDiskMemoryMB = 256
+
+// DiskOptions are the options of RewriteCNFOnDisk.
+type DiskOptions struct {
+	Dir          string // Directory of the temporary files, the default directory for temporary files if "".
+	MaxMemoryMB  int    // Memory used by the runs of the sort, in MB, defaultDiskMemoryMB if 0.
+	KeepComments bool   // Whether the comments before the header are written before the header of the result.
+}
+
+// A DiskReport describes what RewriteCNFOnDisk did.
+type DiskReport struct {
+	Status     Status // Sat if no clause but units is left, Unsat if the empty clause was found.
+	NbVars     int
+	NbClauses  int // Clauses written, units included.
+	NbUnits    int // Units written.
+	Rounds     int // Rounds of propagation of the units.
+	Duplicates int // Duplicate clauses removed.
+}
+
+// RewriteCNFOnDisk reads a CNF file from f and writes an equivalent CNF file to w, where units were propagated
+// and duplicate clauses removed, with a bounded amount of memory besides a byte per var, see above.
+func RewriteCNFOnDisk(f io.Reader, w io.Writer, opts DiskOptions) (DiskReport, error) {
+	d := &diskRewriter{opts: opts}
+	defer d.removeFiles()
+	if err := d.rewrite(f, w); err != nil {
+		return DiskReport{}, err
+	}
+	return d.report, nil
+}
+
+// A diskRewriter holds the state of RewriteCNFOnDisk.
+type diskRewriter struct {
+	opts     DiskOptions
+	report   DiskReport
+	comments []string
+	model    []int8   // 1 if the var is true, -1 if it is false, 0 if it is unbound.
+	newUnits bool     // Whether units were found since the start of the current round.
+	files    []string // Temporary files, removed at the end.
+}
+
+// rewrite reads the problem from f, simplifies it and writes it to w.
+func (d *diskRewriter) rewrite(f io.Reader, w io.Writer) error {
+	name, err := d.parse(f)
+	for err == nil && d.report.Status != Unsat && d.newUnits {
+		d.report.Rounds++
+		name, err = d.propagate(name)
+	}
+	if err == nil && d.report.Status != Unsat {
+		name, err = d.sortUnique(name)
+	}
+	if err != nil {
+		return err
+	}
+	return d.write(name, w)
+}
+
+// parse copies the clauses of the CNF file to a temporary file, simplified by the units found so far,
+// and returns the name of that file.
+func (d *diskRewriter) parse(f io.Reader) (string, error) {
+	out, err := d.create()
+	if err != nil {
+		return "", err
+	}
+	r := bufio.NewReader(f)
+	var (
+		lits          []Lit // Lits of the clause being read.
+		headerWasRead bool
+	)
+	b, err := r.ReadByte()
+	for err == nil {
+		if b == 'c' { // Comment
+			var comment string
+			comment, err = readComment(r)
+			if d.opts.KeepComments && !headerWasRead {
+				d.comments = append(d.comments, comment)
+			}
+		} else if b == 'p' { // Parse header
+			d.report.NbVars, _, err = parseHeader(r)
+			if err != nil {
+				out.close()
+				return "", badInput("cannot parse CNF header: %v", err)
+			}
+			d.model = make([]int8, d.report.NbVars)
+			headerWasRead = true
+		} else {
+			lits = lits[:0]
+			for {
+				val, err := readInt(&b, r)
+				if err == io.EOF {
+					if len(lits) != 0 { // This is not a trailing space at the end...
+						out.close()
+						return "", badInput("unfinished clause while EOF found")
+					}
+					break // When there are only several useless spaces at the end of the file, that is ok
+				}
+				if err != nil {
+					out.close()
+					return "", badInput("cannot parse clause: %v", err)
+				}
+				if val == 0 {
+					if !headerWasRead {
+						out.close()
+						return "", badInput("clause found before the header")
+					}
+					c := NewClause(lits)
+					if c.Simplify() {
+						break
+					}
+					if err := d.add(c.lits, out); err != nil {
+						out.close()
+						return "", err
+					}
+					break
+				}
+				if val > d.report.NbVars || -val > d.report.NbVars {
+					out.close()
+					return "", badInput("invalid literal %d for problem with %d vars only", val, d.report.NbVars)
+				}
+				lits = append(lits, IntToLit(LitInt(val)))
+			}
+		}
+		if d.report.Status == Unsat {
+			break
+		}
+		b, err = r.ReadByte()
+	}
+	if err != nil && err != io.EOF {
+		out.close()
+		return "", err
+	}
+	return out.name(), out.close()
+}
+
+// propagate copies the clauses of the given temporary file to a new one, simplified by the units found so far,
+// and returns the name of the new file. The given file is removed.
+func (d *diskRewriter) propagate(name string) (string, error) {
+	in, err := openClauses(name)
+	if err != nil {
+		return "", err
+	}
+	defer d.remove(in)
+	out, err := d.create()
+	if err != nil {
+		return "", err
+	}
+	d.newUnits = false
+	var lits []Lit
+	for d.report.Status != Unsat {
+		if lits, err = in.read(lits); err != nil {
+			break
+		}
+		if err = d.add(lits, out); err != nil {
+			break
+		}
+	}
+	if err != nil && err != io.EOF {
+		out.close()
+		return "", err
+	}
+	return out.name(), out.close()
+}
+
+// add writes the clause, in canonical form, to out, unless it is satisfied by a unit, once stripped of its false lits.
+// A unit is recorded in the model instead, and the empty clause makes the problem Unsat.
+func (d *diskRewriter) add(lits []Lit, out *clauseWriter) error {
+	n := 0
+	for _, lit := range lits {
+		switch d.value(lit) {
+		case 1:
+			return nil
+		case 0:
+			lits[n] = lit
+			n++
+		}
+	}
+	lits = lits[:n]
+	switch len(lits) {
+	case 0:
+		d.report.Status = Unsat
+	case 1:
+		if lits[0].IsPositive() {
+			d.model[lits[0].Var()] = 1
+		} else {
+			d.model[lits[0].Var()] = -1
+		}
+		d.newUnits = true
+	default:
+		return out.write(lits)
+	}
+	return nil
+}
+
+// value returns 1 if lit is true, -1 if it is false, 0 if its var is unbound.
+func (d *diskRewriter) value(lit Lit) int8 {
+	if lit.IsPositive() {
+		return d.model[lit.Var()]
+	}
+	return -d.model[lit.Var()]
+}
+
+// sortUnique sorts the clauses of the given temporary file, without duplicates, to a new file,
+// and returns the name of the new file. The given file is removed.
+func (d *diskRewriter) sortUnique(name string) (string, error) {
+	budget := d.opts.MaxMemoryMB
+	if budget <= 0 {
+		budget = defaultDiskMemoryMB
+	}
+	in, err := openClauses(name)
+	if err != nil {
+		return "", err
+	}
+	defer d.remove(in)
+	var (
+		runs  []string // Sorted runs written so far.
+		batch [][]Lit  // Clauses of the current run.
+		size  int      // Estimated size of the current run.
+		lits  []Lit
+	)
+	for {
+		if lits, err = in.read(lits); err != nil && err != io.EOF {
+			return "", err
+		}
+		if err == nil {
+			batch = append(batch, append([]Lit(nil), lits...))
+			size += clauseSize(len(lits))
+		}
+		if size >= budget<<20 || err == io.EOF && (len(batch) > 0 || len(runs) == 0) {
+			run, err := d.writeRun(batch)
+			if err != nil {
+				return "", err
+			}
+			runs = append(runs, run)
+			batch, size = batch[:0], 0
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(runs) == 1 {
+		return runs[0], nil
+	}
+	return d.merge(runs)
+}
+
+// writeRun sorts the given clauses, and writes them without duplicates to a new temporary file, whose name is returned.
+func (d *diskRewriter) writeRun(batch [][]Lit) (string, error) {
+	sort.Slice(batch, func(i, j int) bool { return compareLits(batch[i], batch[j]) < 0 })
+	out, err := d.create()
+	if err != nil {
+		return "", err
+	}
+	for i, lits := range batch {
+		if i > 0 && compareLits(batch[i-1], lits) == 0 {
+			d.report.Duplicates++
+			continue
+		}
+		if err := out.write(lits); err != nil {
+			out.close()
+			return "", err
+		}
+	}
+	return out.name(), out.close()
+}
+
+// merge merges the given sorted runs, without duplicates, to a new temporary file, whose name is returned.
+// The runs are removed.
+func (d *diskRewriter) merge(runs []string) (string, error) {
+	var h runHeap
+	defer func() {
+		for _, head := range h {
+			d.remove(head.r)
+		}
+	}()
+	for _, run := range runs {
+		r, err := openClauses(run)
+		if err != nil {
+			return "", err
+		}
+		head := &runHead{r: r}
+		if head.lits, err = r.read(nil); err == io.EOF {
+			d.remove(r)
+			continue
+		} else if err != nil {
+			d.remove(r)
+			return "", err
+		}
+		h = append(h, head)
+	}
+	heap.Init(&h)
+	out, err := d.create()
+	if err != nil {
+		return "", err
+	}
+	var last []Lit // Last clause written.
+	for len(h) > 0 {
+		head := h[0]
+		if out.nb > 0 && compareLits(last, head.lits) == 0 {
+			d.report.Duplicates++
+		} else {
+			if err := out.write(head.lits); err != nil {
+				out.close()
+				return "", err
+			}
+			last = append(last[:0], head.lits...)
+		}
+		if head.lits, err = head.r.read(head.lits); err == io.EOF {
+			d.remove(heap.Pop(&h).(*runHead).r)
+		} else if err != nil {
+			out.close()
+			return "", err
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	return out.name(), out.close()
+}
+
+// write writes the units and the clauses of the given temporary file to w, in the DIMACS CNF format.
+func (d *diskRewriter) write(name string, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, comment := range d.comments {
+		fmt.Fprintf(bw, "c%s\n", comment)
+	}
+	if d.report.Status == Unsat {
+		d.report.NbClauses = 1
+		fmt.Fprintf(bw, "p cnf %d 1\n0\n", d.report.NbVars)
+		return bw.Flush()
+	}
+	in, err := openClauses(name)
+	if err != nil {
+		return err
+	}
+	defer in.close()
+	nbClauses := 0
+	var lits []Lit
+	for {
+		if lits, err = in.read(lits); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		nbClauses++
+	}
+	for _, val := range d.model {
+		if val != 0 {
+			d.report.NbUnits++
+		}
+	}
+	d.report.NbClauses = nbClauses + d.report.NbUnits
+	if nbClauses == 0 {
+		d.report.Status = Sat
+	}
+	fmt.Fprintf(bw, "p cnf %d %d\n", d.report.NbVars, d.report.NbClauses)
+	for v, val := range d.model {
+		if val != 0 {
+			lit := Var(v).Lit()
+			if val < 0 {
+				lit = lit.Negation()
+			}
+			fmt.Fprintf(bw, "%d 0\n", lit.Int())
+		}
+	}
+	if err := in.rewind(); err != nil {
+		return err
+	}
+	for {
+		if lits, err = in.read(lits); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "%s0\n", dimacsLits(lits))
+	}
+	return bw.Flush()
+}
+
+// create creates a new temporary file of clauses.
+func (d *diskRewriter) create() (*clauseWriter, error) {
+	f, err := ioutil.TempFile(d.opts.Dir, "clauses-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	d.files = append(d.files, f.Name())
+	return &clauseWriter{f: f, bw: bufio.NewWriter(f)}, nil
+}
+
+// remove closes and removes a temporary file that is not needed anymore.
+func (d *diskRewriter) remove(r *clauseReader) {
+	r.close()
+	os.Remove(r.f.Name())
+}
+
+// removeFiles removes all the temporary files.
+func (d *diskRewriter) removeFiles() {
+	for _, name := range d.files {
+		os.Remove(name)
+	}
+}
+
+// A clauseWriter writes clauses to a temporary file, see above.
+type clauseWriter struct {
+	f   *os.File
+	bw  *bufio.Writer
+	buf []byte
+	nb  int // Number of clauses written.
+}
+
+func (w *clauseWriter) write(lits []Lit) error {
+	w.buf = appendVarint(w.buf[:0], uint64(len(lits)))
+	for _, lit := range lits {
+		w.buf = appendVarint(w.buf, uint64(lit))
+	}
+	w.nb++
+	_, err := w.bw.Write(w.buf)
+	return err
+}
+
+func (w *clauseWriter) name() string {
+	return w.f.Name()
+}
+
+// close flushes and closes the file.
+func (w *clauseWriter) close() error {
+	if err := w.bw.Flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// A clauseReader reads the clauses of a temporary file, see above.
+type clauseReader struct {
+	f  *os.File
+	br *bufio.Reader
+}
+
+func openClauses(name string) (*clauseReader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &clauseReader{f: f, br: bufio.NewReader(f)}, nil
+}
+
+// read reads the lits of the next clause to lits, and returns them. It returns io.EOF if no clause is left.
+func (r *clauseReader) read(lits []Lit) ([]Lit, error) {
+	n, err := binary.ReadUvarint(r.br)
+	if err != nil {
+		return nil, err
+	}
+	lits = lits[:0]
+	for i := uint64(0); i < n; i++ {
+		x, err := binary.ReadUvarint(r.br)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		lits = append(lits, Lit(x))
+	}
+	return lits, nil
+}
+
+// rewind reads the file from its start again.
+func (r *clauseReader) rewind() error {
+	if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.br.Reset(r.f)
+	return nil
+}
+
+func (r *clauseReader) close() error {
+	return r.f.Close()
+}
+
+// A runHead is the next clause of a sorted run, see diskRewriter.merge.
+type runHead struct {
+	r    *clauseReader
+	lits []Lit
+}
+
+// A runHeap is a min-heap of run heads, by their clauses.
+type runHeap []*runHead
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return compareLits(h[i].lits, h[j].lits) < 0 }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runHead)) }
+
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// compareLits compares the lits of two clauses in lexicographic order: it returns a negative number if a comes first,
+// a positive one if b does, 0 if they are equal. A clause comes before the clauses it is a prefix of.
+func compareLits(a, b []Lit) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
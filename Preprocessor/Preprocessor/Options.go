@@ -0,0 +1,86 @@
+package Preprocessor
+
+import (
+	"io"
+	"time"
+)
+
+// Options tunes the preprocessing techniques. The zero value gives the default behavior.
+type Options struct {
+	Provenance    bool      // If true, the derivation and deletion of clauses is recorded, see Problem.Provenance. Set it at parse time.
+	LRAT          io.Writer // If not nil, an LRAT proof of the simplifications is written to it, see Problem.ProofErr. Set it at parse time.
+	DeltaWriter   io.Writer // If not nil, each clause added, strengthened or deleted is logged to it, see Problem.DeltaErr.
+	EventWriter   io.Writer // If not nil, passes and rounds are logged to it as JSON lines, see Events.go and Problem.EventErr.
+	CacheDir      string    // If not empty, Preprocess stores simplified problems in this directory, and reads them back for the same input, see Cache.go.
+	Interpolation bool      // If true, clauses are in partition A or B, and passes keep interpolants valid, see Interpolation.go. Set it at parse time.
+	KeepComments  bool      // If true, the comment lines met before the header are kept in Problem.Comments. Set it at parse time.
+	DryRun        bool      // If true, Preprocess, Fixpoint and PreprocessParallel only report what their passes would do, see DryRun.go.
+	Seed          int64     // Seed of the random number generator used by randomized techniques. Set it before the first of them is run.
+
+	// Resources
+	MaxMemoryMB  int     // Max size of the heap, in MB. Passes stop early or are skipped rather than exceed it. 0 means no limit.
+	MaxGrowth    float64 // Passes making the lits of the clauses grow by more than this percentage are rolled back, see Rollback.go. 0 means no limit. The edits of each pass are then logged until it ends. Ignored if Provenance, LRAT or DeltaWriter is set.
+	CollectUnits int     // Clauses satisfied by units are removed once this many units were found since they last were, see Refs.go. 0 means 64, -1 never.
+
+	// Elimination
+	EliminateOnly     []Var // If not empty, passes eliminating vars, e.g SelfSub, only eliminate these vars.
+	NeverEliminate    []Var // Vars that passes eliminating vars must keep, e.g projection or assumption vars.
+	Selectors         []Var // Selector vars of an assumption-based MUS or MaxSAT solver. They are never eliminated nor flipped, see Selectors.go.
+	MaxResolventScore int   // Vars are only eliminated if none of their resolvents has a larger score, see resolventScore. 0 means no limit.
+
+	// Patterns
+	DetectPatterns bool // If true, Preprocess and Fixpoint first look for known Unsat families, e.g pigeonhole, see Problem.DetectPatterns.
+
+	// Probing
+	ProbeVars        int  // Max number of vars probed by Probe, most occurring first. 0 means all vars.
+	ProbeBinaries    bool // If true, Probe adds the binary clause (-x | y) for each y implied by x.
+	ProbeMaxBinaries int  // Max number of binary clauses added by Probe. 0 means no limit.
+
+	// Long clauses
+	MaxClauseLen int // Clauses longer than this do not subsume nor strengthen other clauses, and are not vivified, but can still be subsumed. 0 means no limit.
+
+	// Unhiding
+	UnhideRounds int // Number of randomized DFS run by Unhide. 0 means 1.
+	ShrinkBudget int // Max number of edges of the BIG ShrinkClausesViaBIG visits per clause. 0 means 1000.
+
+	// Output
+	OmitUnits            bool // If true, CNF propagates the units and only writes the remaining clauses. Bound vars then look free, e.g to model counters.
+	ReplaceCardinalities bool // If true, WriteKNF and WriteOPB write the cardinality constraints found instead of the clauses encoding them.
+
+	// Fixpoint
+	FixpointRounds int           // Max number of rounds run by Fixpoint. 0 means no limit.
+	FixpointTime   time.Duration // Max time spent by Fixpoint. 0 means no limit.
+	ShufflePasses  bool          // If true, Fixpoint runs the passes in a random order in each round.
+}
+
+// isLong is true iff c is too long to subsume or strengthen other clauses, see Options.MaxClauseLen.
+func (pb *Problem) isLong(c *Clause) bool {
+	return pb.Options.MaxClauseLen > 0 && c.Len() > pb.Options.MaxClauseLen
+}
+
+// resolventLBD returns an upper bound of the LBD of a resolvent of c1 and c2, or 0 if the LBD of either is unknown.
+// Both share the level of the pivot, so the resolvent cannot span more levels than both of them together.
+func resolventLBD(c1, c2 *Clause) int {
+	if c1.lbd == 0 || c2.lbd == 0 {
+		return 0
+	}
+	return c1.lbd + c2.lbd - 1
+}
+
+// resolventScore returns the quality score of c, a resolvent of c1 and c2: its LBD if it is known, see resolventLBD,
+// or its length otherwise. Lower is better.
+func resolventScore(c, c1, c2 *Clause) int {
+	if lbd := resolventLBD(c1, c2); lbd > 0 && lbd < c.Len() {
+		return lbd
+	}
+	return c.Len()
+}
+
+// keepResolvent is true iff c, a resolvent of c1 and c2, is good enough to be added to the problem,
+// see Options.MaxResolventScore, and does not mix the groups of a GCNF problem, see GCNF.go.
+func (pb *Problem) keepResolvent(c, c1, c2 *Clause) bool {
+	if pb.mixesGroups(c) {
+		return false
+	}
+	return pb.Options.MaxResolventScore <= 0 || resolventScore(c, c1, c2) <= pb.Options.MaxResolventScore
+}
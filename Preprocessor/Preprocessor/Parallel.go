@@ -0,0 +1,175 @@
+package Preprocessor
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PARALLEL PREPROCESSING
+// Many industrial problems are made of many independent parts: the connected components of their variable interaction
+// graph, see Components.go. Passes such as SelfSub and Subsumption never relate clauses without a common var, so components
+// can be preprocessed at the same time. PreprocessParallel spreads the components over a few workers, balanced
+// by their number of lits, and gives each worker a problem of its own holding the clauses of its components;
+// the clauses are shared with pb, and moved back to it once all workers are done.
+// Workers number the clauses they derive independently, so these clauses are given new IDs when they are moved back.
+// Proofs and provenance need a single history, so problems whose history is tracked are preprocessed sequentially.
+
+// PreprocessParallel runs the given passes, or DefaultPasses if none is given, until fixpoint on each connected
+// component of the problem, with up to workers components preprocessed at the same time. Passes must not add vars.
+// If workers <= 1, the problem has a single component, its history is tracked, it is an interpolation problem,
+// or a mark is set, see Rollback.go, since workers would edit its clauses without recording it, Fixpoint is run instead.
+func (pb *Problem) PreprocessParallel(workers int, passes ...Pass) Result {
+	start, nbSteps, nbUnits := time.Now(), pb.nbSteps, len(pb.Units)
+	pb.writeEvent(Event{Event: EventStart, Pass: "parallel"})
+	defer func() {
+		pb.writeEvent(Event{Event: EventEnd, Pass: "parallel", NewUnits: len(pb.Units) - nbUnits, Modified: pb.nbSteps != nbSteps, Ms: since(start)})
+	}()
+	if pb.Options.DryRun {
+		pb.Fixpoint(passes...)
+		return pb.result(start, false)
+	}
+	if pb.Status == Undetermined {
+		pb.Simplify2() // Components are separated by the units too
+	}
+	buckets := pb.componentBuckets(workers)
+	if len(buckets) <= 1 || pb.tracking() || pb.Options.Interpolation || pb.nbMarks > 0 {
+		pb.Fixpoint(passes...)
+		return pb.result(start, false)
+	}
+	log.Printf("Preprocessing %d clauses in %d parallel workers", len(pb.Clauses), len(buckets))
+	subs := make([]*Problem, len(buckets))
+	var events *lockedWriter
+	if pb.Options.EventWriter != nil {
+		events = &lockedWriter{w: pb.Options.EventWriter}
+	}
+	var wg sync.WaitGroup
+	for i, clauses := range buckets {
+		subs[i] = pb.subproblem(clauses)
+		subs[i].worker = i + 1
+		if events != nil {
+			subs[i].Options.EventWriter = events
+		}
+		wg.Add(1)
+		go func(sub *Problem) {
+			defer wg.Done()
+			sub.Fixpoint(passes...)
+		}(subs[i])
+	}
+	wg.Wait()
+	pb.mergeSubproblems(subs)
+	return pb.result(start, false)
+}
+
+// componentBuckets spreads the connected components of the problem over at most workers buckets of clauses,
+// largest components first, each in the bucket with the fewest lits so far.
+func (pb *Problem) componentBuckets(workers int) [][]*Clause {
+	if workers <= 1 || pb.Status != Undetermined {
+		return nil
+	}
+	components := pb.components()
+	sizes := make([]int, len(components))
+	for i, clauses := range components {
+		for _, c := range clauses {
+			sizes[i] += c.Len()
+		}
+	}
+	if len(components) < workers {
+		workers = len(components)
+	}
+	order := make([]int, len(components))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sizes[order[i]] > sizes[order[j]] })
+	buckets := make([][]*Clause, workers)
+	loads := make([]int, workers)
+	for _, i := range order {
+		best := 0
+		for j, load := range loads {
+			if load < loads[best] {
+				best = j
+			}
+		}
+		buckets[best] = append(buckets[best], components[i]...)
+		loads[best] += sizes[i]
+	}
+	return buckets
+}
+
+// subproblem returns a problem made of the given clauses of pb, with the bindings and the options of pb.
+// The clauses are shared with pb. The cost function, lit weights and gates are shared too, and must not be modified.
+func (pb *Problem) subproblem(clauses []*Clause) *Problem {
+	sub := &Problem{
+		NbVars:     pb.NbVars,
+		Clauses:    clauses,
+		Model:      append([]decLevel(nil), pb.Model...),
+		minLits:    pb.minLits,
+		minWeights: pb.minWeights,
+		Gates:      pb.Gates,
+		LitWeights: pb.LitWeights,
+		Options:    pb.Options,
+		lastID:     pb.lastID,
+	}
+	sub.Options.DetectPatterns = false
+	return sub
+}
+
+// mergeSubproblems moves the clauses, units and eliminated clauses of the given subproblems back to pb.
+// Clauses derived by the subproblems get new IDs, and the refs and the index of pb are updated.
+func (pb *Problem) mergeSubproblems(subs []*Problem) {
+	lastID := pb.lastID // IDs above it were given by the subproblems
+	var clauses []*Clause
+	clean := true
+	for _, sub := range subs {
+		newIDs := make(map[int]int)
+		for _, c := range sub.Clauses {
+			if c.id > lastID {
+				newIDs[c.id] = pb.nextID()
+				c.id = newIDs[c.id]
+			}
+			clauses = append(clauses, c)
+		}
+		for _, lit := range sub.Units {
+			if id := sub.UnitID(lit.Var()); id > lastID {
+				if newIDs[id] == 0 {
+					newIDs[id] = pb.nextID()
+				}
+				pb.setUnitID(lit, newIDs[id])
+			} else if id != 0 {
+				pb.setUnitID(lit, id)
+			}
+			pb.addUnit(lit)
+		}
+		if sub.Status == Unsat {
+			pb.Status = Unsat
+		}
+		pb.eliminated = append(pb.eliminated, sub.eliminated...)
+		pb.nbSteps += sub.nbSteps
+		if pb.eventErr == nil {
+			pb.eventErr = sub.eventErr
+		}
+		clean = clean && sub.isClean("")
+	}
+	pb.Clauses = clauses
+	// Subproblems gave refs of their own to the clauses: refs of pb are restored, and those of removed clauses dropped
+	for ref, c := range pb.refs {
+		if c != nil && c.removed {
+			pb.refs[ref] = nil
+		} else if c != nil {
+			c.ref = ClauseRef(ref)
+		}
+	}
+	pb.idx = nil
+	for _, c := range pb.Clauses {
+		c.indexed = false
+	}
+	pb.pending = nil
+	if pb.Status == Undetermined && len(pb.Clauses) == 0 {
+		pb.Status = Sat
+	}
+	if clean && pb.Status == Undetermined {
+		pb.setClean("")
+	}
+}
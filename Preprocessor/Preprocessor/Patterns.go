@@ -0,0 +1,250 @@
+package Preprocessor
+
+import "log"
+
+// KNOWN UNSAT PATTERNS
+// Some families of formulas are Unsat for a simple counting reason, but are out of reach of resolution,
+// and hence of the other passes and of most solvers. DetectPatterns recognizes two of them:
+//  - the pigeonhole principle: n pigeons, each in one of its holes (a positive clause), but no two pigeons
+//    in the same hole (negative binary clauses). If the candidate holes can be grouped into less than n sets
+//    of pairwise exclusive vars, some set holds two pigeons.
+//  - the mutilated chessboard: each cell of a board is covered by exactly one domino (a positive clause and
+//    negative binary clauses), each domino covering two cells of different colors. The dominoes then cover as many
+//    cells of both colors, so the problem is Unsat if the board does not have as many cells of both colors.
+// Only a subset of the clauses is used, so other clauses may be mixed with the pattern.
+// Recognizers are greedy: they may miss instances, but never declare a satisfiable problem Unsat.
+
+// DetectPatterns makes the problem Unsat if it contains a known Unsat pattern, see above.
+// It is run by Preprocess if pb.Options.DetectPatterns is set. It is skipped if an LRAT proof is written,
+// as the refutation cannot be expressed as a resolution proof of reasonable size.
+func (pb *Problem) DetectPatterns() {
+	if pb.Status != Undetermined || pb.proof() != nil || pb.Options.Interpolation || pb.skipped("patterns") {
+		return
+	}
+	excl := pb.exclusions()
+	for _, pattern := range []struct {
+		name   string
+		detect func(excl map[[2]Var]int) []int
+	}{
+		{"pigeonhole", pb.pigeonhole},
+		{"chessboard", pb.chessboard},
+	} {
+		if premises := pattern.detect(excl); premises != nil {
+			log.Printf("%s pattern found, problem is UNSAT", pattern.name)
+			pb.derived(NewClause([]Lit{}), pattern.name, premises...)
+			pb.Status = Unsat
+			return
+		}
+	}
+}
+
+// exclusions returns the IDs of the negative binary clauses, by pair of vars, the lowest var first.
+func (pb *Problem) exclusions() map[[2]Var]int {
+	res := make(map[[2]Var]int)
+	for _, c := range pb.Clauses {
+		if c.Len() != 2 || c.Get(0).IsPositive() || c.Get(1).IsPositive() {
+			continue
+		}
+		res[exclusion(c.Get(0).Var(), c.Get(1).Var())] = c.id
+	}
+	return res
+}
+
+// exclusion returns the key of the pair {v1, v2} in the map returned by exclusions.
+func exclusion(v1, v2 Var) [2]Var {
+	if v1 > v2 {
+		return [2]Var{v2, v1}
+	}
+	return [2]Var{v1, v2}
+}
+
+// isPositive returns whether all lits of c are positive.
+func isPositive(c *Clause) bool {
+	for _, lit := range c.lits {
+		if !lit.IsPositive() {
+			return false
+		}
+	}
+	return true
+}
+
+// atMostOne returns the IDs of the exclusions between the vars of c, if all pairs of them are exclusive.
+func atMostOne(c *Clause, excl map[[2]Var]int) ([]int, bool) {
+	var ids []int
+	for i := 0; i < c.Len(); i++ {
+		for j := i + 1; j < c.Len(); j++ {
+			id, ok := excl[exclusion(c.Get(i).Var(), c.Get(j).Var())]
+			if !ok {
+				return nil, false
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
+
+// pigeonhole looks for a pigeonhole pattern. Pigeons are positive clauses with pairwise disjoint vars,
+// holes are sets of pairwise exclusive vars holding at most one var per pigeon.
+// If less holes than pigeons cover the vars of the pigeons, it returns the IDs of the clauses involved.
+func (pb *Problem) pigeonhole(excl map[[2]Var]int) []int {
+	var pigeons []*Clause
+	pigeonOf := make([]int, pb.NbVars) // For each var, 1 + the index of its pigeon, 0 if none.
+	for _, c := range pb.Clauses {
+		if !isPositive(c) {
+			continue
+		}
+		disjoint := true
+		for _, lit := range c.lits {
+			disjoint = disjoint && pigeonOf[lit.Var()] == 0
+		}
+		if disjoint {
+			pigeons = append(pigeons, c)
+			for _, lit := range c.lits {
+				pigeonOf[lit.Var()] = len(pigeons)
+			}
+		}
+	}
+	if len(pigeons) < 2 {
+		return nil
+	}
+	var premises []int
+	for _, c := range pigeons {
+		premises = append(premises, c.id)
+	}
+	covered := make([]bool, pb.NbVars)
+	nbHoles := 0
+	for i, c := range pigeons {
+		for _, lit := range c.lits {
+			if covered[lit.Var()] {
+				continue
+			}
+			nbHoles++
+			if nbHoles >= len(pigeons) {
+				return nil
+			}
+			hole := []Var{lit.Var()}
+			covered[lit.Var()] = true
+			for j, c2 := range pigeons {
+				if j == i {
+					continue
+				}
+				for _, lit2 := range c2.lits {
+					v := lit2.Var()
+					if covered[v] {
+						continue
+					}
+					var ids []int
+					for _, v2 := range hole {
+						id, ok := excl[exclusion(v, v2)]
+						if !ok {
+							break
+						}
+						ids = append(ids, id)
+					}
+					if len(ids) == len(hole) {
+						hole = append(hole, v)
+						covered[v] = true
+						premises = append(premises, ids...)
+						break // At most one var per pigeon
+					}
+				}
+			}
+		}
+	}
+	return premises
+}
+
+// chessboard looks for a mutilated chessboard pattern. Cells are positive clauses whose vars are pairwise exclusive,
+// dominoes are vars appearing in exactly two cells. If the cells connected by dominoes can be colored
+// so that each domino covers two cells of different colors, but the colors are not as frequent,
+// it returns the IDs of the clauses involved.
+func (pb *Problem) chessboard(excl map[[2]Var]int) []int {
+	var cells []*Clause
+	var amos [][]int // IDs of the exclusions of each cell.
+	for _, c := range pb.Clauses {
+		if !isPositive(c) {
+			continue
+		}
+		if ids, ok := atMostOne(c, excl); ok {
+			cells = append(cells, c)
+			amos = append(amos, ids)
+		}
+	}
+	// Cells holding a var that does not appear in exactly two cells are removed, until there is none.
+	cellsOf := make([][]int, pb.NbVars)
+	for i, c := range cells {
+		for _, lit := range c.lits {
+			cellsOf[lit.Var()] = append(cellsOf[lit.Var()], i)
+		}
+	}
+	removed := make([]bool, len(cells))
+	var queue []int
+	for i := range cells {
+		queue = append(queue, i)
+	}
+	for len(queue) > 0 {
+		i := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if removed[i] {
+			continue
+		}
+		for _, lit := range cells[i].lits {
+			if len(cellsOf[lit.Var()]) != 2 {
+				removed[i] = true
+				break
+			}
+		}
+		if !removed[i] {
+			continue
+		}
+		for _, lit := range cells[i].lits {
+			v := lit.Var()
+			for k, j := range cellsOf[v] {
+				if j == i {
+					cellsOf[v] = append(cellsOf[v][:k], cellsOf[v][k+1:]...)
+					break
+				}
+			}
+			queue = append(queue, cellsOf[v]...)
+		}
+	}
+	// Each connected set of cells is colored through a DFS.
+	color := make([]int, len(cells)) // 0 means not colored yet, else 1 or 2.
+	for start := range cells {
+		if removed[start] || color[start] != 0 {
+			continue
+		}
+		color[start] = 1
+		component := []int{start}
+		bipartite := true
+		nbCells := [3]int{0, 1, 0}
+		for stack := []int{start}; len(stack) > 0; {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, lit := range cells[i].lits {
+				for _, j := range cellsOf[lit.Var()] {
+					if j == i {
+						continue
+					}
+					if color[j] == 0 {
+						color[j] = 3 - color[i]
+						nbCells[color[j]]++
+						component = append(component, j)
+						stack = append(stack, j)
+					} else if color[j] == color[i] {
+						bipartite = false
+					}
+				}
+			}
+		}
+		if bipartite && nbCells[1] != nbCells[2] {
+			var premises []int
+			for _, i := range component {
+				premises = append(premises, cells[i].id)
+				premises = append(premises, amos[i]...)
+			}
+			return premises
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PHASE HINTS
+// Probing and vivification propagate many assumptions. The polarity each var is most often forced to
+// is a cheap guess of its value in a model, that a CDCL solver can use to initialize phase saving.
+// Vars that were never forced are guessed from their occurrences instead: a var mostly occurring positively,
+// in short clauses above all, satisfies more clauses when it is true, and a pure lit satisfies all its clauses.
+// Occurrences are weighted as in the Jeroslow-Wang heuristic, by 2^-len for a clause of len lits.
+
+// recordPhases records that the given lits were forced by propagation.
+func (pb *Problem) recordPhases(lits []Lit) {
+	if len(lits) == 0 {
+		return
+	}
+	if pb.phases == nil {
+		pb.phases = make([]int, pb.NbVars)
+	}
+	if phases := pb.phases; pb.nbMarks > 0 {
+		lits := append([]Lit(nil), lits...)
+		old := make([]int, len(lits))
+		for i, lit := range lits {
+			old[i] = phases[lit.Var()]
+		}
+		pb.logUndo(func() {
+			for i := len(lits) - 1; i >= 0; i-- {
+				phases[lits[i].Var()] = old[i]
+			}
+		})
+	}
+	for _, lit := range lits {
+		if lit.IsPositive() {
+			pb.phases[lit.Var()]++
+		} else {
+			pb.phases[lit.Var()]--
+		}
+	}
+}
+
+// PhaseHints returns, for each var, the polarity it should be given first by a solver:
+// 1 for true, -1 for false, 0 if there is no evidence either way.
+// Vars bound by units get their value, other vars the polarity they were most often forced to by propagation.
+func (pb *Problem) PhaseHints() []int8 {
+	res := make([]int8, pb.NbVars)
+	for v := range res {
+		count := 0
+		if v < len(pb.phases) {
+			count = pb.phases[v]
+		}
+		switch {
+		case pb.Model[v] != 0:
+			res[v] = int8(pb.Model[v])
+		case count > 0:
+			res[v] = 1
+		case count < 0:
+			res[v] = -1
+		}
+	}
+	return res
+}
+
+// PolarityHints is like PhaseHints, but vars without evidence from propagation get the polarity of the lit
+// whose occurrences weigh the most, see above. Vars whose lits weigh the same, e.g vars without occurrences, get 0.
+func (pb *Problem) PolarityHints() []int8 {
+	res := pb.PhaseHints()
+	weights := make([]float64, 2*pb.NbVars)
+	for _, c := range pb.Clauses {
+		w := math.Ldexp(1, -c.Len())
+		for _, lit := range c.lits {
+			weights[lit] += w
+		}
+	}
+	for v, hint := range res {
+		if hint != 0 {
+			continue
+		}
+		lit := Var(v).Lit()
+		switch pos, neg := weights[lit], weights[lit.Negation()]; {
+		case pos > neg:
+			res[v] = 1
+		case neg > pos:
+			res[v] = -1
+		}
+	}
+	return res
+}
+
+// WritePhases writes a phase file for solvers that accept initial phases: the lit of each var with a hint,
+// see PolarityHints, in DIMACS format, ended by 0, as in "1 -2 4 0". Vars without hint are omitted.
+func (pb *Problem) WritePhases(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c phases: %d vars\n", pb.NbVars)
+	sep := ""
+	for v, hint := range pb.PolarityHints() {
+		lit := Var(v).Lit()
+		switch hint {
+		case 0:
+			continue
+		case -1:
+			lit = lit.Negation()
+		}
+		fmt.Fprintf(bw, "%s%d", sep, lit.Int())
+		sep = " "
+	}
+	fmt.Fprintf(bw, "%s0\n", sep)
+	return bw.Flush()
+}
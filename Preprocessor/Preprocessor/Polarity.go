@@ -0,0 +1,149 @@
+package Preprocessor
+
+// POLARITY CANONICALIZATION
+// Flipping a var, i.e renaming v to -v everywhere, gives a problem with the same models up to the value of v.
+// Two problems that only differ by such flips are the same problem to a solver, but not to a hash of their clauses,
+// and heuristics assuming that vars mostly occur positively, e.g for the initial phase, treat them differently.
+// CanonicalizePolarity flips each var occurring more often negatively than positively, so that every var occurs
+// at least as often positively as negatively. Flips are recorded, and a model of the renamed problem is turned back
+// into a model of the original one by UnflipModel.
+
+// CanonicalizePolarity flips the vars whose negative lit occurs in more clauses than their positive lit,
+// selectors excepted, see Options.Selectors, and returns the number of vars flipped. Everything about the problem is renamed: clauses, units and model,
+// eliminated clauses, cost lits, lit weights, gates and cardinality constraints, so that clauses or assumptions given
+// to the problem afterwards are about the renamed vars too. WriteModel writes the values of the original vars,
+// but models of the renamed problem, e.g those of CompleteModel or CombineModels, must be given to UnflipModel.
+// Flipping vars cannot be expressed as a derivation of clauses, so nothing is done, and 0 is returned,
+// if the history of the problem is tracked, see Options.Provenance, Options.LRAT and Options.DeltaWriter,
+// nor for interpolation problems, whose interpolants are about the original vars, see Interpolation.go.
+func (pb *Problem) CanonicalizePolarity() int {
+	if pb.tracking() || pb.Options.Interpolation || pb.Status == Unsat {
+		return 0
+	}
+	balance := make([]int, pb.NbVars) // For each var, its positive occurrences minus its negative ones
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			if lit.IsPositive() {
+				balance[lit.Var()]++
+			} else {
+				balance[lit.Var()]--
+			}
+		}
+	}
+	flip := make([]bool, pb.NbVars)
+	nbFlipped := 0
+	isSelector := pb.selectors()
+	for v, b := range balance {
+		if b < 0 && !isSelector[v] {
+			flip[v] = true
+			nbFlipped++
+		}
+	}
+	if nbFlipped == 0 {
+		return 0
+	}
+	pb.flip(flip)
+	return nbFlipped
+}
+
+// flip renames the lits of the given vars to their negation everywhere in the problem, and records it in pb.flipped.
+// Slices of the problem are replaced rather than written to, so that only clauses need to be saved for a rollback.
+func (pb *Problem) flip(flip []bool) {
+	rename := func(lit Lit) Lit {
+		if flip[lit.Var()] {
+			return lit.Negation()
+		}
+		return lit
+	}
+	renameAll := func(lits []Lit) []Lit { // Lits may be in a memory mapping, see Mapped.go
+		res := make([]Lit, len(lits))
+		for i, lit := range lits {
+			res[i] = rename(lit)
+		}
+		return res
+	}
+	for _, c := range pb.Clauses {
+		pb.saveClause(c)
+		c.setLits(renameAll(c.lits))
+		c.Sort()
+		c.pbData = nil
+		c.indexed = false
+	}
+	pb.idx = nil
+	pb.Units = renameAll(pb.Units)
+	model := make([]decLevel, len(pb.Model))
+	for v, val := range pb.Model {
+		if model[v] = val; flip[v] {
+			model[v] = -val
+		}
+	}
+	pb.Model = model
+	if pb.phases != nil {
+		phases := make([]int, len(pb.phases))
+		for v, phase := range pb.phases {
+			if phases[v] = phase; flip[v] {
+				phases[v] = -phase
+			}
+		}
+		pb.phases = phases
+	}
+	eliminated := make([]elimination, len(pb.eliminated))
+	for i, e := range pb.eliminated {
+		eliminated[i] = elimination{lits: renameAll(e.lits)}
+		for _, lits := range e.others {
+			eliminated[i].others = append(eliminated[i].others, renameAll(lits))
+		}
+	}
+	pb.eliminated = eliminated
+	pb.minLits = renameAll(pb.minLits)
+	if pb.LitWeights != nil {
+		weights := make(map[Lit]float64, len(pb.LitWeights))
+		for lit, w := range pb.LitWeights {
+			weights[rename(lit)] = w
+		}
+		pb.LitWeights = weights
+	}
+	if pb.Gates != nil {
+		gates := make([]Gate, len(pb.Gates))
+		for i, g := range pb.Gates {
+			gates[i] = Gate{Kind: g.Kind, Out: rename(g.Out), In: renameAll(g.In)}
+		}
+		pb.Gates = gates
+	}
+	if pb.cards != nil {
+		cards := append([]Cardinality(nil), pb.cards...)
+		for i := range cards {
+			cards[i].Lits = renameAll(cards[i].Lits)
+		}
+		pb.cards = cards
+	}
+	if pb.amos != nil {
+		amos := make([][]Lit, len(pb.amos))
+		for i, amo := range pb.amos {
+			amos[i] = renameAll(amo)
+		}
+		pb.amos = amos
+	}
+	flipped := make([]bool, pb.NbVars)
+	for v, f := range flip {
+		flipped[v] = f != (pb.flipped != nil && pb.flipped[v])
+	}
+	pb.flipped = flipped
+	pb.nbSteps++ // Passes must run again, and the propagator be rebuilt
+}
+
+// Flipped returns whether v was flipped by CanonicalizePolarity, i.e whether the var v of the problem is -v
+// in the original problem.
+func (pb *Problem) Flipped(v Var) bool {
+	return int(v) < len(pb.flipped) && pb.flipped[v]
+}
+
+// UnflipModel turns model, a model of the problem, into a model of the problem as it was before CanonicalizePolarity,
+// by negating the values of the flipped vars in place.
+func (pb *Problem) UnflipModel(model []bool) {
+	for v, f := range pb.flipped {
+		if f && v < len(model) {
+			model[v] = !model[v]
+		}
+	}
+}
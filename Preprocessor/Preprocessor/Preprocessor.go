@@ -1,8 +1,13 @@
 package Preprocessor
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"strings"
+	"time"
 )
 
 //
@@ -15,23 +20,199 @@ type Status byte
 type Problem struct {
 	NbVars     int        // Total number of vars
 	Clauses    []*Clause  // List of non-empty, non-unit clauses
+	lits       []Lit      // Lits of the clauses read by ParseCNFMapped, if any, see Mapped.go.
+	mapping    []byte     // Memory mapping holding lits, if any, see Mapped.go.
 	Status     Status     // Status of the problem. Can be trivially UNSAT (if empty clause was met or inferred by UP) or Indet.
 	Units      []Lit      // List of unit literal found in the problem.
 	Model      []decLevel // For each var, its inferred binding. 0 means unbound, 1 means bound to true, -1 means bound to false.
 	minLits    []Lit      // For an optimisation problem, the list of lits whose sum must be minimized
 	minWeights []int      // For an optimisation problem, the weight of each lit.
+	Gates      []Gate     // Gate definitions known for the problem, e.g when it was built from a circuit.
+	LitWeights map[Lit]float64 // For weighted model counting, the weight of each lit. Lits without weight weigh 1, see WeightFactor.
+	Comments   []string   // Comment lines written before the header by CNF, without their leading "c", see Options.KeepComments.
+	Options    Options    // Options used by the preprocessing techniques.
+	lastID     int         // Last ID given to a clause.
+	unitIDs    []int       // For each var, the ID of the unit clause that bound it.
+	provenance *Provenance // History of the clauses, if tracked.
+	emptyID    int         // ID of the first empty clause met, see UnsatExplanation.
+	lrat       *lratProof  // LRAT proof being written, if any.
+	idx        *Index      // Subsumption index, see Index.go.
+	prop       *propagator // Propagation engine used by Propagate, valid as long as nbSteps is propSteps.
+	propSteps  int
+	nbSteps    int         // Number of derivations, deletions and added clauses so far, to detect modifications.
+	clean      map[string]int // For each pass, the value of nbSteps when it last left the problem unchanged, see Dirty.
+	pending    []*Clause      // Clauses added since the problem was preprocessed, see Incremental.go.
+	rng        *rand.Rand  // Random number generator, seeded with Options.Seed.
+	mem        *memAccountant // Estimation of the memory used, if pb.Options.MaxMemoryMB is set.
+	refs       []*Clause      // Clauses by ClauseRef, see Refs.go.
+	nbMarked   int            // Number of clauses marked as removed, but not swept yet.
+	nbCollected int           // Number of units whose satisfied clauses were removed, see collectSatisfied.
+	amos       [][]Lit        // At-most-one constraints among cost lits, see MineAtMostOnes.
+	cards      []Cardinality  // Cardinality constraints implied by the clauses, see DetectCardinalities.
+	eliminated []elimination  // Clauses removed along with their vars, last eliminated last, see Elimination.go.
+	origVars   []Var          // For a component, the var of the original problem of each of its vars, see Components.
+	phases     []int       // For each var, how many more times it was forced to true than to false, see PhaseHints.
+	flipped    []bool      // For each var, whether it was flipped by CanonicalizePolarity, nil if none was.
+	selectorClauses map[Var]int // For each selector, the clauses containing it when the first pass was run, see SelectorStats.
+	selectorLits    map[Var]int
+	groupOf         []int       // For each var, the GCNF group it is the selector of, 0 if none, see GCNF.go.
+	partitionVars   [2][]bool   // For each partition, whether each var occurs in its clauses, see InterpolationUnits.
+	unitPartitions  []Partition // For each var, the partition of the unit that bound it, see Interpolation.go.
+	emptyPartition  Partition   // Partition of the first empty clause met, see EmptyPartition.
+	emptyUnits      int         // Number of units when the first empty clause was met, see InterpolationUnits.
+	selfSubCursor int      // Var SelfSub starts its next round with, see SelfSub.
+	deltaErr   error       // First error met while writing to Options.DeltaWriter.
+	eventErr   error       // First error met while writing to Options.EventWriter.
+	worker     int         // Worker of PreprocessParallel preprocessing the problem, if any, see Events.go.
+	savepoints []savepoint // States saved by Savepoint, oldest first, see Rollback.go.
+	dryRuns    []DryRunReport // Reports of the passes run while Options.DryRun was set, see DryRun.go.
+	lastSavepoint SavepointID
+	undo       []func()    // Edits to undo to roll the problem back, oldest first, see Rollback.go.
+	nbMarks    int         // Number of marks set, savepoints included: edits are logged as long as there is one.
 }
 
-// CNF returns a DIMACS CNF representation of the problem.
+// NewProblem returns an empty problem over nbVars vars.
+func NewProblem(nbVars int) *Problem {
+	return &Problem{
+		NbVars:  nbVars,
+		Clauses: make([]*Clause, 0),
+		Model:   make([]decLevel, nbVars),
+	}
+}
+
+// NewVar adds a fresh unbound var to the problem, e.g an auxiliary var of an encoding, and returns it.
+func (pb *Problem) NewVar() Var {
+	v := Var(pb.NbVars)
+	pb.NbVars++
+	pb.Model = append(pb.Model, 0)
+	if pb.unitIDs != nil {
+		pb.unitIDs = append(pb.unitIDs, 0)
+	}
+	if pb.phases != nil {
+		pb.phases = append(pb.phases, 0)
+	}
+	if pb.flipped != nil {
+		pb.flipped = append(pb.flipped, false)
+	}
+	if pb.unitPartitions != nil {
+		pb.unitPartitions = append(pb.unitPartitions, PartitionA)
+	}
+	for p := range pb.partitionVars {
+		if pb.partitionVars[p] != nil {
+			pb.partitionVars[p] = append(pb.partitionVars[p], false)
+		}
+	}
+	return v
+}
+
+// AddClause adds a clause made of the given lits to the problem.
+// Empty clauses make the problem Unsat, unit clauses are added as units and tautologies are ignored.
+// Vars of lits the problem does not know of are added to it, as NewVar does, and the clauses of the eliminated vars
+// of lits are added back to the problem first, see Elimination.go.
+// As with ParseCNF, units are not propagated until Simplify2 is called.
+// Once the problem was preprocessed, the units are propagated through the clause, and the clause is scheduled
+// for the next call to Preprocess, which only simplifies the problem around it, see Incremental.go.
+func (pb *Problem) AddClause(lits []Lit) {
+	pb.AddClauseWithOptions(lits, ClauseOptions{})
+}
+
+// ClauseOptions tune how AddClauseWithOptions adds a clause to the problem.
+type ClauseOptions struct {
+	// Protected clauses are never removed nor modified by passes, e.g selector clauses of MUS or MaxSAT frameworks,
+	// that are extracted from the simplified problem, even once units satisfy them. Their vars are never eliminated,
+	// but they still subsume and strengthen other clauses, and take part in propagation. Tautologies are not added
+	// anyway, and neither units nor the empty clause are clauses of the problem, so they cannot be protected.
+	Protected bool
+	// Partition is the partition, A or B, of the clause of an interpolation problem, see Options.Interpolation.
+	// It is ignored unless Options.Interpolation is set.
+	Partition Partition
+}
+
+// AddClauseWithOptions is like AddClause, with the given options.
+func (pb *Problem) AddClauseWithOptions(lits []Lit, opts ClauseOptions) {
+	pb.addVars(lits)
+	if len(pb.eliminated) > 0 {
+		pb.restore(lits)
+	}
+	incremental := pb.isClean("") || pb.pending != nil
+	c := NewClause(append([]Lit(nil), lits...))
+	c.id = pb.nextID()
+	c.protected = opts.Protected
+	c.partition = opts.Partition
+	pb.nbSteps++
+	if c.Simplify() {
+		pb.deleted(c, "tautology")
+		return
+	}
+	pb.recordPartitionVars(c.lits, c.partition)
+	if incremental && !c.protected {
+		if pb.simplifyClause(c) {
+			return
+		}
+		pb.pending = append(pb.pending, c)
+	}
+	switch c.Len() {
+	case 0:
+		pb.foundEmpty(c.id)
+		pb.setPartition(c.lits, c.partition)
+		pb.Status = Unsat
+	case 1:
+		lit := c.First()
+		if pb.Model[lit.Var()] == 0 || (pb.Model[lit.Var()] == 1) != lit.IsPositive() {
+			pb.setUnitID(lit, c.id)
+			pb.setPartition(c.lits, c.partition)
+			pb.addUnit(lit)
+		}
+	default:
+		pb.Clauses = append(pb.Clauses, c)
+		if pb.Status == Sat {
+			pb.Status = Undetermined
+		}
+	}
+}
+
+// CNF returns a DIMACS CNF representation of the problem, as written by WriteCNF.
 func (pb *Problem) CNF() string {
-	res := fmt.Sprintf("p cnf %d %d\n", pb.NbVars, len(pb.Clauses)+len(pb.Units))
-	for _, unit := range pb.Units {
-		res += fmt.Sprintf("%d 0\n", unit.Int())
+	var sb strings.Builder
+	pb.WriteCNF(&sb)
+	return sb.String()
+}
+
+// WriteCNF writes the problem in the DIMACS CNF format.
+// If pb.Options.OmitUnits is set, units are propagated first, on a copy of the problem, and are not written:
+// no clause of the result is a unit, and an Unsat problem is written as the empty clause.
+func (pb *Problem) WriteCNF(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, comment := range pb.Comments {
+		fmt.Fprintf(bw, "c%s\n", comment)
+	}
+	units, clauses := pb.Units, pb.Clauses
+	if pb.Options.OmitUnits {
+		propagated := pb.Clone()
+		propagated.Simplify2()
+		if propagated.Status == Unsat {
+			fmt.Fprintf(bw, "p cnf %d 1\n0\n", pb.NbVars)
+			return bw.Flush()
+		}
+		units, clauses = nil, propagated.Clauses
 	}
-	for _, clause := range pb.Clauses {
-		res += fmt.Sprintf("%s\n", clause.CNF())
+	fmt.Fprintf(bw, "p cnf %d %d\n", pb.NbVars, len(clauses)+len(units))
+	for _, unit := range units {
+		fmt.Fprintf(bw, "%d 0\n", unit.Int())
 	}
-	return res
+	for _, clause := range clauses {
+		fmt.Fprintln(bw, clause.CNF())
+	}
+	return bw.Flush()
+}
+
+// random returns the random number generator of the problem. All randomized techniques use it,
+// so that a given seed always gives the same result. Copies made by Clone start again from the seed.
+func (pb *Problem) random() *rand.Rand {
+	if pb.rng == nil {
+		pb.rng = rand.New(rand.NewSource(pb.Options.Seed))
+	}
+	return pb.rng
 }
 
 ///// PROBLEM UTILITY FUNCTIONS FROM GOPHERSAT
@@ -49,113 +230,308 @@ func (pb *Problem) addUnit(lit Lit) {
 			pb.Status = Unsat
 			return
 		}
-		pb.Model[lit.Var()] = 1
+		pb.setValue(lit.Var(), 1)
 	} else {
 		if pb.Model[lit.Var()] == 1 {
 			pb.Status = Unsat
 			return
 		}
-		pb.Model[lit.Var()] = -1
+		pb.setValue(lit.Var(), -1)
 	}
 	pb.Units = append(pb.Units, lit)
 }
 
 // simplify simplifies the pure SAT problem, i.e runs unit propagation if possible.
+// Units are propagated with watched lits, then satisfied clauses are removed and false lits are removed from clauses,
+// in a single scan. The order of the remaining lits is kept.
 func (pb *Problem) Simplify2() {
-	nbClauses := len(pb.Clauses)
-	restart := true
-	for restart {
-		restart = false
-		i := 0
-		for i < nbClauses {
-			c := pb.Clauses[i]
-			nbLits := c.Len()
-			clauseSat := false
-			j := 0
-			for j < nbLits {
-				lit := c.Get(j)
-				if pb.Model[lit.Var()] == 0 {
-					j++
-				} else if (pb.Model[lit.Var()] == 1) == lit.IsPositive() {
-					clauseSat = true
-					break
-				} else {
-					nbLits--
-					c.Set(j, c.Get(nbLits))
+	for {
+		p := pb.propagator()
+		conflict := p.propagate()
+		for _, lit := range p.trail {
+			if reason := p.reasons[lit.Var()]; reason != noClause {
+				c := p.clauses[reason]
+				var premises []int
+				if pb.tracking() {
+					for _, lit2 := range c.lits {
+						if lit2 != lit {
+							premises = append(premises, pb.UnitID(lit2.Var()))
+						}
+					}
 				}
+				pb.derivedUnit(lit, "simplify", append(premises, c.id)...)
+				pb.setPartition([]Lit{lit}, c.partition)
+				pb.addUnit(lit)
 			}
-			if clauseSat {
-				nbClauses--
-				pb.Clauses[i] = pb.Clauses[nbClauses]
-			} else if nbLits == 0 {
-				pb.Status = Unsat
-				return
-			} else if nbLits == 1 { // UP
-				pb.addUnit(c.First())
-				if pb.Status == Unsat {
-					return
-				}
-				nbClauses--
-				pb.Clauses[i] = pb.Clauses[nbClauses]
-				restart = true // Must restart, since this lit might have made one more clause Unit or SAT.
-			} else { // nb lits unbound > cardinality
-				if c.Len() != nbLits {
-					c.Shrink(nbLits)
-				}
-				i++
+		}
+		if conflict == unitConflict { // Opposite units: both are bound at level 0 and share the ID of their var
+			v := p.conflictUnit.Var()
+			pb.derived(NewClause([]Lit{}), "simplify", pb.unitReasons([]Lit{p.conflictUnit})...)
+			pb.setPartition(nil, pb.unitPartition(v))
+			pb.Status = Unsat
+			return
+		}
+		if conflict != noClause {
+			c := p.clauses[conflict]
+			pb.derived(NewClause([]Lit{}), "simplify", append(pb.unitReasons(c.lits), c.id)...)
+			pb.setPartition(nil, c.partition)
+			pb.Status = Unsat
+			return
+		}
+		nbUnits := len(pb.Units)
+		pb.ApplyUnits() // Units appear only when c has duplicate lits, which are both watched
+		if len(pb.Units) == nbUnits || pb.Status == Unsat {
+			return
+		}
+	}
+}
+
+// ApplyUnits removes the clauses satisfied by the units of the problem, and the false lits of the other clauses,
+// protected clauses excepted,
+// in a single scan, without propagating the units first. A clause left with a single lit becomes a unit, which is applied
+// to the clauses scanned after it, but the clauses scanned before are not scanned again: unlike Simplify2, ApplyUnits
+// never loops. A clause left without any lit makes the problem Unsat.
+// It returns the number of clauses removed, and of clauses shortened but kept.
+func (pb *Problem) ApplyUnits() (removed, shortened int) {
+	if pb.Status == Unsat {
+		return 0, 0
+	}
+	nbUnits := len(pb.Units) // Units found during the scan may satisfy clauses scanned before
+	for _, c := range pb.Clauses {
+		if c.protected {
+			continue
+		}
+		nbLits := c.Len()
+		if pb.simplifyClause(c) {
+			pb.markRemoved(c)
+			removed++
+			continue
+		}
+		switch c.Len() {
+		case 0:
+			pb.setPartition(nil, c.partition)
+			pb.Status = Unsat
+			pb.markRemoved(c)
+			removed++
+		case 1:
+			pb.derivedUnit(c.First(), "simplify", c.id)
+			pb.setPartition(c.lits, c.partition)
+			pb.addUnit(c.First())
+			pb.markRemoved(c)
+			removed++
+		default:
+			if c.Len() < nbLits {
+				shortened++
 			}
 		}
+		if pb.Status == Unsat {
+			break
+		}
+	}
+	pb.sweep()
+	if pb.nbCollected < nbUnits {
+		pb.nbCollected = nbUnits
+	}
+	pb.updateStatus(len(pb.Clauses))
+	return removed, shortened
+}
+
+// simplifyClause removes the lits of c that are false according to the units of the problem.
+// It returns true if c is satisfied, and must be removed.
+func (pb *Problem) simplifyClause(c *Clause) (sat bool) {
+	// Satisfied clauses are looked for first, so that deleted clauses are recorded with their lits untouched.
+	bound := false
+	for _, lit := range c.lits {
+		if val := pb.Model[lit.Var()]; val != 0 && (val == 1) == lit.IsPositive() {
+			pb.deleted(c, "simplify", pb.UnitID(lit.Var()))
+			return true
+		} else if val != 0 {
+			bound = true
+		}
+	}
+	if !bound {
+		return false
+	}
+	pb.saveClause(c)
+	var reasons []int
+	var falsified []Lit
+	nbLits := 0
+	for _, lit := range c.lits {
+		if pb.Model[lit.Var()] == 0 {
+			c.lits[nbLits] = lit
+			nbLits++
+		} else {
+			reasons = append(reasons, pb.UnitID(lit.Var()))
+			falsified = append(falsified, lit)
+		}
+	}
+	if nbLits < c.Len() {
+		oldID := c.id
+		c.Shrink(nbLits)
+		pb.replaced(c, oldID, append(falsified, c.lits...), "simplify", append(reasons, oldID)...)
 	}
-	pb.updateStatus(nbClauses)
+	return false
 }
 
 // Preprocess main function
+// It runs DefaultPasses once. It does nothing if the problem was not modified since it was last preprocessed, see Dirty,
+// and passes that left the problem unchanged are not run again until it is modified.
+// If clauses were added since, only the problem around them is simplified again, see Incremental.go.
+// The returned Result tells why it stopped.
+
+func (pb *Problem) Preprocess() Result {
+	return pb.preprocess(nil)
+}
+
+// preprocess runs Preprocess. If yield is not nil, it is called before each pass, and returns whether the problem
+// was modified meanwhile, see SafeProblem: the problem is then not recorded as preprocessed.
+func (pb *Problem) preprocess(yield func() bool) Result {
+	start, nbSteps, nbUnits := time.Now(), pb.nbSteps, len(pb.Units)
+	pb.writeEvent(Event{Event: EventStart, Pass: "preprocess"})
+	defer func() {
+		pb.writeEvent(Event{Event: EventEnd, Pass: "preprocess", NewUnits: len(pb.Units) - nbUnits, Modified: pb.nbSteps != nbSteps, Ms: since(start)})
+	}()
+	if !pb.Dirty() {
+		log.Printf("Problem unchanged since it was preprocessed")
+		return pb.result(start, true)
+	}
+	if pb.pending != nil && !pb.Options.DryRun {
+		pb.resimplify()
+		pb.setClean("")
+		return pb.result(start, false)
+	}
+	var key string // Name of the file caching the result, see Cache.go
+	if pb.Options.CacheDir != "" && !pb.tracking() && !pb.hasProtected() && !pb.Options.Interpolation && !pb.Options.DryRun {
+		key = pb.cacheKey()
+		if pb.readCache(key) {
+			pb.setClean("")
+			res := pb.result(start, false)
+			res.Cached = true
+			return res
+		}
+	}
+	if pb.Options.DetectPatterns {
+		pb.runPatterns()
+	}
+	modified := false // By others than the passes
+	for _, pass := range DefaultPasses {
+		if yield != nil && yield() {
+			modified = true
+		}
+		pb.runPass(pass)
+	}
+	if !modified && !pb.Options.DryRun {
+		pb.setClean("")
+	}
+	res := pb.result(start, false)
+	if key != "" && !modified && res.Termination != MemoryExhausted {
+		pb.writeCache(key)
+	}
+	return res
+}
 
-func (pb *Problem) Preprocess() {
-	pb.SelfSub()
-	pb.Subsumption()
+// eliminable returns, for each var, whether passes eliminating vars may remove its occurrences,
+// as set by pb.Options.EliminateOnly, pb.Options.NeverEliminate and pb.Options.Selectors.
+// Vars that are not vars of the problem are ignored.
+// Vars of protected clauses are not eliminable, since their clauses would be removed, see ClauseOptions,
+// nor are the vars occurring in both partitions of an interpolation problem, see Interpolation.go.
+func (pb *Problem) eliminable() []bool {
+	res := make([]bool, pb.NbVars)
+	for i := range res {
+		res[i] = len(pb.Options.EliminateOnly) == 0
+	}
+	for _, v := range pb.Options.EliminateOnly {
+		if v >= 0 && int(v) < pb.NbVars {
+			res[v] = true
+		}
+	}
+	for _, v := range pb.Options.NeverEliminate {
+		if v >= 0 && int(v) < pb.NbVars {
+			res[v] = false
+		}
+	}
+	for v, isSelector := range pb.selectors() {
+		if isSelector {
+			res[v] = false
+		}
+	}
+	if pb.Options.Interpolation {
+		for v, shared := range pb.sharedVars() {
+			if shared {
+				res[v] = false
+			}
+		}
+	}
+	for _, c := range pb.Clauses {
+		if c.protected {
+			for _, lit := range c.lits {
+				res[lit.Var()] = false
+			}
+		}
+	}
+	return res
 }
 
 // RUN self-subsuming resolution
+// Resolution on a var v removes occurrences of v, so only vars that are eliminable, see Options.EliminateOnly, are examined.
+// Occurrence lists come from the index of the problem, which is updated as clauses are added, removed or strengthened, see Index.
+// Each round scans all vars, starting right after the last var that was simplified, or where the previous call stopped,
+// and wrapping around: low-indexed vars are thus not favored over the others when the pass is interrupted or repeated.
 func (pb *Problem) SelfSub() {
-	log.Printf("Preprocessing... %d clauses currently", len(pb.Clauses))
-	occurs := make([][]int, pb.NbVars*2)
-	for i, c := range pb.Clauses {
-		for j := 0; j < c.Len(); j++ {
-			occurs[c.Get(j)] = append(occurs[c.Get(j)], i)
-		}
+	pb.selfSub(pb.eliminable())
+}
+
+// selfSub runs self-subsuming resolution on the vars for which eliminable is true.
+func (pb *Problem) selfSub(eliminable []bool) {
+	if pb.Status != Undetermined || pb.skipped("selfsub") {
+		return
 	}
-	log.Printf("Occurence list: %s", occurs)
+	log.Printf("Preprocessing... %d clauses currently", len(pb.Clauses))
+	pb.useBitsets()
+	occurs := pb.index()
+	log.Printf("Occurence list: %v", occurs.refs)
 	modified := true
 	neverModified := true
 	for modified {
 		modified = false
 
-		// for each variable
-		for i := 0; i < pb.NbVars; i++ {
-			if pb.Model[i] != 0 {
+		// for each variable, starting from the cursor
+		if pb.selfSubCursor >= pb.NbVars {
+			pb.selfSubCursor = 0
+		}
+		start := pb.selfSubCursor
+		for k := 0; k < pb.NbVars; k++ {
+			i := (start + k) % pb.NbVars
+			if pb.memory().exhausted() {
+				log.Printf("Memory budget exhausted, stopping")
+				pb.selfSubCursor = i
+				modified = false
+				break
+			}
+			if pb.Model[i] != 0 || !eliminable[i] {
 				continue
 			}
+			nbSteps := pb.nbSteps
 			v := Var(i)
 			lit := v.Lit()
-			nbLit := len(occurs[lit])
-			nbLit2 := len(occurs[lit.Negation()])
+			nbLit := len(occurs.live(lit))
+			nbLit2 := len(occurs.live(lit.Negation()))
 
 			// slow method is only effective with less than 10 literals
 			if (nbLit < 10 || nbLit2 < 10) && (nbLit != 0 || nbLit2 != 0) {
 				log.Printf("Examining literal: %d", lit.Int())
 				// loop through the occurence list and check clauses where literals and their negations exist
-				for _, idx1 := range occurs[lit] {
-					for _, idx2 := range occurs[lit.Negation()] {
-						log.Printf("%d can be removed: %d and %d", lit.Int(), len(occurs[lit]), len(occurs[lit.Negation()]))
+				for _, idx1 := range occurs.refs[lit] {
+					for _, idx2 := range occurs.refs[lit.Negation()] {
+						log.Printf("%d can be removed: %d and %d", lit.Int(), len(occurs.refs[lit]), len(occurs.refs[lit.Negation()]))
 						// positive clause
-						c1 := pb.Clauses[idx1]
+						c1 := pb.Clause(idx1)
 						// negative clause
-						c2 := pb.Clauses[idx2]
+						c2 := pb.Clause(idx2)
 
 						// determine whether self-subsuming resolution is possible for clauses (both ways)
-						canP := c1.SelfSubsumes(c2)
-						canN := c2.SelfSubsumes(c1)
+						canP := !pb.isLong(c1) && c1.SelfSubsumes(c2)
+						canN := !pb.isLong(c2) && c2.SelfSubsumes(c1)
 
 						log.Printf("Can positive clause be self-subsumed? %t",canP)
 						log.Printf("Can negative clause be self-subsumed? %t",canN)
@@ -163,30 +539,35 @@ func (pb *Problem) SelfSub() {
 						// if both are true then remove negative clause and literal from positive clause
 						if(canP && canN){
 							// generate new clause with self-subsuming resolution
+							pb.memory().charge(clauseSize(c1.Len() + c2.Len()))
 							newC := c1.Generate(c2, v)
 							if !newC.Simplify() {
+								pb.derived(newC, "selfsub", c1.id, c2.id)
 								switch newC.Len() {
 								case 0:
 									log.Printf("Inferred UNSAT")
+									pb.setPartition(nil, newC.partition)
 									pb.Status = Unsat
 									return
 								case 1:
 									log.Printf("Unit %d", newC.First().Int())
 									lit2 := newC.First()
+									pb.setUnitID(lit2, newC.id)
+									pb.setPartition(newC.lits, newC.partition)
 									if lit2.IsPositive() {
 										if pb.Model[lit2.Var()] == -1 {
 											pb.Status = Unsat
 											log.Printf("Inferred UNSAT")
 											return
 										}
-										pb.Model[lit2.Var()] = 1
+										pb.setValue(lit2.Var(), 1)
 									} else {
 										if pb.Model[lit2.Var()] == 1 {
 											pb.Status = Unsat
 											log.Printf("Inferred UNSAT")
 											return
 										}
-										pb.Model[lit2.Var()] = -1
+										pb.setValue(lit2.Var(), -1)
 									}
 
 									// Check if unit literal exists so that we don't add duplicates
@@ -204,23 +585,21 @@ func (pb *Problem) SelfSub() {
 									}
 								default:
 									pb.Clauses = append(pb.Clauses, newC)
+									occurs.add(newC)
 								}
 							}
 
 							// REMOVE THE LITERAL FROM POSITIVE CLAUSE AND DELETE NEGATIVE CLAUSE
 							//nbRemoved := 0
-							if len(occurs[lit.Negation()])>0{
-								pb.Clauses[idx1] = pb.Clauses[len(pb.Clauses)-1]
-								pb.Clauses = pb.Clauses[:len(pb.Clauses)-1]
-								pb.Clauses[idx2] = pb.Clauses[len(pb.Clauses)-1]
-								pb.Clauses = pb.Clauses[:len(pb.Clauses)-1]
-
-								occurs = make([][]int, pb.NbVars*2)
-								for i, c := range pb.Clauses {
-									for j := 0; j < c.Len(); j++ {
-										occurs[c.Get(j)] = append(occurs[c.Get(j)], i)
-									}
+							if len(occurs.refs[lit.Negation()])>0{
+								pb.deleted(c1, "selfsub", newC.id)
+								pb.deleted(c2, "selfsub", newC.id)
+								pb.removeClauses(c1, c2)
+								pb.backwardSubsume(newC, occurs)
+								if pb.Status == Unsat {
+									return
 								}
+
 								modified = true
 								neverModified = false
 								break
@@ -231,30 +610,35 @@ func (pb *Problem) SelfSub() {
 						// if positive clause subsumes negative clause, delete literal from negative clause
 						} else if(canP){
 							// generate new clause with self-subsuming resolution
+							pb.memory().charge(clauseSize(c1.Len() + c2.Len()))
 							newC := c1.Generate(c2, v)
 							if !newC.Simplify() {
+								pb.derived(newC, "selfsub", c1.id, c2.id)
 								switch newC.Len() {
 								case 0:
 									log.Printf("Inferred UNSAT")
+									pb.setPartition(nil, newC.partition)
 									pb.Status = Unsat
 									return
 								case 1:
 									log.Printf("Unit %d", newC.First().Int())
 									lit2 := newC.First()
+									pb.setUnitID(lit2, newC.id)
+									pb.setPartition(newC.lits, newC.partition)
 									if lit2.IsPositive() {
 										if pb.Model[lit2.Var()] == -1 {
 											pb.Status = Unsat
 											log.Printf("Inferred UNSAT")
 											return
 										}
-										pb.Model[lit2.Var()] = 1
+										pb.setValue(lit2.Var(), 1)
 									} else {
 										if pb.Model[lit2.Var()] == 1 {
 											pb.Status = Unsat
 											log.Printf("Inferred UNSAT")
 											return
 										}
-										pb.Model[lit2.Var()] = -1
+										pb.setValue(lit2.Var(), -1)
 									}
 
 									// Check if unit literal exists so that we don't add duplicates
@@ -272,20 +656,19 @@ func (pb *Problem) SelfSub() {
 									}
 								default:
 									pb.Clauses = append(pb.Clauses, newC)
+									occurs.add(newC)
 								}
 							}
 
 							// REMOVE THE LITERAL FROM POSITIVE CLAUSE
-							if len(occurs[lit.Negation()])>0{
-								pb.Clauses[idx2] = pb.Clauses[len(pb.Clauses)-1]
-								pb.Clauses = pb.Clauses[:len(pb.Clauses)-1]
-								// Redo occurs
-								occurs = make([][]int, pb.NbVars*2)
-								for i, c := range pb.Clauses {
-									for j := 0; j < c.Len(); j++ {
-										occurs[c.Get(j)] = append(occurs[c.Get(j)], i)
-									}
+							if len(occurs.refs[lit.Negation()])>0{
+								pb.deleted(c2, "selfsub", newC.id)
+								pb.removeClauses(c2)
+								pb.backwardSubsume(newC, occurs)
+								if pb.Status == Unsat {
+									return
 								}
+								// Redo occurs
 								modified = true
 								neverModified = false
 								break
@@ -296,30 +679,35 @@ func (pb *Problem) SelfSub() {
 							// if negative clause subsumes positive clause, delete literal from positive clause
 						} else if(canN){
 							// generate new clause with self-subsuming resolution
+							pb.memory().charge(clauseSize(c2.Len() + c1.Len()))
 							newC := c2.Generate(c1, v)
 							if !newC.Simplify() {
+								pb.derived(newC, "selfsub", c2.id, c1.id)
 								switch newC.Len() {
 								case 0:
 									log.Printf("Inferred UNSAT")
+									pb.setPartition(nil, newC.partition)
 									pb.Status = Unsat
 									return
 								case 1:
 									log.Printf("Unit %d", newC.First().Int())
 									lit2 := newC.First()
+									pb.setUnitID(lit2, newC.id)
+									pb.setPartition(newC.lits, newC.partition)
 									if lit2.IsPositive() {
 										if pb.Model[lit2.Var()] == -1 {
 											pb.Status = Unsat
 											log.Printf("Inferred UNSAT")
 											return
 										}
-										pb.Model[lit2.Var()] = 1
+										pb.setValue(lit2.Var(), 1)
 									} else {
 										if pb.Model[lit2.Var()] == 1 {
 											pb.Status = Unsat
 											log.Printf("Inferred UNSAT")
 											return
 										}
-										pb.Model[lit2.Var()] = -1
+										pb.setValue(lit2.Var(), -1)
 									}
 
 									// Check if unit literal exists so that we don't add duplicates
@@ -337,20 +725,19 @@ func (pb *Problem) SelfSub() {
 									}
 								default:
 									pb.Clauses = append(pb.Clauses, newC)
+									occurs.add(newC)
 								}
 							}
 
 							// REMOVE THE LITERAL FROM NEGATIVE CLAUSE
-							if len(occurs[lit.Negation()])>0{
-								pb.Clauses[idx1] = pb.Clauses[len(pb.Clauses)-1]
-								pb.Clauses = pb.Clauses[:len(pb.Clauses)-1]
-								// Redo occurs
-								occurs = make([][]int, pb.NbVars*2)
-								for i, c := range pb.Clauses {
-									for j := 0; j < c.Len(); j++ {
-										occurs[c.Get(j)] = append(occurs[c.Get(j)], i)
-									}
+							if len(occurs.refs[lit.Negation()])>0{
+								pb.deleted(c1, "selfsub", newC.id)
+								pb.removeClauses(c1)
+								pb.backwardSubsume(newC, occurs)
+								if pb.Status == Unsat {
+									return
 								}
+								// Redo occurs
 								modified = true
 								neverModified = false
 								break
@@ -364,31 +751,104 @@ func (pb *Problem) SelfSub() {
 						break
 					}
 				}
+				if pb.nbSteps != nbSteps {
+					pb.selfSubCursor = (i + 1) % pb.NbVars
+				}
 				log.Printf("clauses=%s", pb.CNF())
 				continue
 			}
 		}
 	}
 	if !neverModified {
+		pb.clearUnusedCostLits()
 		pb.Simplify2()
 	}
 	log.Printf("Done. %d clauses now", len(pb.Clauses))
 }
 
+// removeClauses removes the given clauses from the problem.
+// Clauses are identified by ref, since the indices of the others may have changed.
+func (pb *Problem) removeClauses(cs ...*Clause) {
+	for _, c := range cs {
+		pb.markRemoved(c)
+	}
+	pb.sweep()
+}
+
+// backwardSubsume removes the clauses subsumed by c, a newly added clause, and strengthens the clauses it self-subsumes.
+// Strengthened clauses are in turn used for backward subsumption, so that the formula only shrinks.
+// As in SatELite, candidates are only looked for in the occurrences of the var of c that occurs the least:
+// a clause subsumed or strengthened by c contains either of its lits. They are then filtered through their signature.
+// c is ignored if it is not a clause of the problem. Long clauses do not subsume, see Options.MaxClauseLen.
+func (pb *Problem) backwardSubsume(c *Clause, occurs *Index) {
+	queue := make([]*Clause, 0, 1)
+	if c.ref != 0 && pb.Clause(c.ref) == c {
+		queue = append(queue, c)
+	}
+	defer pb.sweep()
+	for len(queue) > 0 {
+		c = queue[0]
+		queue = queue[1:]
+		if c.removed || pb.isLong(c) {
+			continue
+		}
+		sig := c.signature()
+		best, nbBest := c.First(), 0
+		for i, lit := range c.lits {
+			if nb := len(occurs.live(lit)) + len(occurs.live(lit.Negation())); i == 0 || nb < nbBest {
+				best, nbBest = lit, nb
+			}
+		}
+		candidates := append(occurs.supersets(best, sig), occurs.supersets(best.Negation(), sig)...)
+		for _, ref := range candidates {
+			c2 := pb.Clause(ref)
+			if c2 == nil || c2 == c || c2.removed || c2.protected || c2.Len() < c.Len() {
+				continue
+			}
+			if c.Subsumes(c2) {
+				pb.markRemoved(c2)
+				pb.deleted(c2, "selfsub", c.id)
+			} else if !pb.crossesPartitions(c, c2) && c.SelfSubsumes(c2) {
+				oldID, oldLits := c2.id, c2.lits
+				pb.saveClause(c2)
+				c2.setLits(c2.strengthen(c))
+				c2.pbData = nil
+				c2.origin = Derived
+				pb.replaced(c2, oldID, oldLits, "selfsub", oldID, c.id)
+				if c2.Len() == 1 {
+					pb.markRemoved(c2)
+					pb.setUnitID(c2.First(), c2.id)
+					pb.setPartition(c2.lits, c2.partition)
+					if pb.Model[c2.First().Var()] == 0 {
+						pb.addUnit(c2.First())
+					} else if (pb.Model[c2.First().Var()] == 1) != c2.First().IsPositive() {
+						pb.Status = Unsat
+						return
+					}
+				} else {
+					queue = append(queue, c2)
+				}
+			}
+		}
+	}
+}
+
 // Simplify with Subsumption
 func (pb *Problem) Subsumption() {
-	log.Printf("Preprocessing... %d clauses currently", len(pb.Clauses))
-	occurs := make([][]int, pb.NbVars*2)
-	for i, c := range pb.Clauses {
-		for j := 0; j < c.Len(); j++ {
-			occurs[c.Get(j)] = append(occurs[c.Get(j)], i)
-		}
+	if pb.Status != Undetermined || pb.skipped("subsumption") {
+		return
 	}
-	log.Printf("Occurence list: %s", occurs)
-	toRemove := make([]int, 0)
+	log.Printf("Preprocessing... %d clauses currently", len(pb.Clauses))
+	pb.useBitsets()
+	occurs := pb.index()
+	log.Printf("Occurence list: %v", occurs.refs)
 
 	// for each positive variable
 	for i := 0; i < pb.NbVars; i++ {
+		if pb.memory().exhausted() {
+			log.Printf("Memory budget exhausted, stopping")
+			break
+		}
 		if pb.Model[i] != 0 {
 			continue
 		}
@@ -398,64 +858,72 @@ func (pb *Problem) Subsumption() {
 		//nbLit2 := len(occurs[lit.Negation()])
 		log.Printf("Examining literal: %d", lit.Int())
 		// loop through the occurence list and compare clauses where the literals exist
-		for _, idx1 := range occurs[lit] {
-			for _, idx2 := range occurs[lit] {
+		for _, idx1 := range occurs.live(lit) {
+			for _, idx2 := range occurs.refs[lit] {
 				// clause 1
-				c1 := pb.Clauses[idx1]
+				c1 := pb.Clause(idx1)
 				// clause 2
-				c2 := pb.Clauses[idx2]
+				c2 := pb.Clause(idx2)
 
 				// CHECK IF POSSIBLE
 				if idx1 <= idx2 {
 					continue
 				}
-				if c1.Len() > c2.Len(){
+				if c1.Len() > c2.Len() && !pb.isLong(c2) && !c1.protected {
 					canP := c2.Subsumes(c1)
 					log.Printf("Can clause 2 subsume clause 1? %t",canP)
 					if canP{
-						// Save index of clause to remove for later
-						toRemove = append(toRemove, idx1)
+						// Mark the clause as removed, it is swept at the end
+						if pb.markRemoved(c1) {
+							pb.deleted(c1, "subsumption", c2.id)
+						}
 					}
 
 				}
-				if c2.Len() > c1.Len(){
+				if c2.Len() > c1.Len() && !pb.isLong(c1) && !c2.protected {
 					canN := c1.Subsumes(c2)
 					log.Printf("Can clause 1 subsume clause 2? %t",canN)
 					if canN{
-						// Save index of clause to remove for later
-						toRemove = append(toRemove, idx2)
+						// Mark the clause as removed, it is swept at the end
+						if pb.markRemoved(c2) {
+							pb.deleted(c2, "subsumption", c1.id)
+						}
 					}
 				}
 			}
 		}
 
 		// negative literal loop
-		for _, idx1 := range occurs[lit.Negation()] {
-			for _, idx2 := range occurs[lit.Negation()] {
+		for _, idx1 := range occurs.live(lit.Negation()) {
+			for _, idx2 := range occurs.refs[lit.Negation()] {
 				// clause 1
-				c1 := pb.Clauses[idx1]
+				c1 := pb.Clause(idx1)
 				// clause 2
-				c2 := pb.Clauses[idx2]
+				c2 := pb.Clause(idx2)
 
 				// CHECK IF POSSIBLE
 				if idx1 <= idx2 {
 					continue
 				}
-				if c1.Len() > c2.Len(){
+				if c1.Len() > c2.Len() && !pb.isLong(c2) && !c1.protected {
 					canP := c2.Subsumes(c1)
 					log.Printf("Can clause 2 subsume clause 1? %t",canP)
 					if canP{
-						// Save index of clause to remove for later
-						toRemove = append(toRemove, idx1)
+						// Mark the clause as removed, it is swept at the end
+						if pb.markRemoved(c1) {
+							pb.deleted(c1, "subsumption", c2.id)
+						}
 					}
 
 				}
-				if c2.Len() > c1.Len(){
+				if c2.Len() > c1.Len() && !pb.isLong(c1) && !c2.protected {
 					canN := c1.Subsumes(c2)
 					log.Printf("Can clause 1 subsume clause 2? %t",canN)
 					if canN{
-						// Save index of clause to remove for later
-						toRemove = append(toRemove, idx2)
+						// Mark the clause as removed, it is swept at the end
+						if pb.markRemoved(c2) {
+							pb.deleted(c2, "subsumption", c1.id)
+						}
 					}
 				}
 
@@ -463,31 +931,9 @@ func (pb *Problem) Subsumption() {
 		}
 	}
 
-	// Generate new clause list by removing all the subsumed clauses
-	newClauses := make([]*Clause, 0, 0)
-	match := false
-	for i := 0; i < len(pb.Clauses); i++ {
-		match = false
-		for j := 0; j < len(toRemove); j++ {
-
-			// REMOVE THE SUBSUMED CLAUSE
-			if toRemove[j] == i{
-				match = true
-			}
-		}
-		if match == false{
-			newClauses = append(newClauses,pb.Clauses[i])
-		}
-	}
-	pb.Clauses = newClauses
-
-	// Redo occurs
-	occurs = make([][]int, pb.NbVars*2)
-	for i, c := range pb.Clauses {
-		for j := 0; j < c.Len(); j++ {
-			occurs[c.Get(j)] = append(occurs[c.Get(j)], i)
-		}
-	}
+	// Remove all the subsumed clauses
+	pb.sweep()
+	pb.clearUnusedCostLits()
 
 	log.Printf("clauses=%s", pb.CNF())
 	pb.Simplify2()
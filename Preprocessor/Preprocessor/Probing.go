@@ -0,0 +1,142 @@
+package Preprocessor
+
+import (
+	"log"
+	"sort"
+)
+
+// PROBING
+// Failed literal probing with double lookahead: each probed var x is propagated with both polarities.
+// If x leads to a conflict, -x is a unit. If both x and -x imply l, l is a unit.
+// Optionally, each implication x -> y is recorded as the binary clause (-x | y).
+
+// Probe runs failed literal probing and double lookahead on the problem, as tuned by pb.Options.
+func (pb *Problem) Probe() {
+	if pb.Status != Undetermined || pb.Options.Interpolation || pb.skipped("probing") {
+		return
+	}
+	log.Printf("Probing... %d clauses currently", len(pb.Clauses))
+	db := pb.db("probe")
+	binaries := pb.binaries()
+	nbUnits := len(pb.Units)
+	nbBinaries := 0
+	for _, v := range pb.probeOrder() {
+		if pb.memory().exhausted() {
+			log.Printf("Memory budget exhausted, stopping")
+			break
+		}
+		if pb.Model[v] != 0 {
+			continue
+		}
+		confPos, impPos := db.Propagate([]Lit{v.Lit()})
+		confNeg, impNeg := db.Propagate([]Lit{v.Lit().Negation()})
+		switch {
+		case confPos && confNeg:
+			log.Printf("Inferred UNSAT")
+			pb.derivedUnitRUP(v.Lit().Negation(), "probe")
+			pb.addUnit(v.Lit().Negation())
+			pb.derivedRUP(NewClause([]Lit{}), "probe", nil)
+			pb.Status = Unsat
+			return
+		case confPos:
+			pb.derivedUnitRUP(v.Lit().Negation(), "probe")
+			pb.addUnit(v.Lit().Negation())
+		case confNeg:
+			pb.derivedUnitRUP(v.Lit(), "probe")
+			pb.addUnit(v.Lit())
+		default:
+			implied := make(map[Lit]bool, len(impPos))
+			for _, lit := range impPos {
+				implied[lit] = true
+			}
+			for _, lit := range impNeg {
+				if implied[lit] && pb.Model[lit.Var()] == 0 {
+					pb.lookaheadUnit(v, lit)
+					pb.addUnit(lit)
+				}
+			}
+			if !pb.Options.ProbeBinaries {
+				continue
+			}
+			for _, imp := range []struct {
+				lit     Lit
+				implied []Lit
+			}{{v.Lit(), impPos}, {v.Lit().Negation(), impNeg}} {
+				for _, lit := range imp.implied {
+					if pb.Options.ProbeMaxBinaries > 0 && nbBinaries >= pb.Options.ProbeMaxBinaries {
+						break
+					}
+					key := binaryKey(imp.lit.Negation(), lit)
+					if pb.Model[lit.Var()] == 0 && !binaries[key] {
+						binaries[key] = true
+						pb.memory().charge(clauseSize(2))
+						db.Add([]Lit{imp.lit.Negation(), lit})
+						nbBinaries++
+					}
+				}
+			}
+		}
+		if pb.Status == Unsat {
+			return
+		}
+	}
+	if len(pb.Units) > nbUnits {
+		db.newUnits = true
+	}
+	db.commit()
+	log.Printf("Done. %d units and %d binary clauses found, %d clauses now", len(pb.Units)-nbUnits, nbBinaries, len(pb.Clauses))
+}
+
+// lookaheadUnit records the derivation of the unit lit, implied by both v and -v.
+// lit is not implied by propagation, so the binary clauses (-v | lit) and (v | lit) are derived first, then deleted.
+func (pb *Problem) lookaheadUnit(v Var, lit Lit) {
+	pos := NewClause([]Lit{v.Lit().Negation(), lit})
+	neg := NewClause([]Lit{v.Lit(), lit})
+	pb.derivedRUP(pos, "probe", nil)
+	pb.derivedRUP(neg, "probe", nil)
+	pb.derivedUnit(lit, "probe", pos.id, neg.id)
+	pb.deleted(pos, "probe")
+	pb.deleted(neg, "probe")
+}
+
+// probeOrder returns the unbound vars to probe, most occurring first, as limited by pb.Options.ProbeVars.
+func (pb *Problem) probeOrder() []Var {
+	occurs := make([]int, pb.NbVars)
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			occurs[lit.Var()]++
+		}
+	}
+	vars := make([]Var, 0, pb.NbVars)
+	for i := 0; i < pb.NbVars; i++ {
+		if pb.Model[i] == 0 && occurs[i] > 0 {
+			vars = append(vars, Var(i))
+		}
+	}
+	// Ties are broken randomly
+	pb.random().Shuffle(len(vars), func(i, j int) { vars[i], vars[j] = vars[j], vars[i] })
+	sort.SliceStable(vars, func(i, j int) bool { return occurs[vars[i]] > occurs[vars[j]] })
+	if pb.Options.ProbeVars > 0 && len(vars) > pb.Options.ProbeVars {
+		vars = vars[:pb.Options.ProbeVars]
+	}
+	return vars
+}
+
+// binaries returns the set of binary clauses of the problem.
+func (pb *Problem) binaries() map[[2]Lit]bool {
+	res := make(map[[2]Lit]bool)
+	for _, c := range pb.Clauses {
+		if c.Len() == 2 {
+			res[binaryKey(c.Get(0), c.Get(1))] = true
+		}
+	}
+	return res
+}
+
+// binaryKey returns a key identifying the binary clause (l1 | l2), whatever the order of its lits.
+func binaryKey(l1, l2 Lit) [2]Lit {
+	if l1 > l2 {
+		return [2]Lit{l2, l1}
+	}
+	return [2]Lit{l1, l2}
+}
@@ -0,0 +1,133 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PROBLEM PROFILES
+// A few cheap statistics are often enough to characterize an instance, e.g to tell random problems,
+// whose clauses all have the same length and whose vars have similar degrees, from industrial ones.
+// Profile computes them in a single scan of the clauses, and WriteText renders them as text histograms.
+
+// profileBarWidth is the length of the longest bar of the histograms written by WriteText.
+const profileBarWidth = 50
+
+// A Profile gives statistics about the clauses of a problem. Units are only counted.
+type Profile struct {
+	NbVars    int
+	NbClauses int
+	NbUnits   int
+	Lengths   []int // Lengths[k] is the number of clauses with k lits.
+	NbPos     int   // Number of occurrences of positive lits in clauses.
+	NbNeg     int   // Number of occurrences of negative lits in clauses.
+	Degrees   []int // Degrees[d] is the number of vars appearing in d clauses. Vars bound by units or eliminated have a degree of 0.
+}
+
+// Profile returns the profile of the problem.
+func (pb *Problem) Profile() *Profile {
+	p := &Profile{NbVars: pb.NbVars, NbClauses: len(pb.Clauses), NbUnits: len(pb.Units)}
+	degrees := make([]int, pb.NbVars)
+	for _, c := range pb.Clauses {
+		for len(p.Lengths) <= c.Len() {
+			p.Lengths = append(p.Lengths, 0)
+		}
+		p.Lengths[c.Len()]++
+		for _, lit := range c.lits {
+			if lit.IsPositive() {
+				p.NbPos++
+			} else {
+				p.NbNeg++
+			}
+			degrees[lit.Var()]++
+		}
+	}
+	for _, d := range degrees {
+		for len(p.Degrees) <= d {
+			p.Degrees = append(p.Degrees, 0)
+		}
+		p.Degrees[d]++
+	}
+	return p
+}
+
+// BinaryFraction returns the fraction of clauses that are binary, or 0 if there is no clause.
+func (p *Profile) BinaryFraction() float64 {
+	if p.NbClauses == 0 || len(p.Lengths) <= 2 {
+		return 0
+	}
+	return float64(p.Lengths[2]) / float64(p.NbClauses)
+}
+
+// PositiveFraction returns the fraction of lit occurrences that are positive, or 0 if there is no clause.
+func (p *Profile) PositiveFraction() float64 {
+	if p.NbPos+p.NbNeg == 0 {
+		return 0
+	}
+	return float64(p.NbPos) / float64(p.NbPos+p.NbNeg)
+}
+
+// MeanDegree returns the average number of clauses a var appears in.
+func (p *Profile) MeanDegree() float64 {
+	if p.NbVars == 0 {
+		return 0
+	}
+	return float64(p.NbPos+p.NbNeg) / float64(p.NbVars)
+}
+
+// WriteText writes the profile in a human-readable form: a summary, followed by the histogram of clause lengths,
+// and the histogram of var degrees, whose degrees are grouped by powers of two.
+func (p *Profile) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%d vars, %d clauses, %d units\n", p.NbVars, p.NbClauses, p.NbUnits)
+	fmt.Fprintf(bw, "lits: %d positive, %d negative (%.1f%% positive)\n", p.NbPos, p.NbNeg, 100*p.PositiveFraction())
+	fmt.Fprintf(bw, "binary clauses: %.1f%%\n", 100*p.BinaryFraction())
+	fmt.Fprintf(bw, "mean degree: %.2f\n", p.MeanDegree())
+	var labels []string
+	var counts []int
+	for k, n := range p.Lengths {
+		if n > 0 {
+			labels = append(labels, fmt.Sprint(k))
+			counts = append(counts, n)
+		}
+	}
+	fmt.Fprintln(bw, "clause lengths:")
+	writeHistogram(bw, labels, counts)
+	labels, counts = nil, nil
+	for lo, hi := 0, 0; lo < len(p.Degrees); lo, hi = hi+1, 2*hi+1 { // 0, 1, 2-3, 4-7, ...
+		n := 0
+		for d := lo; d <= hi && d < len(p.Degrees); d++ {
+			n += p.Degrees[d]
+		}
+		if n > 0 {
+			label := fmt.Sprint(lo)
+			if hi > lo {
+				label = fmt.Sprintf("%d-%d", lo, hi)
+			}
+			labels = append(labels, label)
+			counts = append(counts, n)
+		}
+	}
+	fmt.Fprintln(bw, "var degrees:")
+	writeHistogram(bw, labels, counts)
+	return bw.Flush()
+}
+
+// writeHistogram writes one line per label, with its count and a bar proportional to it.
+func writeHistogram(w io.Writer, labels []string, counts []int) {
+	width, max := 0, 0
+	for i, label := range labels {
+		if len(label) > width {
+			width = len(label)
+		}
+		if counts[i] > max {
+			max = counts[i]
+		}
+	}
+	for i, label := range labels {
+		bar := (counts[i]*profileBarWidth + max - 1) / max
+		fmt.Fprintf(w, "  %*s %8d %s\n", width, label, counts[i], strings.Repeat("#", bar))
+	}
+}
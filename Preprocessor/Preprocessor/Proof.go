@@ -0,0 +1,169 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LRAT PROOFS
+// When pb.Options.LRAT is set, each derivation and deletion of a clause is written as an LRAT proof step
+// (see https://www.cs.cmu.edu/~mheule/publications/lrat.pdf), using clause IDs as LRAT IDs.
+// Hints of resolution-based steps (selfsub, subsumption, unit propagation) are the premises of the step.
+// Propagation-based techniques (vivification, probing, unhiding) do not know which clauses their propagations used,
+// so their hints are recomputed by propagating the negation of the derived clause on the clauses of the problem.
+// The proof is only valid w.r.t the original CNF if the option was set at parse time.
+
+// lratProof is the state of an LRAT proof being written.
+type lratProof struct {
+	w       io.Writer
+	deleted map[int]bool // IDs of the clauses deleted in the proof.
+	err     error        // First error met while writing.
+}
+
+// ProofErr returns the first error met while writing the LRAT proof, if any.
+func (pb *Problem) ProofErr() error {
+	if pb.lrat == nil {
+		return nil
+	}
+	return pb.lrat.err
+}
+
+// proof returns the LRAT proof being written, or nil if pb.Options.LRAT is not set.
+func (pb *Problem) proof() *lratProof {
+	if pb.Options.LRAT == nil {
+		return nil
+	}
+	if pb.lrat == nil {
+		pb.lrat = &lratProof{w: pb.Options.LRAT, deleted: make(map[int]bool)}
+	}
+	return pb.lrat
+}
+
+// tracking is true iff derivations and deletions must be given their premises.
+func (pb *Problem) tracking() bool {
+	return pb.Options.Provenance || pb.Options.LRAT != nil || pb.Options.DeltaWriter != nil
+}
+
+// writeLine writes a line of the proof, unless an error already happened.
+func (p *lratProof) writeLine(line string) {
+	if p.err == nil {
+		_, p.err = io.WriteString(p.w, line)
+	}
+}
+
+// add writes the derivation of the clause with the given ID, lits and hints.
+func (p *lratProof) add(id int, lits []Lit, hints []int) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d ", id)
+	for _, lit := range lits {
+		fmt.Fprintf(&sb, "%d ", lit.Int())
+	}
+	sb.WriteString("0")
+	seen := make(map[int]bool, len(hints))
+	for _, hint := range hints {
+		if hint != 0 && !seen[hint] { // A hint is useless once used
+			seen[hint] = true
+			fmt.Fprintf(&sb, " %d", hint)
+		}
+	}
+	sb.WriteString(" 0\n")
+	p.writeLine(sb.String())
+}
+
+// delete writes the deletion of the clause with the given ID. last is the ID of the last derived clause.
+func (p *lratProof) delete(last, id int) {
+	if id == 0 || p.deleted[id] {
+		return
+	}
+	p.deleted[id] = true
+	p.writeLine(fmt.Sprintf("%d d %d 0\n", last, id))
+}
+
+// rupHints returns the IDs of the clauses that, in that order, become unit then falsified
+// when the lits of c are falsified, units of the problem included.
+// Clauses deleted from the proof and c itself are ignored, the old clauses are considered in addition to pb.Clauses.
+// It returns nil if c cannot be derived by unit propagation.
+func (pb *Problem) rupHints(c *Clause, old ...*Clause) []int {
+	values := make([]decLevel, pb.NbVars)
+	reasons := make([]*Clause, pb.NbVars) // nil for falsified lits of c.
+	trail := make([]Lit, 0, len(pb.Units)+c.Len())
+	assign := func(lit Lit, reason *Clause) {
+		if lit.IsPositive() {
+			values[lit.Var()] = 1
+		} else {
+			values[lit.Var()] = -1
+		}
+		reasons[lit.Var()] = reason
+		trail = append(trail, lit)
+	}
+	isTrue := func(lit Lit) bool { return values[lit.Var()] != 0 && (values[lit.Var()] == 1) == lit.IsPositive() }
+	for _, lit := range c.lits {
+		if values[lit.Var()] == 0 {
+			assign(lit.Negation(), nil)
+		}
+	}
+	for _, lit := range pb.Units {
+		if values[lit.Var()] == 0 {
+			assign(lit, &Clause{lits: []Lit{lit}, id: pb.UnitID(lit.Var())})
+		} else if !isTrue(lit) { // c is satisfied by a unit
+			return []int{pb.UnitID(lit.Var())}
+		}
+	}
+	clauses := make([]*Clause, 0, len(pb.Clauses)+len(old))
+	for _, c2 := range append(pb.Clauses, old...) {
+		if c2 != c && (pb.lrat == nil || !pb.lrat.deleted[c2.id]) { // No proof is written when only the provenance is recorded
+			clauses = append(clauses, c2)
+		}
+	}
+	var conflict *Clause
+	for modified := true; modified && conflict == nil; {
+		modified = false
+		for _, c2 := range clauses {
+			var unbound Lit
+			nbUnbound := 0
+			sat := false
+			for _, lit := range c2.lits {
+				if values[lit.Var()] == 0 {
+					unbound = lit
+					nbUnbound++
+				} else if isTrue(lit) {
+					sat = true
+					break
+				}
+			}
+			if sat || nbUnbound > 1 {
+				continue
+			}
+			if nbUnbound == 0 {
+				conflict = c2
+				break
+			}
+			assign(unbound, c2)
+			modified = true
+		}
+	}
+	if conflict == nil {
+		return nil
+	}
+	// Only keep the reasons the conflict depends on, in trail order.
+	needed := make([]bool, pb.NbVars)
+	for _, lit := range conflict.lits {
+		needed[lit.Var()] = true
+	}
+	hints := []int{conflict.id}
+	for i := len(trail) - 1; i >= 0; i-- {
+		v := trail[i].Var()
+		if !needed[v] || reasons[v] == nil {
+			continue
+		}
+		hints = append(hints, reasons[v].id)
+		for _, lit := range reasons[v].lits {
+			needed[lit.Var()] = true
+		}
+	}
+	for i, j := 0, len(hints)-1; i < j; i, j = i+1, j-1 {
+		hints[i], hints[j] = hints[j], hints[i]
+	}
+	return hints
+}
@@ -0,0 +1,373 @@
+package Preprocessor
+
+// UNIT PROPAGATION
+// A propagator runs unit propagation on a set of clauses with two watched lits per clause:
+// a clause is only visited when one of its two watched lits becomes false, so propagation is linear
+// in the number of visited watches rather than in the size of the formula.
+// The lits of the clauses are never reordered: the positions of the watched lits are kept by the propagator.
+// Level 0 holds the units of the problem and what they imply; assumptions are made at level 1 and undone by backtrack.
+
+// noClause is the index used for lits that have no reason clause, and for the absence of conflict.
+const noClause = -1
+
+// unitConflict is the index used for the conflict when a unit of the problem is false at level 0, see sync.
+const unitConflict = -2
+
+// A propagator is a unit propagation engine over clauses identified by their index.
+type propagator struct {
+	clauses []*Clause
+	removed []bool
+	watched [][2]int // For each clause, positions of its watched lits.
+	watches [][]int  // For each lit, indices of the clauses watching it.
+	values  []decLevel
+	reasons []int // For each bound var, index of the clause that implied it, or noClause.
+	trail   []Lit
+	head    int // Index in trail of the next lit to propagate.
+	level0  int // Length of the trail at level 0, or -1 if at level 0.
+	nbUnits int // Number of units of the problem already known to the propagator.
+	unsat   int // Index of a clause falsified at level 0, unitConflict, or noClause.
+
+	conflictUnit Lit // Unit of the problem found false by sync, if unsat is unitConflict.
+}
+
+// propagator returns a propagation engine over the clauses and units of the problem.
+// Units are only propagated when propagate is called.
+func (pb *Problem) propagator() *propagator {
+	pb.memory().charge(propagatorSize(pb.NbVars, len(pb.Clauses)))
+	p := &propagator{
+		watches: make([][]int, pb.NbVars*2),
+		values:  make([]decLevel, pb.NbVars),
+		reasons: make([]int, pb.NbVars),
+		trail:   make([]Lit, 0, pb.NbVars),
+		level0:  -1,
+		unsat:   noClause,
+	}
+	p.sync(pb)
+	for _, c := range pb.Clauses {
+		p.add(c)
+	}
+	return p
+}
+
+// Propagate runs unit propagation on the clauses and units of the problem under the given assumptions,
+// e.g for lookahead, implication queries or to check that a partial assignment is consistent. The problem is not modified.
+// It returns whether a conflict was met, and if not, the lits implied by the assumptions, in propagation order.
+// Neither the assumptions nor the lits implied by the units alone are part of implied.
+// The propagation engine is kept between calls, until the problem is modified.
+func (pb *Problem) Propagate(assumptions []Lit) (conflict bool, implied []Lit) {
+	if pb.Status == Unsat {
+		return true, nil
+	}
+	p := pb.cachedPropagator()
+	defer p.backtrack()
+	assumed := make(map[Lit]bool, len(assumptions))
+	for _, lit := range assumptions {
+		if !p.assume(lit) {
+			return true, nil
+		}
+		assumed[lit] = true
+	}
+	if p.propagate() != noClause {
+		return true, nil
+	}
+	if p.level0 == -1 { // No assumptions
+		return false, nil
+	}
+	for _, lit := range p.trail[p.level0:] {
+		if !assumed[lit] {
+			implied = append(implied, lit)
+		}
+	}
+	return false, implied
+}
+
+// Implies returns whether propagating a implies b under the clauses and units of the problem,
+// e.g to find out why an encoding forces a var. If it does, it also returns the IDs of the clauses the propagation used,
+// in an order where each clause only relies on the previous ones and on a; units are given by the ID of their clause.
+// If a leads to a conflict, it implies every lit, and the clauses leading to the conflict are returned.
+func (pb *Problem) Implies(a, b Lit) (bool, []int) {
+	if pb.Status == Unsat {
+		if pb.emptyID != 0 {
+			return true, []int{pb.emptyID}
+		}
+		return true, nil
+	}
+	p := pb.cachedPropagator()
+	defer p.backtrack()
+	e := &explainer{pb: pb, p: p, assumption: -1, seen: make([]bool, pb.NbVars)}
+	if p.level0 == -1 {
+		if idx := p.propagate(); idx != noClause { // The problem is Unsat through propagation alone
+			return true, e.clause(idx)
+		}
+	}
+	if p.isFalse(a) {
+		return true, e.lit(a.Negation())
+	}
+	if !p.isTrue(a) {
+		e.assumption = a.Var()
+	}
+	p.assume(a)
+	if idx := p.propagate(); idx != noClause {
+		return true, e.clause(idx)
+	}
+	if !p.isTrue(b) {
+		return false, nil
+	}
+	return true, e.lit(b)
+}
+
+// An explainer gathers the clauses that made a propagator bind lits.
+type explainer struct {
+	pb         *Problem
+	p          *propagator
+	assumption Var // Var assumed by the propagator, whose binding needs no explanation, or -1 if none.
+	seen       []bool
+	ids        []int
+}
+
+// lit returns the IDs of the clauses that made lit true, see Implies.
+func (e *explainer) lit(lit Lit) []int {
+	e.explain(lit.Var())
+	return e.ids
+}
+
+// clause returns the IDs of the clauses that made every lit of the clause with the given index false,
+// followed by its own ID. For unitConflict, they are the IDs of the clauses that made the conflicting unit false,
+// followed by the ID of the unit.
+func (e *explainer) clause(idx int) []int {
+	if idx == unitConflict {
+		v := e.p.conflictUnit.Var()
+		e.explain(v)
+		if e.p.reasons[v] != noClause { // Otherwise the unit shares its ID with the opposite unit
+			if id := e.pb.UnitID(v); id != 0 {
+				e.ids = append(e.ids, id)
+			}
+		}
+		return e.ids
+	}
+	for _, lit := range e.p.clauses[idx].lits {
+		e.explain(lit.Var())
+	}
+	e.ids = append(e.ids, e.p.clauses[idx].id)
+	return e.ids
+}
+
+// explain adds the IDs of the clauses that made v bound, after the IDs of the clauses they rely on.
+func (e *explainer) explain(v Var) {
+	if e.seen[v] {
+		return
+	}
+	e.seen[v] = true
+	idx := e.p.reasons[v]
+	if idx == noClause {
+		if v != e.assumption {
+			if id := e.pb.UnitID(v); id != 0 {
+				e.ids = append(e.ids, id)
+			}
+		}
+		return
+	}
+	for _, lit := range e.p.clauses[idx].lits {
+		if lit.Var() != v {
+			e.explain(lit.Var())
+		}
+	}
+	e.ids = append(e.ids, e.p.clauses[idx].id)
+}
+
+// cachedPropagator returns a propagation engine over the problem, kept between calls until the problem is modified.
+func (pb *Problem) cachedPropagator() *propagator {
+	if pb.prop == nil || pb.propSteps != pb.nbSteps || len(pb.prop.values) != pb.NbVars {
+		pb.prop, pb.propSteps = pb.propagator(), pb.nbSteps
+	}
+	return pb.prop
+}
+
+// isTrue is true iff lit is bound to true.
+func (p *propagator) isTrue(lit Lit) bool {
+	val := p.values[lit.Var()]
+	return val != 0 && (val == 1) == lit.IsPositive()
+}
+
+// isFalse is true iff lit is bound to false.
+func (p *propagator) isFalse(lit Lit) bool {
+	val := p.values[lit.Var()]
+	return val != 0 && (val == 1) != lit.IsPositive()
+}
+
+// assign binds lit to true because of the given clause.
+func (p *propagator) assign(lit Lit, reason int) {
+	if lit.IsPositive() {
+		p.values[lit.Var()] = 1
+	} else {
+		p.values[lit.Var()] = -1
+	}
+	p.reasons[lit.Var()] = reason
+	p.trail = append(p.trail, lit)
+}
+
+// sync assigns, at level 0, the units that were added to the problem since the last call.
+func (p *propagator) sync(pb *Problem) {
+	for _, lit := range pb.Units[p.nbUnits:] {
+		if p.isFalse(lit) {
+			p.unsat, p.conflictUnit = unitConflict, lit
+		} else if !p.isTrue(lit) {
+			p.assign(lit, noClause)
+		}
+	}
+	p.nbUnits = len(pb.Units)
+}
+
+// add adds c to the clauses, at level 0, and returns its index.
+// If c is unit or falsified by the current assignment, its lit is assigned or c is recorded as the conflict.
+func (p *propagator) add(c *Clause) int {
+	idx := len(p.clauses)
+	p.clauses = append(p.clauses, c)
+	p.removed = append(p.removed, false)
+	p.watched = append(p.watched, [2]int{noClause, noClause})
+	p.watch(idx)
+	return idx
+}
+
+// watch chooses the watched lits of the clause with the given index, at level 0.
+func (p *propagator) watch(idx int) {
+	c := p.clauses[idx]
+	// Watch the first two lits that are not false, if any, or a true lit
+	w := [2]int{noClause, noClause}
+	nbWatched := 0
+	for i, lit := range c.lits {
+		if !p.isFalse(lit) {
+			w[nbWatched] = i
+			if nbWatched++; nbWatched == 2 || p.isTrue(lit) {
+				break
+			}
+		}
+	}
+	switch {
+	case c.Len() == 0 || nbWatched == 0:
+		p.unsat = idx
+	case nbWatched == 1 && !p.isTrue(c.lits[w[0]]):
+		p.assign(c.lits[w[0]], idx)
+	}
+	// Other lits are false at level 0, and will stay so.
+	for i := 0; i < c.Len() && nbWatched < 2; i++ {
+		if i != w[0] {
+			w[nbWatched] = i
+			nbWatched++
+		}
+	}
+	p.watched[idx] = w
+	if c.Len() >= 2 { // Shorter clauses are already assigned or falsified
+		for _, pos := range w {
+			p.watches[c.lits[pos]] = append(p.watches[c.lits[pos]], idx)
+		}
+	}
+}
+
+// remove removes the clause with the given index. It is lazily removed from the watch lists.
+func (p *propagator) remove(idx int) {
+	p.removed[idx] = true
+}
+
+// restore undoes the removal of the clause with the given index, at level 0.
+// Its watches may have been dropped or have become false meanwhile, so they are chosen again.
+func (p *propagator) restore(idx int) {
+	p.removed[idx] = false
+	if c := p.clauses[idx]; c.Len() >= 2 {
+		for _, pos := range p.watched[idx] {
+			watches := p.watches[c.lits[pos]]
+			for i := range watches {
+				if watches[i] == idx {
+					p.watches[c.lits[pos]] = append(watches[:i], watches[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	p.watch(idx)
+}
+
+// propagate propagates the lits assigned since the last call, and returns the index of a falsified clause, or noClause.
+func (p *propagator) propagate() int {
+	if p.unsat != noClause {
+		return p.unsat
+	}
+	for p.head < len(p.trail) {
+		falseLit := p.trail[p.head].Negation()
+		p.head++
+		watches := p.watches[falseLit]
+		kept := 0
+		for i, idx := range watches {
+			if p.removed[idx] {
+				continue
+			}
+			c := p.clauses[idx]
+			w := &p.watched[idx]
+			if c.lits[w[0]] == falseLit {
+				w[0], w[1] = w[1], w[0]
+			}
+			// Now, the false lit is watched at position w[1]
+			if p.isTrue(c.lits[w[0]]) {
+				watches[kept] = idx
+				kept++
+				continue
+			}
+			moved := false
+			for pos, lit := range c.lits {
+				if pos != w[0] && pos != w[1] && !p.isFalse(lit) {
+					w[1] = pos
+					p.watches[lit] = append(p.watches[lit], idx)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				continue
+			}
+			watches[kept] = idx
+			kept++
+			if p.isFalse(c.lits[w[0]]) {
+				kept += copy(watches[kept:], watches[i+1:])
+				p.watches[falseLit] = watches[:kept]
+				if p.level0 == -1 {
+					p.unsat = idx
+				}
+				return idx
+			}
+			p.assign(c.lits[w[0]], idx)
+		}
+		p.watches[falseLit] = watches[:kept]
+	}
+	return noClause
+}
+
+// assume makes a new decision level, unless there is one already, and assigns lit in it.
+// Level 0 is propagated first. It returns false if lit is already false.
+func (p *propagator) assume(lit Lit) bool {
+	if p.level0 == -1 {
+		if p.propagate() != noClause {
+			return false
+		}
+		p.level0 = len(p.trail)
+	}
+	if p.isFalse(lit) {
+		return false
+	}
+	if !p.isTrue(lit) {
+		p.assign(lit, noClause)
+	}
+	return true
+}
+
+// backtrack undoes all assignments made since the last return to level 0.
+func (p *propagator) backtrack() {
+	if p.level0 == -1 {
+		return
+	}
+	for _, lit := range p.trail[p.level0:] {
+		p.values[lit.Var()] = 0
+	}
+	p.trail = p.trail[:p.level0]
+	p.head = p.level0
+	p.level0 = -1
+}
@@ -0,0 +1,44 @@
+package Preprocessor
+
+import (
+	"strings"
+	"testing"
+)
+
+// withOppositeUnits returns a problem whose units hold both 1 and -1, as some passes may append them.
+func withOppositeUnits(t *testing.T) *Problem {
+	pb, err := ParseCNFWithOptions(strings.NewReader("p cnf 3 1\n1 0\n2 3 0\n"), Options{Provenance: true})
+	if err != nil {
+		t.Fatalf("could not parse problem: %v", err)
+	}
+	if len(pb.Units) != 1 {
+		t.Fatalf("expected unit 1, got %v", pb.Units)
+	}
+	pb.Units = append(pb.Units, IntToLit(-1))
+	return pb
+}
+
+func TestSimplifyOppositeUnits(t *testing.T) {
+	pb := withOppositeUnits(t)
+	id := pb.UnitID(0)
+	pb.Simplify2()
+	if pb.Status != Unsat {
+		t.Fatalf("expected Unsat, got %v", pb.Status)
+	}
+	steps := pb.Provenance().Steps()
+	last := steps[len(steps)-1]
+	if len(last.Lits) != 0 || len(last.Premises) != 1 || last.Premises[0] != id {
+		t.Errorf("expected the empty clause derived from unit %d, got %+v", id, last)
+	}
+}
+
+func TestImpliesOppositeUnits(t *testing.T) {
+	pb := withOppositeUnits(t)
+	ok, premises := pb.Implies(IntToLit(2), IntToLit(3))
+	if !ok {
+		t.Fatalf("expected an Unsat problem to imply anything")
+	}
+	if id := pb.UnitID(0); len(premises) != 1 || premises[0] != id {
+		t.Errorf("expected premises [%d], got %v", id, premises)
+	}
+}
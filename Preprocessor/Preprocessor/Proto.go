@@ -0,0 +1,398 @@
+package Preprocessor
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// PROTOBUF EXCHANGE
+// Distributed solvers ship preprocessed problems between machines: ToProto and FromProto encode them as the
+// Problem message of problem.proto, so that any protobuf implementation can read them, without the cost of parsing text.
+// The wire format is simple enough to be written by hand, which spares the package a dependency on a protobuf library.
+// Besides the clauses and units, the message holds what is needed to use the problem on the other side:
+// the cost function, the lit weights, and the elimination stack, so that models can be extended, see extendModel.
+// Gates, provenance, protected clauses, partitions of interpolation problems and the state of passes are not exchanged.
+// Problems built with the lit64 tag, see Lit32.go, write their lits the same way, but problems with more than 2^30 vars
+// can only be read by readers taking the fields of lits as sint64 fields, and nb_vars as a uint64 field.
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Field numbers of the messages of problem.proto.
+const (
+	protoNbVars      = 1
+	protoStatus      = 2
+	protoUnits       = 3
+	protoClauses     = 4
+	protoCostLits    = 5
+	protoCostWeights = 6
+	protoLitWeights  = 7
+	protoEliminated  = 8
+
+	protoClauseLits = 1
+
+	protoElimLits   = 1
+	protoElimOthers = 2
+
+	protoMapKey   = 1
+	protoMapValue = 2
+)
+
+// ToProto returns the problem encoded as a Problem message of problem.proto.
+func (pb *Problem) ToProto() []byte {
+	var b []byte
+	b = appendVarintField(b, protoNbVars, uint64(pb.NbVars))
+	b = appendVarintField(b, protoStatus, uint64(pb.Status))
+	b = appendLits(b, protoUnits, pb.Units)
+	for _, c := range pb.Clauses {
+		b = appendBytes(b, protoClauses, appendLits(nil, protoClauseLits, c.lits))
+	}
+	b = appendLits(b, protoCostLits, pb.minLits)
+	if len(pb.minWeights) > 0 {
+		var weights []byte
+		for _, w := range pb.minWeights {
+			weights = appendVarint(weights, uint64(int64(w)))
+		}
+		b = appendBytes(b, protoCostWeights, weights)
+	}
+	lits := make([]Lit, 0, len(pb.LitWeights))
+	for lit := range pb.LitWeights {
+		lits = append(lits, lit)
+	}
+	sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+	for _, lit := range lits {
+		entry := appendVarintField(nil, protoMapKey, zigzag(lit.Int()))
+		entry = appendVarint(entry, protoMapValue<<3|wireFixed64)
+		var bits [8]byte
+		binary.LittleEndian.PutUint64(bits[:], math.Float64bits(pb.LitWeights[lit]))
+		entry = append(entry, bits[:]...)
+		b = appendBytes(b, protoLitWeights, entry)
+	}
+	for _, e := range pb.eliminated {
+		elim := appendLits(nil, protoElimLits, e.lits)
+		for _, others := range e.others {
+			elim = appendBytes(elim, protoElimOthers, appendLits(nil, protoClauseLits, others))
+		}
+		b = appendBytes(b, protoEliminated, elim)
+	}
+	return b
+}
+
+// FromProto returns the problem encoded in the given Problem message of problem.proto.
+// Units are not propagated, as with ParseCNF. The returned error wraps ErrBadInput if the message is malformed.
+func FromProto(data []byte) (*Problem, error) {
+	var (
+		nbVars      int
+		status      Status
+		units       []Lit
+		clauses     [][]Lit
+		costLits    []Lit
+		costWeights []int
+		litWeights  map[Lit]float64
+		eliminated  []elimination
+	)
+	err := readFields(data, func(field, wireType int, r *protoReader) error {
+		switch field {
+		case protoNbVars:
+			n, err := r.varint()
+			if err == nil && n > MaxVar {
+				return badInput("invalid protobuf: invalid number of vars %d", n)
+			}
+			nbVars = int(n)
+			return err
+		case protoStatus:
+			s, err := r.varint()
+			if err == nil && s > uint64(Unsat) {
+				return badInput("invalid protobuf: unknown status %d", s)
+			}
+			status = Status(s)
+			return err
+		case protoUnits:
+			return r.lits(wireType, &units)
+		case protoClauses:
+			var lits []Lit
+			err := r.message(func(field, wireType int, r *protoReader) error {
+				if field == protoClauseLits {
+					return r.lits(wireType, &lits)
+				}
+				return r.skip(wireType)
+			})
+			clauses = append(clauses, lits)
+			return err
+		case protoCostLits:
+			return r.lits(wireType, &costLits)
+		case protoCostWeights:
+			return r.varints(wireType, func(w uint64) { costWeights = append(costWeights, int(int64(w))) })
+		case protoLitWeights:
+			var lit Lit
+			var w float64
+			err := r.message(func(field, wireType int, r *protoReader) error {
+				switch {
+				case field == protoMapKey:
+					var lits []Lit
+					err := r.lits(wireType, &lits)
+					if len(lits) == 1 {
+						lit = lits[0]
+					}
+					return err
+				case field == protoMapValue && wireType == wireFixed64:
+					bits, err := r.fixed64()
+					w = math.Float64frombits(bits)
+					return err
+				}
+				return r.skip(wireType)
+			})
+			if litWeights == nil {
+				litWeights = make(map[Lit]float64)
+			}
+			litWeights[lit] = w
+			return err
+		case protoEliminated:
+			var e elimination
+			err := r.message(func(field, wireType int, r *protoReader) error {
+				switch field {
+				case protoElimLits:
+					return r.lits(wireType, &e.lits)
+				case protoElimOthers:
+					var lits []Lit
+					err := r.message(func(field, wireType int, r *protoReader) error {
+						if field == protoClauseLits {
+							return r.lits(wireType, &lits)
+						}
+						return r.skip(wireType)
+					})
+					e.others = append(e.others, lits)
+					return err
+				}
+				return r.skip(wireType)
+			})
+			if err == nil && len(e.lits) == 0 {
+				return badInput("invalid protobuf: empty eliminated clause")
+			}
+			eliminated = append(eliminated, e)
+			return err
+		}
+		return r.skip(wireType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if costWeights != nil && len(costWeights) != len(costLits) {
+		return nil, badInput("invalid protobuf: %d cost lits, but %d weights", len(costLits), len(costWeights))
+	}
+	// Lits must be lits of the nbVars vars: the problem would otherwise grow to fit them
+	all := append(append(append([][]Lit(nil), clauses...), units), costLits)
+	for lit := range litWeights {
+		all = append(all, []Lit{lit})
+	}
+	for _, e := range eliminated {
+		all = append(append(all, e.lits), e.others...)
+	}
+	for _, lits := range all {
+		for _, lit := range lits {
+			if int(lit.Var()) >= nbVars {
+				return nil, badInput("invalid protobuf: lit %d is not a lit of the %d vars", lit.Int(), nbVars)
+			}
+		}
+	}
+	pb := NewProblem(nbVars)
+	for _, lits := range clauses {
+		pb.AddClause(lits)
+	}
+	for _, lit := range units {
+		pb.AddClause([]Lit{lit})
+	}
+	if status == Unsat {
+		pb.AddClause(nil)
+	} else if status == Sat && len(pb.Clauses) == 0 {
+		pb.Status = Sat
+	}
+	pb.SetCostFunc(costLits, costWeights)
+	pb.LitWeights = litWeights
+	pb.eliminated = eliminated
+	return pb, nil
+}
+
+// zigzag returns the zigzag encoding of x, used by sint32 and sint64 fields, which encode the same values the same way.
+func zigzag(x LitInt) uint64 {
+	return uint64(int64(x)<<1) ^ uint64(int64(x)>>63)
+}
+
+// appendVarint appends the varint encoding of x to b.
+func appendVarint(b []byte, x uint64) []byte {
+	for x >= 0x80 {
+		b = append(b, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(b, byte(x))
+}
+
+// appendVarintField appends a varint field to b, unless x is 0, the default value.
+func appendVarintField(b []byte, field int, x uint64) []byte {
+	if x == 0 {
+		return b
+	}
+	return appendVarint(appendVarint(b, uint64(field)<<3|wireVarint), x)
+}
+
+// appendBytes appends a length-delimited field to b.
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|wireBytes)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendLits appends the given lits to b as a packed sint32 field of DIMACS lits, unless there is none.
+func appendLits(b []byte, field int, lits []Lit) []byte {
+	if len(lits) == 0 {
+		return b
+	}
+	var packed []byte
+	for _, lit := range lits {
+		packed = appendVarint(packed, zigzag(lit.Int()))
+	}
+	return appendBytes(b, field, packed)
+}
+
+// A protoReader reads a protobuf message.
+type protoReader struct {
+	data []byte
+}
+
+// readFields calls f on each field of the given message. f must read or skip the value of the field.
+func readFields(data []byte, f func(field, wireType int, r *protoReader) error) error {
+	r := &protoReader{data: data}
+	for len(r.data) > 0 {
+		tag, err := r.varint()
+		if err != nil {
+			return err
+		}
+		if tag>>3 == 0 {
+			return badInput("invalid protobuf: field number 0")
+		}
+		if err := f(int(tag>>3), int(tag&7), r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varint reads a varint.
+func (r *protoReader) varint() (uint64, error) {
+	var x uint64
+	for i, b := range r.data {
+		if i == 10 {
+			break
+		}
+		x |= uint64(b&0x7f) << (7 * uint(i))
+		if b < 0x80 {
+			r.data = r.data[i+1:]
+			return x, nil
+		}
+	}
+	return 0, badInput("invalid protobuf: truncated or overlong varint")
+}
+
+// fixed64 reads a little-endian 64-bit value.
+func (r *protoReader) fixed64() (uint64, error) {
+	if len(r.data) < 8 {
+		return 0, badInput("invalid protobuf: truncated fixed64")
+	}
+	x := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return x, nil
+}
+
+// bytes reads a length-delimited value.
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.data)) {
+		return nil, badInput("invalid protobuf: truncated length-delimited field")
+	}
+	res := r.data[:n]
+	r.data = r.data[n:]
+	return res, nil
+}
+
+// message reads an embedded message, calling f on each of its fields, as readFields does.
+func (r *protoReader) message(f func(field, wireType int, r *protoReader) error) error {
+	data, err := r.bytes()
+	if err != nil {
+		return err
+	}
+	return readFields(data, f)
+}
+
+// varints reads a repeated varint field, packed or not, and calls f on each value.
+func (r *protoReader) varints(wireType int, f func(x uint64)) error {
+	switch wireType {
+	case wireVarint:
+		x, err := r.varint()
+		if err == nil {
+			f(x)
+		}
+		return err
+	case wireBytes:
+		data, err := r.bytes()
+		if err != nil {
+			return err
+		}
+		packed := &protoReader{data: data}
+		for len(packed.data) > 0 {
+			x, err := packed.varint()
+			if err != nil {
+				return err
+			}
+			f(x)
+		}
+		return nil
+	}
+	return badInput("invalid protobuf: wire type %d for a varint field", wireType)
+}
+
+// lits reads a repeated sint32 field of DIMACS lits, packed or not, and appends them to lits.
+func (r *protoReader) lits(wireType int, lits *[]Lit) error {
+	var err error
+	err2 := r.varints(wireType, func(x uint64) {
+		val := int64(x>>1) ^ -int64(x&1)
+		if val == 0 || val > MaxVar || val < -MaxVar {
+			err = badInput("invalid protobuf: invalid lit %d", val)
+			return
+		}
+		*lits = append(*lits, IntToLit(LitInt(val)))
+	})
+	if err2 != nil {
+		return err2
+	}
+	return err
+}
+
+// skip skips the value of a field of the given wire type.
+func (r *protoReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		if len(r.data) < 4 {
+			return badInput("invalid protobuf: truncated fixed32")
+		}
+		r.data = r.data[4:]
+	default:
+		return badInput("invalid protobuf: unsupported wire type %d", wireType)
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package Preprocessor
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// protoProblem returns a problem using every field of the Problem message of problem.proto.
+func protoProblem(t *testing.T) *Problem {
+	pb := parse(t, "p cnf 4 2\n1 -2 0\n-3 0\n")
+	pb.SetCostFunc([]Lit{IntToLit(2), IntToLit(-1)}, []int{5, 1})
+	pb.LitWeights = map[Lit]float64{IntToLit(1): 0.5}
+	pb.eliminated = []elimination{{lits: []Lit{IntToLit(4), IntToLit(1)}, others: [][]Lit{{IntToLit(-4), IntToLit(2)}}}}
+	return pb
+}
+
+// TestProtoEncoding checks ToProto against the encoding of the message by hand, as any protobuf implementation
+// serializes it: fields in increasing order, repeated scalars packed, sint32 zigzag-encoded, doubles little-endian.
+func TestProtoEncoding(t *testing.T) {
+	expected := []byte{
+		0x08, 0x04, // nb_vars: 4
+		0x1a, 0x01, 0x05, // units: [-3]
+		0x22, 0x04, 0x0a, 0x02, 0x02, 0x03, // clauses: [{lits: [1, -2]}]
+		0x2a, 0x02, 0x04, 0x01, // cost_lits: [2, -1]
+		0x32, 0x02, 0x05, 0x01, // cost_weights: [5, 1]
+		0x3a, 0x0b, 0x08, 0x02, 0x11, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xe0, 0x3f, // lit_weights: {1: 0.5}
+		0x42, 0x0a, 0x0a, 0x02, 0x08, 0x02, 0x12, 0x04, 0x0a, 0x02, 0x07, 0x04, // eliminated: [{lits: [4, 1], others: [{lits: [-4, 2]}]}]
+	}
+	if got := protoProblem(t).ToProto(); !bytes.Equal(got, expected) {
+		t.Errorf("invalid encoding:\nexpected % x\ngot      % x", expected, got)
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	pb := protoProblem(t)
+	pb2, err := FromProto(pb.ToProto())
+	if err != nil {
+		t.Fatalf("could not decode problem: %v", err)
+	}
+	if pb2.NbVars != pb.NbVars || pb2.Status != pb.Status {
+		t.Errorf("expected %d vars and status %v, got %d and %v", pb.NbVars, pb.Status, pb2.NbVars, pb2.Status)
+	}
+	if pb2.CNF() != pb.CNF() {
+		t.Errorf("expected clauses and units\n%s\ngot\n%s", pb.CNF(), pb2.CNF())
+	}
+	lits, weights := pb.CostLits()
+	lits2, weights2 := pb2.CostLits()
+	if !reflect.DeepEqual(lits2, lits) || !reflect.DeepEqual(weights2, weights) {
+		t.Errorf("expected cost function %v %v, got %v %v", lits, weights, lits2, weights2)
+	}
+	if !reflect.DeepEqual(pb2.LitWeights, pb.LitWeights) {
+		t.Errorf("expected lit weights %v, got %v", pb.LitWeights, pb2.LitWeights)
+	}
+	if !reflect.DeepEqual(pb2.eliminated, pb.eliminated) {
+		t.Errorf("expected elimination stack %v, got %v", pb.eliminated, pb2.eliminated)
+	}
+	// An Unsat problem stays Unsat
+	pb = parse(t, "p cnf 1 2\n1 0\n-1 0\n")
+	if pb2, err := FromProto(pb.ToProto()); err != nil || pb2.Status != Unsat {
+		t.Errorf("expected an Unsat problem, got %v, %v", pb2, err)
+	}
+}
+
+func TestProtoCorrupt(t *testing.T) {
+	data := protoProblem(t).ToProto()
+	// A prefix ending between two fields is a valid message, any other one is truncated
+	for i := range data {
+		if _, err := FromProto(data[:i]); err != nil && !errors.Is(err, ErrBadInput) {
+			t.Errorf("prefix of %d bytes: expected an error wrapping ErrBadInput, got %v", i, err)
+		}
+	}
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"truncated varint", []byte{0x08, 0x80}},
+		{"overlong varint", []byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"field 0", []byte{0x00, 0x01}},
+		{"truncated clause", []byte{0x08, 0x04, 0x22, 0x04, 0x0a, 0x02}},
+		{"truncated weight", []byte{0x08, 0x04, 0x3a, 0x0b, 0x08, 0x02, 0x11, 0x00, 0x00}},
+		{"null lit", []byte{0x08, 0x04, 0x1a, 0x01, 0x00}},
+		{"lit out of the vars", []byte{0x08, 0x04, 0x1a, 0x01, 0x0a}},
+		{"unknown status", []byte{0x08, 0x04, 0x10, 0x03}},
+		{"unsupported wire type", []byte{0x08, 0x04, 0x4b}},
+		{"wire type of a lit", []byte{0x08, 0x04, 0x1d, 0x00, 0x00, 0x00, 0x00}},
+		{"weights without lits", []byte{0x08, 0x04, 0x32, 0x01, 0x05}},
+		{"empty eliminated clause", []byte{0x08, 0x04, 0x42, 0x00}},
+	} {
+		if _, err := FromProto(c.data); !errors.Is(err, ErrBadInput) {
+			t.Errorf("%s: expected an error wrapping ErrBadInput, got %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PROVENANCE
+// Every clause has an ID. Input clauses are numbered from 1, in the order they were added or parsed.
+// Each time a clause is derived (resolvent, strengthened version of a clause, new unit), it gets a new ID.
+// When pb.Options.Provenance is set, each derivation and each deletion is recorded with the IDs of its premises,
+// so that any clause of the simplified problem can be traced back to the input clauses it comes from.
+// Clauses inferred through propagation (probing, vivification) are given as premises the clause they modify,
+// and every clause the propagation went through.
+// The first empty clause met is remembered, so that UnsatExplanation can tell where a refutation comes from.
+// Likewise, ExplainUnit tells which clauses a unit was inferred from.
+// The same hooks are used to write LRAT proofs, see Proof.go.
+
+// A Step is a recorded derivation or deletion of a clause.
+type Step struct {
+	ID        int    // ID of the derived or deleted clause.
+	Deleted   bool   // If true, the clause was deleted, otherwise it was derived.
+	Technique string // Name of the technique that performed the step.
+	Premises  []int  // Clauses the derived clause was inferred from, or clauses that made the deleted clause redundant.
+	Lits      []Lit  // Lits of the derived clause, or of the deleted clause if they are known.
+}
+
+// Provenance is the history of the clauses of a problem.
+type Provenance struct {
+	steps       []Step
+	derivations map[int]int // Index of the derivation step of each derived clause.
+	deletions   map[int]int // Index of the deletion step of each deleted clause.
+}
+
+// Steps returns every recorded step, in chronological order.
+func (p *Provenance) Steps() []Step {
+	return p.steps
+}
+
+// Derivation returns the step that derived the clause with the given ID.
+// ok is false if the clause is an input clause or if its derivation was not recorded.
+func (p *Provenance) Derivation(id int) (step Step, ok bool) {
+	idx, ok := p.derivations[id]
+	if !ok {
+		return Step{}, false
+	}
+	return p.steps[idx], true
+}
+
+// Deletion returns the step that deleted the clause with the given ID, if any.
+func (p *Provenance) Deletion(id int) (step Step, ok bool) {
+	idx, ok := p.deletions[id]
+	if !ok {
+		return Step{}, false
+	}
+	return p.steps[idx], true
+}
+
+// Origins returns the sorted IDs of the input clauses the clause with the given ID was derived from.
+// The origin of an input clause is itself.
+func (p *Provenance) Origins(id int) []int {
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+	var res []int
+	for len(queue) > 0 {
+		id, queue = queue[0], queue[1:]
+		step, ok := p.Derivation(id)
+		if !ok {
+			res = append(res, id)
+			continue
+		}
+		for _, premise := range step.Premises {
+			if !seen[premise] {
+				seen[premise] = true
+				queue = append(queue, premise)
+			}
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// Chain returns the IDs of the clauses the clause with the given ID was derived from, directly or not, the clause itself
+// included, each of them after the clauses it was derived from.
+func (p *Provenance) Chain(id int) []int {
+	seen := make(map[int]bool)
+	var res []int
+	var visit func(id int)
+	visit = func(id int) {
+		seen[id] = true
+		if step, ok := p.Derivation(id); ok {
+			for _, premise := range step.Premises {
+				if !seen[premise] {
+					visit(premise)
+				}
+			}
+		}
+		res = append(res, id)
+	}
+	visit(id)
+	return res
+}
+
+// clone returns a deep copy of the provenance.
+func (p *Provenance) clone() *Provenance {
+	p2 := &Provenance{
+		steps:       append([]Step(nil), p.steps...),
+		derivations: make(map[int]int, len(p.derivations)),
+		deletions:   make(map[int]int, len(p.deletions)),
+	}
+	for id, idx := range p.derivations {
+		p2.derivations[id] = idx
+	}
+	for id, idx := range p.deletions {
+		p2.deletions[id] = idx
+	}
+	return p2
+}
+
+// Provenance returns the history of the clauses of the problem.
+// It is empty unless pb.Options.Provenance was set, e.g through ParseCNFWithOptions.
+// Steps performed before it was set are not recorded.
+func (pb *Problem) Provenance() *Provenance {
+	if pb.provenance == nil {
+		return &Provenance{}
+	}
+	return pb.provenance
+}
+
+// ID returns the ID of the clause.
+func (c *Clause) ID() int {
+	return c.id
+}
+
+// UnitID returns the ID of the unit clause that bound v, or 0 if v is unbound or its clause is unknown.
+func (pb *Problem) UnitID(v Var) int {
+	if int(v) >= len(pb.unitIDs) {
+		return 0
+	}
+	return pb.unitIDs[v]
+}
+
+// nextID returns a new clause ID.
+func (pb *Problem) nextID() int {
+	pb.lastID++
+	return pb.lastID
+}
+
+// record records the given step if provenance is tracked.
+func (pb *Problem) record(step Step) {
+	if !pb.Options.Provenance {
+		return
+	}
+	if pb.provenance == nil {
+		pb.provenance = &Provenance{derivations: make(map[int]int), deletions: make(map[int]int)}
+	}
+	premises := make([]int, 0, len(step.Premises))
+	seen := make(map[int]bool, len(step.Premises))
+	for _, id := range step.Premises {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			premises = append(premises, id)
+		}
+	}
+	step.Premises = premises
+	if step.Deleted {
+		pb.provenance.deletions[step.ID] = len(pb.provenance.steps)
+	} else {
+		pb.provenance.derivations[step.ID] = len(pb.provenance.steps)
+	}
+	pb.provenance.steps = append(pb.provenance.steps, step)
+}
+
+// derived gives c a new ID and records that it was inferred by technique from the given premises.
+// The premises must be given in an order that makes them valid LRAT hints.
+func (pb *Problem) derived(c *Clause, technique string, premises ...int) {
+	pb.derive(c, technique, premises, premises)
+	pb.writeDelta('a', c.id, technique, c.lits, premises, 0)
+}
+
+// derivedRUP is like derived, for clauses inferred through propagation: their LRAT hints are computed
+// by propagating the negation of c. old are clauses that were used by the propagation and are still in the proof,
+// but are no longer in pb.Clauses, e.g the version of c before it was strengthened.
+func (pb *Problem) derivedRUP(c *Clause, technique string, old []*Clause, premises ...int) {
+	var hints []int
+	recorded := premises
+	if pb.proof() != nil || pb.Options.Provenance {
+		hints = pb.rupHints(c, old...)
+		recorded = append(append([]int(nil), premises...), hints...)
+	}
+	pb.derive(c, technique, recorded, hints)
+	pb.writeDelta('a', c.id, technique, c.lits, premises, 0)
+}
+
+// derive gives c a new ID, records its derivation and writes it to the proof.
+func (pb *Problem) derive(c *Clause, technique string, premises, hints []int) {
+	c.id = pb.nextID()
+	pb.nbSteps++
+	if c.Len() == 0 {
+		pb.foundEmpty(c.id)
+	}
+	pb.record(Step{ID: c.id, Technique: technique, Premises: premises, Lits: append([]Lit(nil), c.lits...)})
+	if p := pb.proof(); p != nil {
+		p.add(c.id, c.lits, hints)
+	}
+}
+
+// deleted records that the clause c was removed by technique, since the given clauses made it redundant.
+func (pb *Problem) deleted(c *Clause, technique string, reasons ...int) {
+	pb.deletion(c.id, c.lits, technique, reasons)
+	pb.writeDelta('d', c.id, technique, c.lits, reasons, 0)
+}
+
+// deletedID is like deleted, for a clause that is only known through its ID and lits, e.g the former version
+// of a clause modified in place. Its lits are not written to the delta log.
+func (pb *Problem) deletedID(id int, lits []Lit, technique string, reasons ...int) {
+	pb.deletion(id, lits, technique, reasons)
+	pb.writeDelta('d', id, technique, nil, reasons, 0)
+}
+
+// deletion records the deletion of the clause with the given ID and lits, nil if unknown, and writes it to the proof.
+func (pb *Problem) deletion(id int, lits []Lit, technique string, reasons []int) {
+	pb.nbSteps++
+	if pb.Options.Provenance && lits != nil {
+		lits = append([]Lit(nil), lits...)
+	}
+	pb.record(Step{ID: id, Deleted: true, Technique: technique, Premises: reasons, Lits: lits})
+	if p := pb.proof(); p != nil {
+		p.delete(pb.lastID, id)
+	}
+}
+
+// replaced records that c, which used to have the given ID and lits, was modified in place by technique,
+// and must be derived again. premises are given as in derived, and must include oldID.
+func (pb *Problem) replaced(c *Clause, oldID int, oldLits []Lit, technique string, premises ...int) {
+	pb.derive(c, technique, premises, premises)
+	pb.deletion(oldID, oldLits, technique, []int{c.id})
+	pb.writeDelta('s', c.id, technique, c.lits, premises, oldID)
+}
+
+// foundEmpty records that the clause with the given ID is empty, unless an empty clause was already met.
+func (pb *Problem) foundEmpty(id int) {
+	if pb.emptyID == 0 {
+		pb.emptyID = id
+	}
+}
+
+// UnsatExplanation returns the sorted IDs of the input clauses, units included, the problem was proven Unsat from.
+// Other input clauses can be removed from the problem, and it stays Unsat.
+// It returns nil if the problem is not Unsat, or if pb.Options.Provenance was not set when it was proven so.
+func (pb *Problem) UnsatExplanation() []int {
+	if pb.Status != Unsat || pb.emptyID == 0 || pb.provenance == nil {
+		return nil
+	}
+	return pb.provenance.Origins(pb.emptyID)
+}
+
+// ExplainUnit returns why lit was fixed: the IDs of the clauses the unit clause of lit was derived from, input clauses
+// included, each of them after the clauses it was derived from, see Provenance.Chain, and that unit clause last.
+// The steps deriving each of them are given by pb.Provenance().Derivation. An error is returned if lit is not a unit
+// of the problem, and ErrNoProvenance if pb.Options.Provenance is not set. If it was set after lit was fixed,
+// the unit clause is given alone.
+func (pb *Problem) ExplainUnit(lit Lit) ([]int, error) {
+	if !pb.Options.Provenance {
+		return nil, ErrNoProvenance
+	}
+	if int(lit.Var()) >= pb.NbVars || pb.Model[lit.Var()] == 0 || (pb.Model[lit.Var()] == 1) != lit.IsPositive() {
+		return nil, fmt.Errorf("%d is not a unit of the problem", lit.Int())
+	}
+	id := pb.UnitID(lit.Var())
+	if id == 0 {
+		return nil, fmt.Errorf("clause fixing %d is unknown", lit.Int())
+	}
+	return pb.Provenance().Chain(id), nil
+}
+
+// setUnitID records that the unit lit comes from the clause with the given ID.
+// If lit contradicts a unit, the empty clause is derived from both of them.
+func (pb *Problem) setUnitID(lit Lit, id int) {
+	if pb.unitIDs == nil {
+		pb.unitIDs = make([]int, pb.NbVars)
+	}
+	if val := pb.Model[lit.Var()]; val != 0 && (val == 1) != lit.IsPositive() {
+		pb.derived(NewClause([]Lit{}), "conflict", pb.UnitID(lit.Var()), id)
+		return
+	}
+	if unitIDs, v := pb.unitIDs, lit.Var(); pb.nbMarks > 0 {
+		old := unitIDs[v]
+		pb.logUndo(func() { unitIDs[v] = old })
+	}
+	pb.unitIDs[lit.Var()] = id
+}
+
+// derivedUnit records that the unit lit was inferred by technique from the given premises, and returns its ID.
+func (pb *Problem) derivedUnit(lit Lit, technique string, premises ...int) int {
+	c := NewClause([]Lit{lit})
+	pb.derived(c, technique, premises...)
+	pb.setUnitID(lit, c.id)
+	return c.id
+}
+
+// derivedUnitRUP is like derivedUnit, for units inferred through propagation.
+func (pb *Problem) derivedUnitRUP(lit Lit, technique string, premises ...int) int {
+	c := NewClause([]Lit{lit})
+	pb.derivedRUP(c, technique, nil, premises...)
+	pb.setUnitID(lit, c.id)
+	return c.id
+}
+
+// unitReasons returns the IDs of the units that falsify the given lits.
+func (pb *Problem) unitReasons(lits []Lit) []int {
+	if !pb.tracking() {
+		return nil
+	}
+	res := make([]int, 0, len(lits))
+	for _, lit := range lits {
+		res = append(res, pb.UnitID(lit.Var()))
+	}
+	return res
+}
@@ -0,0 +1,133 @@
+package Preprocessor
+
+import "unsafe"
+
+// CLAUSE REFERENCES
+// Indices in pb.Clauses change as soon as a clause is removed, so a pass collecting the indices of the clauses
+// to remove breaks if anything else removes or reorders clauses in between.
+// Passes refer to clauses through ClauseRefs instead: stable handles, independent of the position of the clause.
+// Passes never remove clauses from pb.Clauses directly: clauses are marked as removed, in any order,
+// then swept from pb.Clauses at once, which also invalidates their refs.
+
+// A ClauseRef is a stable handle to a clause of a problem. The zero ClauseRef refers to no clause.
+type ClauseRef int32
+
+// Ref returns the handle of c, a clause of the problem. It is valid until c is swept from the problem.
+func (pb *Problem) Ref(c *Clause) ClauseRef {
+	if c.ref == 0 || int(c.ref) >= len(pb.refs) || pb.refs[c.ref] != c {
+		if len(pb.refs) == 0 {
+			pb.refs = append(pb.refs, nil) // The zero ref
+		}
+		c.ref = ClauseRef(len(pb.refs))
+		pb.refs = append(pb.refs, c)
+	}
+	return c.ref
+}
+
+// Clause returns the clause the given ref refers to, or nil if it was swept from the problem.
+// Clauses marked as removed but not swept yet are still returned.
+func (pb *Problem) Clause(ref ClauseRef) *Clause {
+	if ref <= 0 || int(ref) >= len(pb.refs) {
+		return nil
+	}
+	return pb.refs[ref]
+}
+
+// markRemoved marks c, a clause of the problem, as removed. It returns false if it already was.
+// c stays in pb.Clauses until sweep is called.
+func (pb *Problem) markRemoved(c *Clause) bool {
+	if c.removed {
+		return false
+	}
+	pb.logUndo(func() { c.removed = false })
+	c.removed = true
+	pb.nbMarked++
+	return true
+}
+
+// sweep removes the clauses marked as removed from pb.Clauses. Their refs become invalid.
+// Clauses satisfied by the units found since they were last removed are removed too, once there are enough
+// of these units, see collectSatisfied.
+func (pb *Problem) sweep() {
+	pb.collectSatisfied()
+	if pb.nbMarked == 0 {
+		return
+	}
+	if clauses := pb.Clauses; pb.nbMarks > 0 { // Clauses are moved in place
+		old := append([]*Clause(nil), clauses...)
+		pb.memory().charge(len(old) * int(unsafe.Sizeof(&Clause{})))
+		pb.logUndo(func() { copy(clauses, old) })
+	}
+	nbClauses := 0
+	for _, c := range pb.Clauses {
+		if c.removed {
+			if pb.Clause(c.ref) == c {
+				pb.refs[c.ref] = nil
+			}
+			c.indexed = false
+			continue
+		}
+		pb.Clauses[nbClauses] = c
+		nbClauses++
+	}
+	pb.Clauses = pb.Clauses[:nbClauses]
+	pb.nbMarked = 0
+}
+
+// GARBAGE COLLECTION OF SATISFIED CLAUSES
+// Only Simplify2 and ApplyUnits remove the clauses satisfied by units, but passes such as Probe, Unhide
+// or the elimination of vars may find many units before they are run, and the satisfied clauses then fill
+// the occurrence lists and are still scanned by each pass. Rather than propagating units, sweep removes them
+// once Options.CollectUnits units were found since they were last removed: each satisfied clause is removed,
+// but false lits are left for Simplify2 to remove.
+
+// defaultCollectUnits is the number of new units triggering the removal of satisfied clauses, if Options.CollectUnits is 0.
+const defaultCollectUnits = 64
+
+// collectSatisfied marks as removed the clauses satisfied by the units found since pb.nbCollected,
+// if there are enough of them, see Options.CollectUnits.
+func (pb *Problem) collectSatisfied() {
+	if pb.nbCollected > len(pb.Units) { // Units were rolled back
+		pb.nbCollected = len(pb.Units)
+	}
+	threshold := pb.Options.CollectUnits
+	if threshold == 0 {
+		threshold = defaultCollectUnits
+	}
+	if threshold < 0 || len(pb.Units)-pb.nbCollected < threshold || pb.Status == Unsat {
+		return
+	}
+	units := pb.Units[pb.nbCollected:]
+	pb.nbCollected = len(pb.Units)
+	remove := func(c *Clause, lit Lit) {
+		if !c.removed && !c.protected {
+			pb.deleted(c, "collect", pb.UnitID(lit.Var()))
+			pb.markRemoved(c)
+		}
+	}
+	if pb.idx != nil { // Only the occurrences of the new units are scanned
+		for _, lit := range units {
+			if int(lit) < len(pb.idx.refs) {
+				for _, ref := range pb.idx.live(lit) {
+					remove(pb.Clause(ref), lit)
+				}
+			}
+		}
+	}
+	// Clauses that are not indexed yet, or all of them if there is no index, are scanned
+	isNew := make(map[Lit]bool, len(units))
+	for _, lit := range units {
+		isNew[lit] = true
+	}
+	for _, c := range pb.Clauses {
+		if c.removed || (pb.idx != nil && c.indexed) {
+			continue
+		}
+		for _, lit := range c.lits {
+			if isNew[lit] {
+				remove(c, lit)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"log"
+)
+
+// ROLLBACK
+// Some passes can make the formula grow, e.g Probe when it adds binary clauses. With pb.Options.MaxGrowth set,
+// each pass run by Preprocess or Fixpoint is a transaction: a mark is set before the pass, and the problem is
+// rolled back to it if the pass made its clauses grow too much.
+// While a mark is set, edits are recorded in an undo log shared by all passes: each entry undoes an edit that is
+// not an append, e.g the strengthening of a clause, the removal of clauses by sweep or a write to the model.
+// The mark keeps a shallow copy of the problem, i.e its scalars and the length of its slices, so that appends,
+// e.g added clauses and new units, are undone by restoring it. Rolling back replays the log backwards, then restores
+// the copy. Entries write to the arrays and clauses they were recorded for, so that slices reallocated by appends
+// since the mark are left alone. The log costs as much memory as the clauses and values edited since the first mark,
+// and is dropped once no mark is set anymore.
+// Proofs and delta logs cannot be rolled back, so passes are never rolled back when they are written,
+// nor when the provenance of clauses is tracked: MaxGrowth is then ignored, and no log is kept.
+// Users get the same mechanism through Savepoint and Rollback, e.g to undo speculative simplifications
+// made under assumptions by a look-ahead solver.
+
+// A SavepointID identifies a state of a problem saved by Savepoint.
+type SavepointID int
+
+// A savepoint is a state of a problem saved by Savepoint.
+type savepoint struct {
+	id   SavepointID
+	mark mark
+}
+
+// A mark is a state of a problem that rollback can restore.
+type mark struct {
+	nbEdits int     // Length of the undo log when the mark was set.
+	saved   Problem // Shallow copy of the problem when the mark was set.
+}
+
+// Savepoint saves the current state of the problem, and returns an ID that Rollback can restore it from.
+// The edits made to the problem are recorded as long as a savepoint is set, i.e until it is rolled back to,
+// or a former savepoint is.
+func (pb *Problem) Savepoint() SavepointID {
+	pb.lastSavepoint++
+	pb.savepoints = append(pb.savepoints, savepoint{id: pb.lastSavepoint, mark: pb.mark()})
+	return pb.lastSavepoint
+}
+
+// Rollback restores the problem as it was when Savepoint returned id, and drops that savepoint and the later ones.
+// Options are kept, see rollback. An error wrapping ErrNoSavepoint is returned if id is not a savepoint of the
+// problem anymore, and ErrIrreversible if the history of the problem is tracked, see Options.Provenance, Options.LRAT
+// and Options.DeltaWriter: the problem is then left as is.
+func (pb *Problem) Rollback(id SavepointID) error {
+	if pb.tracking() {
+		return ErrIrreversible
+	}
+	for i, sp := range pb.savepoints {
+		if sp.id == id {
+			pb.nbMarks -= len(pb.savepoints) - i - 1
+			pb.savepoints = pb.savepoints[:i]
+			pb.rollback(sp.mark, pb.nbSteps+1)
+			pb.clean = nil // The problem may already have been in the state passes were clean in
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d", ErrNoSavepoint, id)
+}
+
+// mark sets a mark, from which rollback can restore the problem. It must be released, or rolled back to.
+func (pb *Problem) mark() mark {
+	pb.nbMarks++
+	return mark{nbEdits: len(pb.undo), saved: *pb}
+}
+
+// release drops the latest mark. The undo log is dropped too once no mark is left.
+func (pb *Problem) release() {
+	pb.nbMarks--
+	if pb.nbMarks == 0 {
+		pb.undo = nil
+	}
+}
+
+// logUndo records f, which undoes an edit that is about to be made, if a mark is set.
+func (pb *Problem) logUndo(f func()) {
+	if pb.nbMarks > 0 {
+		pb.undo = append(pb.undo, f)
+	}
+}
+
+// saveClause records the current state of c, a clause of the problem that is about to be modified in place,
+// if a mark is set.
+func (pb *Problem) saveClause(c *Clause) {
+	if pb.nbMarks == 0 {
+		return
+	}
+	old := *c
+	old.lits = append([]Lit(nil), c.lits...)
+	pb.memory().charge(clauseSize(c.Len()))
+	pb.logUndo(func() { *c = old })
+}
+
+// setValue binds v to val in the model, which can then be rolled back.
+func (pb *Problem) setValue(v Var, val decLevel) {
+	if model := pb.Model; pb.nbMarks > 0 {
+		old := model[v]
+		pb.logUndo(func() { model[v] = old })
+	}
+	pb.Model[v] = val
+}
+
+// rollback restores the problem as it was when m was set, drops m, and sets its number of steps.
+// Its options, savepoints and memory mapping are kept, IDs given since then are not reused, and refs to its clauses
+// do not refer to a clause anymore.
+func (pb *Problem) rollback(m mark, nbSteps int) {
+	for i := len(pb.undo) - 1; i >= m.nbEdits; i-- {
+		pb.undo[i]()
+		pb.undo[i] = nil
+	}
+	opts, lastID, refs, clean, rng, mem := pb.Options, pb.lastID, pb.refs, pb.clean, pb.rng, pb.mem
+	savepoints, lastSavepoint, mapping, undo, nbMarks := pb.savepoints, pb.lastSavepoint, pb.mapping, pb.undo, pb.nbMarks
+	*pb = m.saved
+	pb.Options, pb.lastID, pb.clean, pb.rng, pb.mem = opts, lastID, clean, rng, mem
+	pb.savepoints, pb.lastSavepoint, pb.mapping = savepoints, lastSavepoint, mapping
+	pb.undo, pb.nbMarks = undo[:m.nbEdits], nbMarks
+	pb.release()
+	// The index and the propagator were updated in place since the mark
+	pb.idx, pb.prop = nil, nil
+	for i := range refs {
+		refs[i] = nil
+	}
+	pb.refs = refs
+	for _, c := range pb.Clauses {
+		c.indexed, c.ref = false, 0
+	}
+	pb.nbSteps = nbSteps
+}
+
+// growthExceeded is true iff the clauses of the problem have more than pb.Options.MaxGrowth percent more lits
+// than nbLits.
+func (pb *Problem) growthExceeded(nbLits int) bool {
+	_, nbLits2, _ := pb.size()
+	return float64(nbLits2) > float64(nbLits)*(1+pb.Options.MaxGrowth/100)
+}
+
+// runTransaction runs the given pass, and undoes it if it made the problem grow too much, see Options.MaxGrowth.
+// It returns whether the pass was rolled back. The edits of the pass are logged, unless MaxGrowth is unset or ignored.
+func (pb *Problem) runTransaction(pass Pass) (rolledBack bool) {
+	if pb.Options.MaxGrowth <= 0 || pb.tracking() {
+		pass.Run(pb)
+		return false
+	}
+	nbSteps := pb.nbSteps
+	_, nbLits, _ := pb.size()
+	m := pb.mark()
+	pass.Run(pb)
+	if pb.nbSteps == nbSteps || !pb.growthExceeded(nbLits) {
+		pb.release()
+		return false
+	}
+	_, nbLits2, _ := pb.size()
+	log.Printf("Pass %s made the clauses grow from %d to %d lits, rolled back", pass.Name, nbLits, nbLits2)
+	pb.rollback(m, nbSteps)
+	return true
+}
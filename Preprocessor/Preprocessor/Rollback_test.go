@@ -0,0 +1,107 @@
+package Preprocessor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// problemState describes the status, units, model, eliminated clauses and clauses of pb, in order.
+func problemState(pb *Problem) string {
+	s := fmt.Sprintf("status %d, units %v, model %v, eliminated %v, phases %v, flipped %v\n",
+		pb.Status, pb.Units, pb.Model, pb.eliminated, pb.phases, pb.flipped)
+	for _, c := range pb.Clauses {
+		s += fmt.Sprintf("%d: %s, removed %t\n", c.id, c.CNF(), c.removed)
+	}
+	return s
+}
+
+// growing runs pass, then adds a clause of all vars for each sign of the first one,
+// so that the pass is rolled back unless it solved the problem.
+func growing(pass Pass) Pass {
+	return Pass{pass.Name, func(pb *Problem) {
+		pass.Run(pb)
+		if pb.Status != Undetermined {
+			return
+		}
+		for _, sign := range []Lit{0, 1} {
+			lits := []Lit{Var(0).Lit() ^ sign}
+			for v := 1; v < pb.NbVars; v++ {
+				lits = append(lits, Var(v).Lit())
+			}
+			pb.AddClause(lits)
+		}
+	}}
+}
+
+func TestRunTransactionRollback(t *testing.T) {
+	passes := append(append([]Pass(nil), fuzzPasses...), Pass{"polarity", func(pb *Problem) { pb.CanonicalizePolarity() }})
+	nbRolledBack := 0
+	for _, cnf := range fuzzSeeds {
+		for _, pass := range passes {
+			pb := parse(t, cnf)
+			pb.Options.MaxGrowth = 10
+			before, nbSteps := problemState(pb), pb.nbSteps
+			if !pb.runTransaction(growing(pass)) {
+				continue
+			}
+			nbRolledBack++
+			if got := problemState(pb); got != before {
+				t.Errorf("%s was not rolled back on %q:\nexpected\n%s\ngot\n%s", pass.Name, cnf, before, got)
+			}
+			if pb.nbSteps != nbSteps || pb.nbMarks != 0 || pb.undo != nil {
+				t.Errorf("%s on %q: expected %d steps and no mark, got %d steps, %d marks and %d edits",
+					pass.Name, cnf, nbSteps, pb.nbSteps, pb.nbMarks, len(pb.undo))
+			}
+			if err := pb.CheckInvariants(); err != nil {
+				t.Errorf("%s on %q: invariant broken: %v", pass.Name, cnf, err)
+			}
+		}
+	}
+	if nbRolledBack < len(passes) {
+		t.Errorf("expected most passes to be rolled back, got %d", nbRolledBack)
+	}
+}
+
+// savedState describes the clauses, units, model and status of pb.
+func savedState(pb *Problem) string {
+	return fmt.Sprintf("%sunits %v, model %v, status %d", pb.CNF(), pb.Units, pb.Model, pb.Status)
+}
+
+func TestSavepointNested(t *testing.T) {
+	pb := parse(t, "p cnf 5 6\n-1 2 0\n-2 3 0\n-3 4 0\n-4 5 0\n-1 3 5 0\n1 -5 2 0\n")
+	original := savedState(pb)
+	first := pb.Savepoint()
+	pb.AddClause([]Lit{IntToLit(-5), IntToLit(4)})
+	pb.Simplify2()
+	pb.AddClause([]Lit{IntToLit(1)})
+	middleState := savedState(pb)
+	middle := pb.Savepoint()
+	pb.Fixpoint()
+	last := pb.Savepoint()
+	pb.AddClause([]Lit{IntToLit(3), IntToLit(4)})
+	if got := savedState(pb); got == middleState {
+		t.Fatalf("expected the problem to change after the middle savepoint, got\n%s", got)
+	}
+	if err := pb.Rollback(middle); err != nil {
+		t.Fatalf("could not roll back to the middle savepoint: %v", err)
+	}
+	if got := savedState(pb); got != middleState {
+		t.Errorf("after rollback to the middle savepoint: expected\n%s\ngot\n%s", middleState, got)
+	}
+	if err := pb.CheckInvariants(); err != nil {
+		t.Errorf("invariant broken after rollback to the middle savepoint: %v", err)
+	}
+	if err := pb.Rollback(last); !errors.Is(err, ErrNoSavepoint) {
+		t.Errorf("expected ErrNoSavepoint when rolling back to a dropped savepoint, got %v", err)
+	}
+	if err := pb.Rollback(first); err != nil {
+		t.Fatalf("could not roll back to the first savepoint: %v", err)
+	}
+	if got := savedState(pb); got != original {
+		t.Errorf("after rollback to the first savepoint: expected\n%s\ngot\n%s", original, got)
+	}
+	if pb.nbMarks != 0 || pb.undo != nil {
+		t.Errorf("expected no mark left, got %d marks and %d edits", pb.nbMarks, len(pb.undo))
+	}
+}
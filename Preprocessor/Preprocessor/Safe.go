@@ -0,0 +1,141 @@
+package Preprocessor
+
+import "sync"
+
+// CONCURRENT ACCESS
+// A Problem is not safe for concurrent use, and a Snapshot is a copy that does not follow later changes.
+// Servers where several goroutines query a problem while another one preprocesses it wrap it in a SafeProblem:
+// queries hold a read lock on the problem, modifications a write lock. Propagate and Implies reuse the propagation
+// engine cached in the problem, so they also take a mutex of their own, and only exclude each other.
+// Occurrences takes the write lock: the index is updated as it is read, and indexing a clause caches its signature
+// in the clause, which the other queries read.
+// In fine-grained mode, Preprocess and Fixpoint release the write lock between passes, so that queries and
+// added clauses do not wait for the whole run, but only for the current pass.
+
+// A SafeProblem is a problem that is safe for concurrent use by several goroutines.
+type SafeProblem struct {
+	mu          sync.RWMutex // Held for reading by queries, for writing by modifications.
+	propMu      sync.Mutex   // Held by the queries using the cached propagator, along with a read lock.
+	pb          *Problem
+	fineGrained bool
+}
+
+// NewSafeProblem returns a synchronized wrapper around pb, which must not be used directly anymore.
+// If fineGrained is set, Preprocess and Fixpoint release their lock between passes.
+func NewSafeProblem(pb *Problem, fineGrained bool) *SafeProblem {
+	return &SafeProblem{pb: pb, fineGrained: fineGrained}
+}
+
+// yield returns the function called between passes by preprocess and fixpoint, or nil if the lock is held
+// during the whole run. The write lock must be held.
+func (s *SafeProblem) yield() func() bool {
+	if !s.fineGrained {
+		return nil
+	}
+	return func() bool {
+		nbSteps := s.pb.nbSteps
+		s.mu.Unlock()
+		s.mu.Lock()
+		return s.pb.nbSteps != nbSteps
+	}
+}
+
+// Read calls f with the problem, which f must not modify, under a read lock.
+// f may propagate, but must not use the index, e.g through Problem.Occurrences: use Write instead.
+func (s *SafeProblem) Read(f func(pb *Problem)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.propMu.Lock() // f may use the cached propagator
+	defer s.propMu.Unlock()
+	f(s.pb)
+}
+
+// Write calls f with the problem, under a write lock.
+func (s *SafeProblem) Write(f func(pb *Problem)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.pb)
+}
+
+// Status returns the status of the problem.
+func (s *SafeProblem) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pb.Status
+}
+
+// Propagate runs unit propagation under the given assumptions, see Problem.Propagate.
+func (s *SafeProblem) Propagate(assumptions []Lit) (conflict bool, implied []Lit) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.propMu.Lock()
+	defer s.propMu.Unlock()
+	return s.pb.Propagate(assumptions)
+}
+
+// Implies returns whether propagating a implies b, and the clauses used, see Problem.Implies.
+func (s *SafeProblem) Implies(a, b Lit) (bool, []int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.propMu.Lock()
+	defer s.propMu.Unlock()
+	return s.pb.Implies(a, b)
+}
+
+// Occurrences returns the clauses containing l, see Problem.Occurrences.
+func (s *SafeProblem) Occurrences(l Lit) []ClauseRef {
+	s.mu.Lock() // The index, and the clauses, are updated as they are read, see above
+	defer s.mu.Unlock()
+	return s.pb.Occurrences(l)
+}
+
+// Profile returns the profile of the problem.
+func (s *SafeProblem) Profile() *Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pb.Profile()
+}
+
+// CNF returns a DIMACS CNF representation of the problem.
+func (s *SafeProblem) CNF() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pb.CNF()
+}
+
+// Fingerprint returns a hash of the clauses of the problem, see Problem.Fingerprint.
+func (s *SafeProblem) Fingerprint() [32]byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pb.Fingerprint()
+}
+
+// Snapshot returns an immutable view of the current state of the problem.
+func (s *SafeProblem) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pb.Snapshot()
+}
+
+// AddClause adds a clause to the problem, see Problem.AddClause.
+func (s *SafeProblem) AddClause(lits []Lit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pb.AddClause(lits)
+}
+
+// Preprocess preprocesses the problem, see Problem.Preprocess. In fine-grained mode, clauses added between two passes
+// are simplified by the remaining passes only, and the problem is not recorded as preprocessed.
+func (s *SafeProblem) Preprocess() Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pb.preprocess(s.yield())
+}
+
+// Fixpoint runs the given passes until a fixpoint is reached, see Problem.Fixpoint. In fine-grained mode,
+// a round during which the problem was modified between two passes is not a fixpoint.
+func (s *SafeProblem) Fixpoint(passes ...Pass) []RoundStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pb.fixpoint(passes, s.yield())
+}
@@ -0,0 +1,69 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// runConcurrently runs each function n times, in a goroutine of its own, and waits for them.
+func runConcurrently(n int, funcs ...func(i int)) {
+	var wg sync.WaitGroup
+	for _, f := range funcs {
+		f := f
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestSafeProblemConcurrentQueries runs the queries of a SafeProblem concurrently, first alone, then along with
+// modifications. It is meant to be run with -race.
+func TestSafeProblemConcurrentQueries(t *testing.T) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "p cnf 30 60\n")
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&sb, "%d %d -%d 0\n", i%30+1, (i*7)%30+1, (i*13+5)%30+1)
+	}
+	s := NewSafeProblem(parse(t, sb.String()), true)
+	lit := func(i int) Lit { return IntToLit(LitInt(i%30 + 1)) }
+	cnf := func(i int) { s.CNF() }
+	snapshot := func(i int) { s.Snapshot() }
+	occurrences := func(i int) { s.Occurrences(lit(i)) }
+	s.Occurrences(lit(0)) // Builds the index
+	for i := 0; i < 30; i++ {
+		s.AddClause([]Lit{lit(i), lit(i + 1), lit(i + 2).Negation()})
+	}
+	// The first call to Occurrences indexes the added clauses, while the clauses are copied and written.
+	runConcurrently(5, occurrences, snapshot, cnf)
+	queries := []func(i int){
+		cnf,
+		snapshot,
+		occurrences,
+		func(i int) { s.Fingerprint() },
+		func(i int) { s.Profile() },
+		func(i int) { s.Propagate([]Lit{lit(i)}) },
+		func(i int) { s.Implies(lit(i), lit(i+1)) },
+		func(i int) { s.Read(func(pb *Problem) { pb.CNF() }) },
+	}
+	modifications := []func(i int){
+		func(i int) { s.AddClause([]Lit{lit(i), lit(i + 3).Negation()}) },
+		func(i int) {
+			if i == 0 {
+				s.Preprocess()
+			}
+		},
+	}
+	runConcurrently(50, append(queries, modifications...)...)
+	s.Read(func(pb *Problem) {
+		if err := pb.CheckInvariants(); err != nil {
+			t.Errorf("invariant broken: %v", err)
+		}
+	})
+}
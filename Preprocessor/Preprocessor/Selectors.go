@@ -0,0 +1,81 @@
+package Preprocessor
+
+// SELECTOR VARS
+// Assumption-based MUS extractors and core-guided MaxSAT solvers add a fresh selector var s to each soft clause C,
+// giving (C | s), and then assume -s to enable C. The cores they get back are sets of selectors, so the selectors
+// must keep their meaning across preprocessing: they must be neither eliminated, which would resolve the clauses
+// containing them on the selectors, nor flipped. Clauses containing selectors may still be subsumed or strengthened
+// on their other lits, as if the selectors were ordinary lits: a core of the simplified problem is a core of the original one.
+// Selectors are declared in pb.Options.Selectors, and SelectorStats tells how much the clauses of each of them were simplified.
+
+// A SelectorStat describes the clauses containing a selector var, as they were when a pass was first run
+// by Preprocess or Fixpoint, and as they are now.
+type SelectorStat struct {
+	Selector       Var
+	InitialClauses int  // Clauses containing the selector, when the first pass was run.
+	InitialLits    int  // Lits of these clauses.
+	Clauses        int  // Clauses containing the selector now.
+	Lits           int  // Lits of these clauses.
+	Value          int8 // 1 or -1 if the selector was bound by a unit, 0 otherwise.
+}
+
+// selectors returns, for each var, whether it is a selector, see Options.Selectors. Vars that are not vars of the
+// problem are ignored.
+func (pb *Problem) selectors() []bool {
+	res := make([]bool, pb.NbVars)
+	for _, v := range pb.Options.Selectors {
+		if v >= 0 && int(v) < pb.NbVars {
+			res[v] = true
+		}
+	}
+	return res
+}
+
+// selectorCounts returns, for each selector, the number of clauses containing it, and the number of their lits.
+func (pb *Problem) selectorCounts() (clauses, lits map[Var]int) {
+	clauses, lits = make(map[Var]int), make(map[Var]int)
+	isSelector := pb.selectors()
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			if v := lit.Var(); isSelector[v] {
+				clauses[v]++
+				lits[v] += c.Len()
+			}
+		}
+	}
+	return clauses, lits
+}
+
+// recordSelectors records the initial counts of SelectorStats, unless they were already recorded.
+func (pb *Problem) recordSelectors() {
+	if len(pb.Options.Selectors) == 0 || pb.selectorClauses != nil {
+		return
+	}
+	pb.selectorClauses, pb.selectorLits = pb.selectorCounts()
+}
+
+// SelectorStats returns the statistics of each selector of pb.Options.Selectors, in the same order.
+// Selectors that are not vars of the problem are skipped.
+// If no pass was run by Preprocess or Fixpoint yet, the initial counts are the current ones.
+func (pb *Problem) SelectorStats() []SelectorStat {
+	clauses, lits := pb.selectorCounts()
+	initClauses, initLits := pb.selectorClauses, pb.selectorLits
+	if initClauses == nil {
+		initClauses, initLits = clauses, lits
+	}
+	var res []SelectorStat
+	for _, v := range pb.Options.Selectors {
+		if v < 0 || int(v) >= pb.NbVars {
+			continue
+		}
+		res = append(res, SelectorStat{
+			Selector:       v,
+			InitialClauses: initClauses[v],
+			InitialLits:    initLits[v],
+			Clauses:        clauses[v],
+			Lits:           lits[v],
+			Value:          int8(pb.Model[v]),
+		})
+	}
+	return res
+}
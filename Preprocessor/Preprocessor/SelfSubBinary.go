@@ -0,0 +1,73 @@
+package Preprocessor
+
+import "log"
+
+// BINARY SELF-SUBSUMPTION
+// Most of the clauses self-subsuming resolution strengthens are strengthened by binary clauses: a binary clause (a | b)
+// removes -a from every clause containing -a and b. Checking it is cheap, since a clause only has to be looked up
+// in the occurrences of -a and tested for b, without comparing whole clauses. SelfSubBinary only does that,
+// so that it fits in-processing budgets where SelfSub, which also eliminates vars, is too slow.
+// It neither removes subsumed clauses nor eliminates vars, and clauses it shortens to binary clauses are used in turn.
+
+// SelfSubBinary runs self-subsuming resolution with the binary clauses of the problem only.
+// Long clauses are strengthened too, see Options.MaxClauseLen.
+func (pb *Problem) SelfSubBinary() {
+	if pb.Status != Undetermined || pb.skipped("selfsubbinary") {
+		return
+	}
+	occurs := pb.index()
+	nbLits := 0
+	newUnits := false
+	var queue []*Clause // Binary clauses to strengthen other clauses with
+	for _, c := range pb.Clauses {
+		if !c.removed && c.Len() == 2 && !pb.isLong(c) {
+			queue = append(queue, c)
+		}
+	}
+	for len(queue) > 0 && pb.Status == Undetermined {
+		c := queue[0]
+		queue = queue[1:]
+		for j := 0; j < 2 && !c.removed; j++ {
+			a, b := c.lits[j], c.lits[1-j]
+			for _, ref := range occurs.live(a.Negation()) {
+				c2 := pb.Clause(ref)
+				if c2.removed || c2.protected || pb.crossesPartitions(c, c2) || !c2.contains(b) {
+					continue
+				}
+				oldID, oldLits := c2.id, c2.lits
+				pb.saveClause(c2)
+				c2.setLits(c2.strengthen(c))
+				c2.pbData = nil
+				c2.origin = Derived
+				pb.replaced(c2, oldID, oldLits, "selfsubbinary", oldID, c.id)
+				nbLits++
+				if c2.Len() == 1 {
+					pb.markRemoved(c2)
+					pb.setUnitID(c2.First(), c2.id)
+					pb.setPartition(c2.lits, c2.partition)
+					if pb.Model[c2.First().Var()] == 0 {
+						pb.addUnit(c2.First())
+						newUnits = true
+					} else if (pb.Model[c2.First().Var()] == 1) != c2.First().IsPositive() {
+						pb.Status = Unsat
+						break
+					}
+				} else if c2.Len() == 2 && !pb.isLong(c2) {
+					queue = append(queue, c2)
+				}
+			}
+			if pb.Status != Undetermined {
+				break
+			}
+		}
+	}
+	pb.sweep()
+	if pb.Status == Unsat {
+		log.Printf("Inferred UNSAT")
+		return
+	}
+	if newUnits {
+		pb.Simplify2()
+	}
+	log.Printf("Done. %d lits removed by binary clauses, %d clauses now", nbLits, len(pb.Clauses))
+}
@@ -0,0 +1,113 @@
+package Preprocessor
+
+import "log"
+
+// CLAUSE SHRINKING THROUGH THE BIG
+// If l1 and l2 are in a clause C and l1 implies l2 through the binary implication graph (BIG), C is satisfied by l2
+// whenever it is by l1: l1 can be removed from C. Unhide finds such lits in constant time, but only when the path
+// from l1 to l2 is in the tree of its DFS, so it misses many of them. ShrinkClausesViaBIG follows every path instead,
+// with a breadth-first search from each lit of each clause, at most pb.Options.ShrinkBudget edges per clause.
+// Lits are removed one at a time, and each of them must imply a lit that is still in the clause, so that only one
+// of two equivalent lits is removed. The shorter clause is derived through propagation, as in Unhide.
+
+// defaultShrinkBudget is the number of edges of the BIG visited per clause, if Options.ShrinkBudget is 0.
+const defaultShrinkBudget = 1000
+
+// ShrinkClausesViaBIG removes from each clause the lits implying another lit of the clause through the BIG.
+func (pb *Problem) ShrinkClausesViaBIG() {
+	if pb.Status != Undetermined || pb.Options.Interpolation || pb.skipped("shrinking") {
+		return
+	}
+	log.Printf("Shrinking clauses through the BIG... %d clauses currently", len(pb.Clauses))
+	budget := pb.Options.ShrinkBudget
+	if budget <= 0 {
+		budget = defaultShrinkBudget
+	}
+	big := pb.big()
+	inClause := make([]bool, pb.NbVars*2) // Lits of the current clause that were not removed
+	visited := make([]int, pb.NbVars*2)   // Number of the last search that visited each lit
+	search := 0
+	var queue []Lit
+	// implied returns whether lit implies a lit of the current clause, and spends the budget of the clause.
+	implied := func(lit Lit, steps *int) bool {
+		search++
+		visited[lit] = search
+		queue = append(queue[:0], lit)
+		for len(queue) > 0 && *steps < budget {
+			l := queue[0]
+			queue = queue[1:]
+			for _, l2 := range big[l] {
+				*steps++
+				if inClause[l2] {
+					return true
+				}
+				if visited[l2] != search {
+					visited[l2] = search
+					queue = append(queue, l2)
+				}
+			}
+		}
+		return false
+	}
+	nbLits := 0
+	newUnits := false
+	for _, c := range pb.Clauses {
+		if pb.Status != Undetermined {
+			break
+		}
+		if c.removed || c.protected {
+			continue
+		}
+		for _, lit := range c.lits {
+			inClause[lit] = true
+		}
+		nbRemoved, steps := 0, 0
+		for _, lit := range c.lits {
+			inClause[lit] = false
+			if steps < budget && implied(lit, &steps) {
+				nbRemoved++
+			} else {
+				inClause[lit] = true
+			}
+		}
+		var lits []Lit
+		for _, lit := range c.lits {
+			if inClause[lit] && nbRemoved > 0 {
+				lits = append(lits, lit)
+			}
+			inClause[lit] = false
+		}
+		if nbRemoved == 0 {
+			continue
+		}
+		nbLits += nbRemoved
+		if len(lits) == 1 {
+			pb.derivedUnitRUP(lits[0], "shrink", c.id)
+			pb.deleted(c, "shrink")
+			if pb.Model[lits[0].Var()] == 0 {
+				pb.addUnit(lits[0])
+			} else if (pb.Model[lits[0].Var()] == 1) != lits[0].IsPositive() {
+				pb.Status = Unsat
+			}
+			pb.markRemoved(c)
+			newUnits = true
+			continue
+		}
+		old := &Clause{lits: c.lits, id: c.id}
+		pb.saveClause(c)
+		c.setLits(lits)
+		c.pbData = nil
+		c.origin = Derived
+		pb.derivedRUP(c, "shrink", []*Clause{old}, old.id)
+		pb.deletedID(old.id, old.lits, "shrink", c.id)
+	}
+	pb.sweep()
+	if pb.Status == Unsat {
+		log.Printf("Inferred UNSAT")
+		return
+	}
+	if newUnits {
+		pb.Simplify2()
+	}
+	log.Printf("Done. %d lits removed, %d clauses now", nbLits, len(pb.Clauses))
+}
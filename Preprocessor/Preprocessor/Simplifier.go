@@ -0,0 +1,300 @@
+package Preprocessor
+
+import "sort"
+
+// IN-PROCESSING
+// A Simplifier runs simplification techniques on any clause database implementing ClauseDB,
+// so that a CDCL solver can apply them to its own original+learnt clauses during search.
+
+// A ClauseDB is a clause database that can be simplified by a Simplifier.
+type ClauseDB interface {
+	// Forall calls f on every clause of the database. id is a handle to the clause, valid until the clause is removed.
+	// f must not modify lits nor the database.
+	Forall(f func(id int, lits []Lit))
+	// Add adds a new clause to the database.
+	Add(lits []Lit)
+	// Remove removes the clause with the given handle from the database.
+	Remove(id int)
+	// Propagate runs unit propagation on the database under the given assumptions.
+	// It returns whether a conflict was met, and if not, the list of lits that were implied (assumptions excluded).
+	Propagate(assumptions []Lit) (conflict bool, implied []Lit)
+}
+
+// A Simplifier applies subsumption, self-subsuming resolution and vivification to a ClauseDB.
+type Simplifier struct {
+	VivifyMaxLen int // Clauses longer than this are not vivified. 0 means no limit.
+}
+
+// NewSimplifier returns a Simplifier with default settings.
+func NewSimplifier() *Simplifier {
+	return &Simplifier{VivifyMaxLen: 20}
+}
+
+// dbClause is a copy of a clause of a ClauseDB.
+type dbClause struct {
+	id      int
+	c       *Clause
+	removed bool
+}
+
+// snapshot copies every clause of db, with its lits sorted, in increasing length order.
+func snapshot(db ClauseDB) []*dbClause {
+	res := make([]*dbClause, 0)
+	db.Forall(func(id int, lits []Lit) {
+		c := NewClause(append([]Lit(nil), lits...))
+		c.Sort()
+		res = append(res, &dbClause{id: id, c: c})
+	})
+	sort.SliceStable(res, func(i, j int) bool { return res[i].c.Len() < res[j].c.Len() })
+	return res
+}
+
+// Subsume removes every clause of db subsumed by another one, and strengthens clauses through self-subsuming resolution.
+// It returns the number of removed and strengthened clauses.
+func (s *Simplifier) Subsume(db ClauseDB) (removed, strengthened int) {
+	clauses := snapshot(db)
+	occurs := make(map[Lit][]*dbClause)
+	for _, dc := range clauses {
+		for _, lit := range dc.c.lits {
+			occurs[lit] = append(occurs[lit], dc)
+		}
+	}
+	for _, dc := range clauses {
+		if dc.removed {
+			continue
+		}
+		for _, lit := range dc.c.lits {
+			// Subsumed clauses contain all lits of dc, self-subsumed ones contain all lits but one, which is negated.
+			for _, candidates := range [][]*dbClause{occurs[lit], occurs[lit.Negation()]} {
+				for _, dc2 := range candidates {
+					if dc2 == dc || dc2.removed || dc2.c.Len() < dc.c.Len() {
+						continue
+					}
+					if dc.c.Subsumes(dc2.c) {
+						db.Remove(dc2.id)
+						dc2.removed = true
+						removed++
+					} else if dc.c.SelfSubsumes(dc2.c) {
+						db.Remove(dc2.id)
+						dc2.removed = true
+						db.Add(dc2.c.strengthen(dc.c))
+						strengthened++
+					}
+				}
+			}
+		}
+	}
+	return removed, strengthened
+}
+
+// strengthen returns the lits of c, minus the one whose negation appears in c2.
+func (c *Clause) strengthen(c2 *Clause) []Lit {
+	res := make([]Lit, 0, c.Len())
+	for _, lit := range c.lits {
+		if !c2.contains(lit.Negation()) {
+			res = append(res, lit)
+		}
+	}
+	return res
+}
+
+// contains is true iff lit appears in c, which must be in canonical form.
+func (c *Clause) contains(lit Lit) bool {
+	i := sort.Search(len(c.lits), func(i int) bool { return c.lits[i] >= lit })
+	return i < len(c.lits) && c.lits[i] == lit
+}
+
+// sameLits is true iff c is made of the given lits, that must not contain duplicates.
+func (c *Clause) sameLits(lits []Lit) bool {
+	if len(lits) != c.Len() {
+		return false
+	}
+	for _, lit := range lits {
+		if !c.contains(lit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Vivify shortens clauses of db through propagation: for a clause (a | b | c | d), if propagating -a implies c,
+// the clause can be shortened to (a | c); if it implies -b, b can be removed from the clause.
+// It returns the number of removed lits.
+func (s *Simplifier) Vivify(db ClauseDB) (nbRemoved int) {
+	for _, dc := range snapshot(db) {
+		if dc.c.Len() < 3 || (s.VivifyMaxLen > 0 && dc.c.Len() > s.VivifyMaxLen) {
+			continue
+		}
+		// The clause must not take part in the propagation
+		db.Remove(dc.id)
+		lits := vivifyLits(db, dc.c.lits)
+		nbRemoved += dc.c.Len() - len(lits)
+		db.Add(lits)
+	}
+	return nbRemoved
+}
+
+// vivifyLits returns the vivified version of the given clause lits. The clause itself must not be in db.
+func vivifyLits(db ClauseDB, lits []Lit) []Lit {
+	res := make([]Lit, 0, len(lits))
+	assumptions := make([]Lit, 0, len(lits))
+	values := make(map[Lit]bool)
+	for _, lit := range lits {
+		if values[lit] { // lit is implied by the negation of the previous lits
+			return append(res, lit)
+		}
+		if values[lit.Negation()] { // lit is implied false: useless
+			continue
+		}
+		res = append(res, lit)
+		assumptions = append(assumptions, lit.Negation())
+		conflict, implied := db.Propagate(assumptions)
+		if conflict { // the negation of the lits so far is inconsistent
+			return res
+		}
+		for _, lit2 := range assumptions {
+			values[lit2] = true
+		}
+		for _, lit2 := range implied {
+			values[lit2] = true
+		}
+	}
+	return res
+}
+
+// problemDB is a ClauseDB view of a Problem. Clause handles are indices in pb.Clauses, and in the propagator of the view;
+// removed clauses are only deleted from the problem when commit is called.
+// A clause added right after the removal of a clause it is a subset of is considered as replacing it,
+// and inherits its metadata; if it is the same clause, the removal is simply undone. Other added clauses are Derived.
+type problemDB struct {
+	pb            *Problem
+	technique     string // Name of the technique using the view, for provenance.
+	prop          *propagator
+	lastRemoved   *Clause
+	lastRemovedID int
+	newUnits      bool
+}
+
+// db returns a ClauseDB view of the problem. As long as the view is used, the problem must not be modified directly.
+func (pb *Problem) db(technique string) *problemDB {
+	return &problemDB{pb: pb, technique: technique, prop: pb.propagator()}
+}
+
+func (db *problemDB) Forall(f func(id int, lits []Lit)) {
+	for i, c := range db.pb.Clauses {
+		if !db.prop.removed[i] && !c.protected { // Protected clauses are propagated, but never removed
+			f(i, c.lits)
+		}
+	}
+}
+
+func (db *problemDB) Add(lits []Lit) {
+	pb, old := db.pb, db.lastRemoved
+	db.lastRemoved = nil
+	if old != nil && old.sameLits(lits) {
+		db.prop.restore(db.lastRemovedID)
+		return
+	}
+	var premises []int
+	var olds []*Clause
+	if old != nil {
+		premises = []int{old.id}
+		olds = []*Clause{old}
+	}
+	c := NewClause(append([]Lit(nil), lits...))
+	if !c.Simplify() {
+		switch c.Len() {
+		case 0:
+			pb.derivedRUP(c, db.technique, olds, premises...)
+			pb.Status = Unsat
+		case 1:
+			if lit := c.First(); pb.Model[lit.Var()] == 0 || (pb.Model[lit.Var()] == 1) != lit.IsPositive() {
+				pb.derivedRUP(c, db.technique, olds, premises...)
+				pb.setUnitID(lit, c.id)
+				pb.addUnit(lit)
+			}
+			db.newUnits = true
+		default:
+			c.origin = Derived
+			if old != nil && c.Len() <= old.Len() {
+				c.activity, c.lbd = old.activity, old.lbd
+				if c.Len() == old.Len() {
+					c.origin = old.origin
+				}
+			}
+			pb.Clauses = append(pb.Clauses, c)
+			db.prop.add(c)
+			pb.derivedRUP(c, db.technique, olds, premises...)
+		}
+	}
+	// The replaced clause is only deleted now, since it may be needed to justify c.
+	if old != nil {
+		pb.deleted(old, db.technique)
+	}
+}
+
+func (db *problemDB) Remove(id int) {
+	db.flush()
+	db.prop.remove(id)
+	db.lastRemoved, db.lastRemovedID = db.pb.Clauses[id], id
+}
+
+// flush records the deletion of the last removed clause, if it was not replaced.
+func (db *problemDB) flush() {
+	if db.lastRemoved != nil {
+		db.pb.deleted(db.lastRemoved, db.technique)
+		db.lastRemoved = nil
+	}
+}
+
+// Propagate returns the lits implied by the assumptions, level 0 implications included, in propagation order.
+func (db *problemDB) Propagate(assumptions []Lit) (conflict bool, implied []Lit) {
+	p := db.prop
+	p.sync(db.pb)
+	defer p.backtrack()
+	for _, lit := range assumptions {
+		if !p.assume(lit) {
+			return true, nil
+		}
+	}
+	if p.propagate() != noClause {
+		return true, nil
+	}
+	for _, lit := range p.trail {
+		if p.reasons[lit.Var()] != noClause {
+			implied = append(implied, lit)
+		}
+	}
+	db.pb.recordPhases(implied)
+	return false, implied
+}
+
+// commit deletes removed clauses from the problem and propagates the units that were found.
+func (db *problemDB) commit() {
+	db.flush()
+	for i, c := range db.pb.Clauses {
+		if db.prop.removed[i] {
+			db.pb.markRemoved(c)
+		}
+	}
+	db.pb.sweep()
+	if db.newUnits && db.pb.Status != Unsat {
+		db.pb.Simplify2()
+	}
+	db.newUnits = false
+	db.prop = db.pb.propagator()
+}
+
+// Vivify runs the Simplifier's vivification on the problem.
+func (pb *Problem) Vivify() {
+	if pb.Status != Undetermined || pb.Options.Interpolation || pb.skipped("vivification") {
+		return
+	}
+	db := pb.db("vivify")
+	s := NewSimplifier()
+	if max := pb.Options.MaxClauseLen; max > 0 && max < s.VivifyMaxLen {
+		s.VivifyMaxLen = max
+	}
+	s.Vivify(db)
+	db.commit()
+}
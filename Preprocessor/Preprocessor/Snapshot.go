@@ -0,0 +1,134 @@
+package Preprocessor
+
+// COPIES OF PROBLEMS
+// Clone lets callers preprocess a copy of a problem while still using the original,
+// Snapshot gives an immutable view that can be shared between goroutines, e.g by portfolio solvers.
+
+// Clone returns a deep copy of the problem. Modifying the copy does not modify pb, and vice versa.
+func (pb *Problem) Clone() *Problem {
+	pb2 := &Problem{
+		NbVars:     pb.NbVars,
+		Clauses:    make([]*Clause, len(pb.Clauses)),
+		Status:     pb.Status,
+		Units:      append([]Lit(nil), pb.Units...),
+		Model:      append([]decLevel(nil), pb.Model...),
+		minLits:    append([]Lit(nil), pb.minLits...),
+		minWeights: append([]int(nil), pb.minWeights...),
+		Comments:   append([]string(nil), pb.Comments...),
+		Options:    pb.Options,
+		lastID:     pb.lastID,
+		emptyID:    pb.emptyID,
+		unitIDs:    append([]int(nil), pb.unitIDs...),
+		phases:     append([]int(nil), pb.phases...),
+		flipped:    append([]bool(nil), pb.flipped...),
+		groupOf:    append([]int(nil), pb.groupOf...),
+
+		unitPartitions: append([]Partition(nil), pb.unitPartitions...),
+		emptyPartition: pb.emptyPartition,
+		emptyUnits:     pb.emptyUnits,
+
+		selfSubCursor: pb.selfSubCursor,
+		nbCollected:   pb.nbCollected,
+
+		selectorClauses: pb.selectorClauses, // Never modified once recorded
+		selectorLits:    pb.selectorLits,
+	}
+	if pb.LitWeights != nil {
+		pb2.LitWeights = make(map[Lit]float64, len(pb.LitWeights))
+		for lit, w := range pb.LitWeights {
+			pb2.LitWeights[lit] = w
+		}
+	}
+	pb2.eliminated = append([]elimination(nil), pb.eliminated...) // Their lits are never modified
+	pb2.origVars = append([]Var(nil), pb.origVars...)
+	for p := range pb.partitionVars {
+		pb2.partitionVars[p] = append([]bool(nil), pb.partitionVars[p]...)
+	}
+	for _, card := range pb.cards {
+		card.Lits = append([]Lit(nil), card.Lits...)
+		pb2.cards = append(pb2.cards, card) // IDs of clauses are never modified
+	}
+	for _, amo := range pb.amos {
+		pb2.amos = append(pb2.amos, append([]Lit(nil), amo...))
+	}
+	// The copy must not write into the proof, the delta log nor the event stream of pb.
+	pb2.Options.LRAT = nil
+	pb2.Options.DeltaWriter = nil
+	pb2.Options.EventWriter = nil
+	if pb.provenance != nil {
+		pb2.provenance = pb.provenance.clone()
+	}
+	for i, c := range pb.Clauses {
+		pb2.Clauses[i] = c.clone()
+	}
+	for _, g := range pb.Gates {
+		g.In = append([]Lit(nil), g.In...)
+		pb2.Gates = append(pb2.Gates, g)
+	}
+	return pb2
+}
+
+// clone returns a deep copy of the clause.
+func (c *Clause) clone() *Clause {
+	c2 := &Clause{lits: append([]Lit(nil), c.lits...), activity: c.activity, lbd: c.lbd, origin: c.origin, id: c.id, abstraction: c.abstraction,
+		bits: c.bits, protected: c.protected, partition: c.partition}
+	if c.pbData != nil {
+		c2.pbData = &pbData{
+			weights: append([]int(nil), c.pbData.weights...),
+			watched: append([]bool(nil), c.pbData.watched...),
+		}
+	}
+	return c2
+}
+
+// A Snapshot is an immutable view of a problem, as it was when the snapshot was taken.
+// It is safe for concurrent use by several goroutines.
+type Snapshot struct {
+	pb *Problem
+}
+
+// Snapshot returns an immutable view of the current state of the problem.
+// Later modifications of pb are not visible in the snapshot.
+func (pb *Problem) Snapshot() *Snapshot {
+	return &Snapshot{pb: pb.Clone()}
+}
+
+// NbVars returns the number of vars of the problem.
+func (s *Snapshot) NbVars() int {
+	return s.pb.NbVars
+}
+
+// NbClauses returns the number of non-unit clauses of the problem.
+func (s *Snapshot) NbClauses() int {
+	return len(s.pb.Clauses)
+}
+
+// Clause returns a copy of the lits of the ith clause.
+func (s *Snapshot) Clause(i int) []Lit {
+	return append([]Lit(nil), s.pb.Clauses[i].lits...)
+}
+
+// Units returns a copy of the units of the problem.
+func (s *Snapshot) Units() []Lit {
+	return append([]Lit(nil), s.pb.Units...)
+}
+
+// Status returns the status of the problem.
+func (s *Snapshot) Status() Status {
+	return s.pb.Status
+}
+
+// Value returns the binding of v: 0 if unbound, 1 if bound to true, -1 if bound to false.
+func (s *Snapshot) Value(v Var) int {
+	return int(s.pb.Model[v])
+}
+
+// CNF returns a DIMACS CNF representation of the problem.
+func (s *Snapshot) CNF() string {
+	return s.pb.CNF()
+}
+
+// Problem returns a new, modifiable copy of the problem, e.g to hand it to a solver thread.
+func (s *Snapshot) Problem() *Problem {
+	return s.pb.Clone()
+}
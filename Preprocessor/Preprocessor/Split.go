@@ -0,0 +1,109 @@
+package Preprocessor
+
+import "sort"
+
+// CUBE AND CONQUER
+// A hard problem can be split into cubes, i.e sets of assumptions covering all assignments, that are then solved
+// in parallel: the problem is Sat iff one of its cubes is. Split picks a few splitting vars, and splits the cubes
+// breadth first, so that cubes have the same number of assumptions, give or take one.
+// Vars are ranked by their number of occurrences, or, as in look-ahead solvers, by how many lits both of their
+// lits imply: a var whose lits imply many others gives cubes that are much simpler than the problem.
+
+// A SplitHeuristic tells how Split chooses its splitting vars.
+type SplitHeuristic byte
+
+const (
+	// SplitByOccurrences chooses the vars appearing in the most clauses.
+	SplitByOccurrences = SplitHeuristic(iota)
+	// SplitByLookahead chooses, among the vars appearing in the most clauses, the ones whose lits imply the most lits.
+	SplitByLookahead
+)
+
+// lookaheadCandidates is the number of vars scored by propagation for each splitting var needed by SplitByLookahead.
+const lookaheadCandidates = 8
+
+// A Cube is a set of assumptions, with a copy of the problem simplified under them.
+type Cube struct {
+	Assumptions []Lit
+	Problem     *Problem // Copy of the problem, where the assumptions are units and were propagated.
+}
+
+// Split returns n cubes of the problem, or fewer if the problem does not have enough unbound vars.
+// The problem is not modified. Cubes found Unsat by propagation are kept, so that the cubes always cover
+// all the assignments of the problem: their Problem is then Unsat. If the problem is decided, or n <= 1,
+// a single cube without assumptions is returned.
+func (pb *Problem) Split(n int, heuristic SplitHeuristic) []Cube {
+	cubes := [][]Lit{nil}
+	if pb.Status == Undetermined {
+		vars := pb.splittingVars(n, heuristic)
+		for len(cubes) < n {
+			cube := cubes[0]
+			if len(cube) == len(vars) {
+				break
+			}
+			lit := vars[len(cube)].Lit()
+			cubes = append(cubes[1:],
+				append(append([]Lit(nil), cube...), lit),
+				append(append([]Lit(nil), cube...), lit.Negation()))
+		}
+	}
+	res := make([]Cube, len(cubes))
+	for i, cube := range cubes {
+		res[i] = Cube{Assumptions: cube, Problem: pb.Clone()}
+		for _, lit := range cube {
+			res[i].Problem.AddClause([]Lit{lit})
+		}
+		res[i].Problem.Simplify2()
+	}
+	return res
+}
+
+// splittingVars returns the vars the n cubes of Split are split on, best first.
+func (pb *Problem) splittingVars(n int, heuristic SplitHeuristic) []Var {
+	depth := 0
+	for 1<<uint(depth) < n {
+		depth++
+	}
+	nbOccs := make([]int, pb.NbVars)
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			nbOccs[lit.Var()]++
+		}
+	}
+	var vars []Var
+	for v, nb := range nbOccs {
+		if nb > 0 && pb.Model[v] == 0 {
+			vars = append(vars, Var(v))
+		}
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return nbOccs[vars[i]] > nbOccs[vars[j]] })
+	if heuristic == SplitByLookahead {
+		if len(vars) > depth*lookaheadCandidates {
+			vars = vars[:depth*lookaheadCandidates]
+		}
+		scores := make(map[Var]int, len(vars))
+		for _, v := range vars {
+			scores[v] = pb.lookaheadScore(v)
+		}
+		sort.SliceStable(vars, func(i, j int) bool { return scores[vars[i]] > scores[vars[j]] })
+	}
+	if len(vars) > depth {
+		vars = vars[:depth]
+	}
+	return vars
+}
+
+// lookaheadScore returns the product of the number of lits implied by each lit of v, plus one,
+// so that vars both of whose lits imply many lits are preferred. Failed lits count as implying every var.
+func (pb *Problem) lookaheadScore(v Var) int {
+	score := 1
+	for _, lit := range []Lit{v.Lit(), v.Lit().Negation()} {
+		conflict, implied := pb.Propagate([]Lit{lit})
+		if conflict {
+			score *= pb.NbVars + 1
+		} else {
+			score *= len(implied) + 1
+		}
+	}
+	return score
+}
@@ -0,0 +1,169 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"io"
+)
+
+// STREAMING PARSE
+// Huge formulas often shrink dramatically once their units are propagated. ParseCNFStreaming simplifies clauses
+// while they are read, so that satisfied clauses and false lits are never stored: each clause is deduplicated,
+// dropped if it is a tautology or is satisfied by a unit, and stripped of its false lits.
+// It is also dropped if it has the same lits as a clause already stored: stored clauses are kept in a hash set,
+// which costs a map entry per clause while parsing.
+// Units found later do not simplify the clauses already stored right away; they are propagated over them
+// once as many lits were stored since the last propagation as there were before it, so the overall cost stays linear.
+// The hash set is then built again, since propagation shortens and removes clauses.
+
+// ParseCNFStreaming is like ParseCNFWithOptions, but simplifies clauses while they are read.
+// If the problem is found to be Unsat, the rest of the input is not read.
+func ParseCNFStreaming(f io.Reader, opts Options) (*Problem, error) {
+	r := bufio.NewReader(f)
+	pb := &Problem{Options: opts, Clauses: make([]*Clause, 0)}
+	var (
+		lits          []Lit // Lits of the clause being read.
+		nbClauses     int   // Number of clauses announced by the header.
+		nbRead        int   // Number of clauses read so far.
+		nbLits        int   // Number of lits stored when units were last propagated.
+		nbNewLits     int   // Number of lits stored since then.
+		pendingUnits  bool  // Whether units were found since then.
+		headerWasRead bool
+		stored        = clauseSet{}
+	)
+	b, err := r.ReadByte()
+	for err == nil {
+		if b == 'c' { // Comment
+			var comment string
+			comment, err = readComment(r)
+			if pb.Options.KeepComments && !headerWasRead {
+				pb.Comments = append(pb.Comments, comment)
+			}
+		} else if b == 'p' { // Parse header
+			pb.NbVars, nbClauses, err = parseHeader(r)
+			if err != nil {
+				return nil, badInput("cannot parse CNF header: %v", err)
+			}
+			pb.Model = make([]decLevel, pb.NbVars)
+			// As in ParseCNF, the ith clause read has ID i: clauses derived while parsing get IDs after them.
+			pb.lastID = nbClauses
+			headerWasRead = true
+		} else {
+			lits = lits[:0]
+			for {
+				val, err := readInt(&b, r)
+				if err == io.EOF {
+					if len(lits) != 0 { // This is not a trailing space at the end...
+						return nil, badInput("unfinished clause while EOF found")
+					}
+					break // When there are only several useless spaces at the end of the file, that is ok
+				}
+				if err != nil {
+					return nil, badInput("cannot parse clause: %v", err)
+				}
+				if val == 0 {
+					if !headerWasRead {
+						return nil, badInput("clause found before the header")
+					}
+					nbRead++
+					id := nbRead
+					if nbRead > nbClauses {
+						if pb.proof() != nil {
+							return nil, badInput("more than the %d clauses announced by the header", nbClauses)
+						}
+						id = pb.nextID()
+					}
+					nbUnits := len(pb.Units)
+					nbNewLits += pb.addStreamed(lits, id, stored)
+					if pb.Status == Unsat {
+						return pb, nil
+					}
+					pendingUnits = pendingUnits || len(pb.Units) > nbUnits
+					if pendingUnits && nbNewLits >= nbLits {
+						pb.Simplify2()
+						if pb.Status == Unsat {
+							return pb, nil
+						}
+						pb.Status = Undetermined // Even if no clause is left, other clauses may follow
+						nbLits, nbNewLits, pendingUnits = 0, 0, false
+						stored = clauseSet{}
+						for _, c := range pb.Clauses {
+							nbLits += c.Len()
+							stored.add(c)
+						}
+					}
+					break
+				}
+				if val > pb.NbVars || -val > pb.NbVars {
+					return nil, badInput("invalid literal %d for problem with %d vars only", val, pb.NbVars)
+				}
+				lits = append(lits, IntToLit(LitInt(val)))
+			}
+		}
+		b, err = r.ReadByte()
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	pb.Simplify2()
+	return pb, nil
+}
+
+// addStreamed adds the clause made of the given lits to the problem, as AddClause does,
+// but drops it if it is satisfied by a unit or is a clause of stored, and removes its false lits.
+// The clause gets the given ID, and is added to stored. It returns the number of lits stored.
+func (pb *Problem) addStreamed(lits []Lit, id int, stored clauseSet) int {
+	c := NewClause(append([]Lit(nil), lits...))
+	c.id = id
+	if c.Simplify() {
+		pb.deleted(c, "tautology")
+		return 0
+	}
+	if pb.simplifyClause(c) {
+		return 0
+	}
+	switch c.Len() {
+	case 0:
+		pb.foundEmpty(c.id)
+		pb.Status = Unsat
+	case 1:
+		pb.setUnitID(c.First(), c.id)
+		pb.addUnit(c.First())
+	default:
+		if c2 := stored.find(c.lits); c2 != nil {
+			pb.deleted(c, "duplicate", c2.id)
+			return 0
+		}
+		pb.Clauses = append(pb.Clauses, c)
+		stored.add(c)
+		return c.Len()
+	}
+	return 0
+}
+
+// A clauseSet holds clauses in canonical form, by the hash of their lits.
+type clauseSet map[uint64][]*Clause
+
+// hashClause returns a hash of the given lits, that must be in canonical form.
+func hashClause(lits []Lit) uint64 {
+	h := uint64(14695981039346656037) // FNV-1a, over whole lits
+	for _, lit := range lits {
+		h = (h ^ uint64(lit)) * 1099511628211
+	}
+	return h
+}
+
+// add adds c, which must be in canonical form, to the set.
+func (s clauseSet) add(c *Clause) {
+	h := hashClause(c.lits)
+	s[h] = append(s[h], c)
+}
+
+// find returns the clause of the set made of the given lits, in canonical form, or nil if there is none.
+func (s clauseSet) find(lits []Lit) *Clause {
+	for _, c := range s[hashClause(lits)] {
+		if c.sameLits(lits) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package Preprocessor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamingDuplicates(t *testing.T) {
+	// 2 1 3 is 1 2 3 with its lits permuted, and -4 1 2 3 is 1 2 3 once -4 is found false.
+	// 1 2 -5 becomes 1 2 after -5 is propagated, so 1 2 read after that is a duplicate too.
+	cnf := "p cnf 5 7\n1 2 3 0\n2 1 3 0\n4 0\n-4 1 2 3 0\n1 2 -5 0\n5 0\n1 2 0\n"
+	pb, err := ParseCNFStreaming(strings.NewReader(cnf), Options{Provenance: true})
+	if err != nil {
+		t.Fatalf("could not parse problem: %v", err)
+	}
+	var got []string
+	for _, c := range pb.Clauses {
+		got = append(got, c.CNF())
+	}
+	if strings.Join(got, ",") != "1 2 3 0,1 2 0" {
+		t.Errorf("expected clauses 1 2 3 and 1 2, got %v", got)
+	}
+	nbDuplicates := 0
+	for _, step := range pb.Provenance().Steps() {
+		if step.Technique == "duplicate" {
+			if !step.Deleted || len(step.Premises) != 1 {
+				t.Errorf("invalid deletion of a duplicate: %+v", step)
+			}
+			nbDuplicates++
+		}
+	}
+	if nbDuplicates != 3 {
+		t.Errorf("expected 3 duplicates, got %d", nbDuplicates)
+	}
+}
@@ -0,0 +1,87 @@
+package Preprocessor
+
+// SUBFORMULA PREPROCESSING
+// Incremental applications often know that most of their clauses are stable, and only want to simplify the new ones.
+// PreprocessSubset only simplifies the selected clauses; the other ones are frozen: they are neither removed nor modified,
+// but they are still used to subsume and strengthen the selected clauses.
+// Units found on the way hold for the whole problem, but frozen clauses are not simplified by them.
+
+// PreprocessSubset runs DefaultPasses until fixpoint on the clauses whose ID is in clauseIDs, as tuned by opts.
+// IDs that are not the ID of a current clause of the problem are ignored.
+// The provenance, LRAT and interpolation settings of pb are kept, since they must be set at parse time.
+func (pb *Problem) PreprocessSubset(clauseIDs []int, opts Options) {
+	selected := make(map[int]bool, len(clauseIDs))
+	for _, id := range clauseIDs {
+		selected[id] = true
+	}
+	var subset, frozen []*Clause
+	for _, c := range pb.Clauses {
+		if selected[c.id] {
+			subset = append(subset, c)
+		} else {
+			frozen = append(frozen, c)
+		}
+	}
+	saved := pb.Options
+	opts.Provenance, opts.LRAT, opts.Interpolation = saved.Provenance, saved.LRAT, saved.Interpolation
+	pb.Options = opts
+	pb.Clauses = subset
+	passes := append(append([]Pass(nil), DefaultPasses...), Pass{
+		Name: "frozen",
+		Run:  func(pb *Problem) { pb.subsumeByFrozen(frozen) },
+	})
+	pb.Fixpoint(passes...)
+	// Removing every selected clause does not make the problem Sat
+	if pb.Status == Sat && len(frozen) > 0 {
+		pb.Status = Undetermined
+	}
+	pb.Clauses = append(frozen, pb.Clauses...)
+	pb.Options = saved
+}
+
+// subsumeByFrozen removes the clauses of the problem subsumed by a frozen clause, and strengthens the ones it self-subsumes.
+// Frozen clauses are not clauses of the problem, and are not modified.
+func (pb *Problem) subsumeByFrozen(frozen []*Clause) {
+	if pb.Status != Undetermined {
+		return
+	}
+	newUnits := false
+	for _, f := range frozen {
+		f = &Clause{lits: append([]Lit(nil), f.lits...), id: f.id, partition: f.partition}
+		f.Sort()
+		sig := f.signature()
+		for _, c := range pb.Clauses {
+			if c.removed || c.protected || c.Len() < f.Len() || sig&^c.signature() != 0 {
+				continue
+			}
+			if f.Subsumes(c) {
+				pb.markRemoved(c)
+				pb.deleted(c, "subsumption", f.id)
+			} else if !pb.crossesPartitions(f, c) && f.SelfSubsumes(c) {
+				oldID, oldLits := c.id, c.lits
+				pb.saveClause(c)
+				c.setLits(c.strengthen(f))
+				c.pbData = nil
+				c.origin = Derived
+				pb.replaced(c, oldID, oldLits, "selfsub", oldID, f.id)
+				if c.Len() == 1 {
+					pb.markRemoved(c)
+					pb.setUnitID(c.First(), c.id)
+					pb.setPartition(c.lits, c.partition)
+					if pb.Model[c.First().Var()] == 0 {
+						pb.addUnit(c.First())
+						newUnits = true
+					} else if (pb.Model[c.First().Var()] == 1) != c.First().IsPositive() {
+						pb.Status = Unsat
+						pb.sweep()
+						return
+					}
+				}
+			}
+		}
+	}
+	pb.sweep()
+	if newUnits {
+		pb.Simplify2()
+	}
+}
@@ -6,8 +6,8 @@ import (
 )
 
 type decLevel int
-type Lit int32
-type Var int32
+type Lit LitInt
+type Var LitInt
 const (
 	// Indet means the problem is not proven sat or unsat yet.
 	Undetermined = Status(iota)
@@ -26,10 +26,31 @@ type pbData struct {
 	watched []bool // indices of watched literals.
 }
 
+// Origin tells whether a clause comes from the input problem or was inferred.
+type Origin byte
+
+const (
+	// Original clauses were part of the input problem.
+	Original = Origin(iota)
+	// Derived clauses were inferred from other clauses, e.g resolvents or strengthened clauses.
+	Derived
+)
+
 // clause structure
 type Clause struct {
 	lits []Lit
 	pbData   *pbData
+	activity float64 // Activity of the clause, as maintained by a solver.
+	lbd      int     // Literal block distance of the clause, 0 if unknown.
+	origin   Origin
+	id       int // Stable ID of the clause, see Provenance.
+	ref      ClauseRef // Handle of the clause in its problem, 0 until asked for.
+	removed  bool      // Whether the clause is marked as removed, see Refs.go.
+	abstraction uint64 // Cached signature of the clause, 0 until computed.
+	bits     []uint64  // Bitset of the lits of the clause, nil unless the problem is dense, see Bitsets.go.
+	indexed  bool      // Whether the clause is in the index of its problem, see Index.go.
+	protected bool     // Whether passes must neither remove nor modify the clause, see ClauseOptions.
+	partition Partition // Partition of the clause in an interpolation problem, see Interpolation.go.
 }
 
 // First returns the first literal from the clause.
@@ -47,35 +68,84 @@ func (c *Clause) Len() int {
 	return len(c.lits)
 }
 
+// Protected returns whether passes must leave c as it is, see ClauseOptions.
+func (c *Clause) Protected() bool {
+	return c.protected
+}
+
 // sorts the literals in the clause
 func (c *Clause) Sort(){
-	sort.Slice(c.lits, func(i, j int) bool {
-		return c.lits[i] < c.lits[j]
-	})
+	if len(c.lits) > 16 {
+		sort.Slice(c.lits, func(i, j int) bool {
+			return c.lits[i] < c.lits[j]
+		})
+		return
+	}
+	// Insertion sort does not allocate, and is faster on short clauses
+	for i := 1; i < len(c.lits); i++ {
+		for j := i; j > 0 && c.lits[j] < c.lits[j-1]; j-- {
+			c.lits[j], c.lits[j-1] = c.lits[j-1], c.lits[j]
+		}
+	}
 }
 
 // Set sets the ith literal of the clause.
+// The clause may not be in canonical form anymore: Canonicalize must be called before it is given back to a problem.
 func (c *Clause) Set(i int, l Lit) {
 	c.lits[i] = l
+	c.abstraction = 0
+	c.bits = nil
 }
 
 // Shrink reduces the length of the clauses, by removing all lits
-// starting from position newLen.
+// starting from position newLen. A clause in canonical form stays so.
 func (c *Clause) Shrink(newLen int) {
 	c.lits = c.lits[:newLen]
+	c.abstraction = 0
+	c.bits = nil
 	if c.pbData != nil {
 		c.pbData.weights = c.pbData.weights[:newLen]
 		c.pbData.watched = c.pbData.watched[:newLen]
 	}
 }
 
+// Activity returns the activity of the clause.
+func (c *Clause) Activity() float64 {
+	return c.activity
+}
+
+// SetActivity sets the activity of the clause.
+func (c *Clause) SetActivity(activity float64) {
+	c.activity = activity
+}
+
+// Lbd returns the literal block distance of the clause, or 0 if it is unknown.
+func (c *Clause) Lbd() int {
+	return c.lbd
+}
+
+// SetLbd sets the literal block distance of the clause.
+func (c *Clause) SetLbd(lbd int) {
+	c.lbd = lbd
+}
+
+// Origin returns whether the clause is an original or a derived clause.
+func (c *Clause) Origin() Origin {
+	return c.origin
+}
+
+// SetOrigin sets the origin of the clause.
+func (c *Clause) SetOrigin(origin Origin) {
+	c.origin = origin
+}
+
 // NewClause returns a clause whose lits are given as an argument.
 func NewClause(lits []Lit) *Clause {
 	return &Clause{lits: lits}
 }
 
 // IntToLit converts a CNF literal to a Lit.
-func IntToLit(i int32) Lit {
+func IntToLit(i LitInt) Lit {
 	if i < 0 {
 		return Lit(2*(-i-1) + 1)
 	}
@@ -88,9 +158,9 @@ func (l Lit) IsPositive() bool {
 }
 
 // Int returns the equivalent CNF literal.
-func (l Lit) Int() int32 {
+func (l Lit) Int() LitInt {
 	sign := l&1 == 1
-	res := int32((l / 2) + 1)
+	res := LitInt((l / 2) + 1)
 	if sign {
 		return -res
 	}
@@ -98,7 +168,7 @@ func (l Lit) Int() int32 {
 }
 
 func (l Lit) Negation() Lit {
-	// bitwise XOR on the literal. Remember we have encoded it as a 32bit integer
+	// bitwise XOR on the literal. Remember we have encoded it as an integer, see LitInt
 	return l ^ 1
 }
 
@@ -127,9 +197,12 @@ func (c *Clause) CNF() string {
 // also assumes we have sorted the literals in the clause
 func (c *Clause) Subsumes(c2 *Clause) bool {
 	// size of c must be less than c2
-	if c.Len() > c2.Len() {
+	if c.Len() > c2.Len() || c.signature()&^c2.signature() != 0 {
 		return false
 	}
+	if c.bits != nil && c2.bits != nil {
+		return subsetBits(c.bits, c2.bits)
+	}
 	for _, lit := range c.lits {
 		match := false
 		for _, lit2 := range c2.lits {
@@ -150,8 +223,40 @@ func (c *Clause) Subsumes(c2 *Clause) bool {
 	return true
 }
 
+// signature returns a 64-bit abstraction of the vars of the clause.
+// If c subsumes or self-subsumes c2, then the signature of c is included in the signature of c2.
+// It is computed once, then cached until the lits of c change through Set, Shrink or setLits.
+func (c *Clause) signature() uint64 {
+	if c.abstraction == 0 {
+		c.abstraction = computeSignature(c.lits)
+	}
+	return c.abstraction
+}
+
+// computeSignature returns the signature of a clause made of the given lits.
+func computeSignature(lits []Lit) uint64 {
+	var sig uint64
+	for _, lit := range lits {
+		sig |= 1 << (uint(lit.Var()) % 64)
+	}
+	return sig
+}
+
+// setLits replaces the lits of c, and drops its cached signature and bitset.
+func (c *Clause) setLits(lits []Lit) {
+	c.lits = lits
+	c.abstraction = 0
+	c.bits = nil
+}
+
 // SelfSubsumes returns true iff c self-subsumes c2.
 func (c *Clause) SelfSubsumes(c2 *Clause) bool {
+	if c.Len() > c2.Len() || c.signature()&^c2.signature() != 0 {
+		return false
+	}
+	if c.bits != nil && c2.bits != nil {
+		return selfSubsetBits(c.bits, c2.bits)
+	}
 	oneNeg := false
 	for _, lit := range c.lits {
 		found := false
@@ -181,41 +286,67 @@ func (c *Clause) SelfSubsumes(c2 *Clause) bool {
 	return oneNeg
 }
 
-// Simplify simplifies the given clause by removing redundant lits.
-// If the clause is trivially satisfied (i.e contains both a lit and its negation),
-// true is returned. Otherwise, false is returned.
-func (c *Clause) Simplify() (isSat bool) {
+// Canonicalize puts c in canonical form: its lits are sorted and deduplicated in place.
+// Clauses of a problem are always in canonical form, so that membership can be tested through binary search,
+// subsumption through a merge, and equality by comparing lits one by one, see Equal.
+func (c *Clause) Canonicalize() {
 	c.Sort()
-	lits := make([]Lit, 0, len(c.lits))
-	i := 0
-	for i < len(c.lits) {
-		if i < len(c.lits)-1 && c.lits[i] == c.lits[i+1].Negation() {
-			return true
+	n := 0
+	for _, lit := range c.lits {
+		if n > 0 && c.lits[n-1] == lit {
+			continue
 		}
-		lit := c.lits[i]
-		lits = append(lits, lit)
-		i++
-		for i < len(c.lits) && c.lits[i] == lit {
-			i++
+		c.lits[n] = lit
+		n++
+	}
+	c.lits = c.lits[:n]
+}
+
+// Equal returns whether c and c2, both in canonical form, have the same lits.
+func (c *Clause) Equal(c2 *Clause) bool {
+	if c.Len() != c2.Len() {
+		return false
+	}
+	for i, lit := range c.lits {
+		if c2.lits[i] != lit {
+			return false
 		}
 	}
-	if len(lits) < len(c.lits) {
-		c.lits = lits
+	return true
+}
+
+// Simplify simplifies the given clause by removing redundant lits.
+// If the clause is trivially satisfied (i.e contains both a lit and its negation), true is returned.
+// Otherwise, false is returned. In both cases, c is put in canonical form, see Canonicalize.
+func (c *Clause) Simplify() (isSat bool) {
+	c.Canonicalize()
+	for i := 1; i < len(c.lits); i++ {
+		if c.lits[i] == c.lits[i-1].Negation() { // A lit and its negation are adjacent once sorted
+			return true
+		}
 	}
 	return false
 }
 
 // Generate returns a subsumed clause from c and c2, by removing v.
+// If c and c2 are in canonical form, their lits are merged so that the result is in canonical form too.
+// The result is in the partition of c, see Interpolation.go.
 func (c *Clause) Generate(c2 *Clause, v Var) *Clause {
-	c3 := &Clause{lits: make([]Lit, 0, len(c.lits)+len(c2.lits)-2)}
-	for _, lit := range c.lits {
-		if lit.Var() != v {
+	c3 := &Clause{lits: make([]Lit, 0, len(c.lits)+len(c2.lits)-2), origin: Derived, partition: c.partition}
+	add := func(lit Lit) {
+		if lit.Var() != v && (len(c3.lits) == 0 || c3.lits[len(c3.lits)-1] != lit) {
 			c3.lits = append(c3.lits, lit)
+			c3.abstraction |= 1 << (uint(lit.Var()) % 64)
 		}
 	}
-	for _, lit2 := range c2.lits {
-		if lit2.Var() != v {
-			c3.lits = append(c3.lits, lit2)
+	i, j := 0, 0
+	for i < len(c.lits) || j < len(c2.lits) {
+		if j == len(c2.lits) || (i < len(c.lits) && c.lits[i] <= c2.lits[j]) {
+			add(c.lits[i])
+			i++
+		} else {
+			add(c2.lits[j])
+			j++
 		}
 	}
 	return c3
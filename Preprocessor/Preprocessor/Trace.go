@@ -0,0 +1,134 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RESOLUTION TRACES
+// The provenance of the clauses, see Provenance.go, is a resolution graph: each derived clause is a resolvent of its
+// premises, e.g the strengthened clauses of SelfSub and the resolvents of the elimination of vars. Once recorded,
+// through pb.Options.Provenance, it can be written as a TraceCheck file, to be checked or processed by proof tools,
+// or as a GraphViz DOT graph, to show students how a formula was simplified. Both can be restricted to the steps
+// of some techniques, e.g "selfsub" and "elim": the premises of these steps that were not derived by them are then
+// written as original clauses.
+// In TraceCheck files, each line is a clause, "<id> <lits> 0 <premises> 0", and original clauses have no premises.
+
+// WriteTraceCheck writes the derivations recorded in the provenance of the problem, or those of the given techniques
+// if any is given, in the TraceCheck format. It returns ErrNoProvenance if pb.Options.Provenance is not set.
+func (pb *Problem) WriteTraceCheck(w io.Writer, techniques ...string) error {
+	steps, originals, lits, err := pb.resolutionGraph(techniques)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, id := range originals {
+		fmt.Fprintf(bw, "%d %s0 0\n", id, dimacsLits(lits[id]))
+	}
+	for _, step := range steps {
+		fmt.Fprintf(bw, "%d %s0 ", step.ID, dimacsLits(step.Lits))
+		for _, premise := range step.Premises {
+			fmt.Fprintf(bw, "%d ", premise)
+		}
+		fmt.Fprintln(bw, "0")
+	}
+	return bw.Flush()
+}
+
+// WriteResolutionDOT writes the derivations recorded in the provenance of the problem, or those of the given
+// techniques if any is given, as a GraphViz DOT graph: each clause is a node, labeled by its ID, its lits and the
+// technique that derived it, if any, with an edge from each of its premises. Original clauses are boxes.
+// It returns ErrNoProvenance if pb.Options.Provenance is not set.
+func (pb *Problem) WriteResolutionDOT(w io.Writer, techniques ...string) error {
+	steps, originals, lits, err := pb.resolutionGraph(techniques)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph resolution {")
+	for _, id := range originals {
+		fmt.Fprintf(bw, "\tc%d [shape=box, label=\"%d: %s0\"];\n", id, id, dimacsLits(lits[id]))
+	}
+	for _, step := range steps {
+		fmt.Fprintf(bw, "\tc%d [label=\"%d: %s0\\n%s\"];\n", step.ID, step.ID, dimacsLits(step.Lits), step.Technique)
+		for _, premise := range step.Premises {
+			fmt.Fprintf(bw, "\tc%d -> c%d;\n", premise, step.ID)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// resolutionGraph returns the derivations of the given techniques, or all of them if none is given, in the order
+// they were recorded, the sorted IDs of their premises that are not among them, and the lits of these premises.
+// An error is returned if the lits of a premise are unknown.
+func (pb *Problem) resolutionGraph(techniques []string) (steps []Step, originals []int, lits map[int][]Lit, err error) {
+	if !pb.Options.Provenance {
+		return nil, nil, nil, ErrNoProvenance
+	}
+	kept := make(map[string]bool, len(techniques))
+	for _, t := range techniques {
+		kept[t] = true
+	}
+	derived := make(map[int]bool)
+	for _, step := range pb.Provenance().Steps() {
+		if !step.Deleted && (len(techniques) == 0 || kept[step.Technique]) {
+			steps = append(steps, step)
+			derived[step.ID] = true
+		}
+	}
+	live := pb.liveLits()
+	lits = make(map[int][]Lit)
+	for _, step := range steps {
+		for _, premise := range step.Premises {
+			if !derived[premise] && lits[premise] == nil {
+				lits[premise] = pb.clauseLits(premise, live)
+				if lits[premise] == nil {
+					return nil, nil, nil, fmt.Errorf("lits of clause %d are unknown", premise)
+				}
+				originals = append(originals, premise)
+			}
+		}
+	}
+	sort.Ints(originals)
+	return steps, originals, lits, nil
+}
+
+// liveLits returns the lits of the clauses and units of the problem, by ID.
+func (pb *Problem) liveLits() map[int][]Lit {
+	res := make(map[int][]Lit, len(pb.Clauses)+len(pb.Units))
+	for _, c := range pb.Clauses {
+		res[c.id] = c.lits
+	}
+	for _, lit := range pb.Units {
+		if id := pb.UnitID(lit.Var()); id != 0 {
+			res[id] = []Lit{lit}
+		}
+	}
+	return res
+}
+
+// clauseLits returns the lits of the clause with the given ID, as recorded in the provenance, or as they are
+// in live, see liveLits. It returns nil if they are unknown.
+func (pb *Problem) clauseLits(id int, live map[int][]Lit) []Lit {
+	p := pb.Provenance()
+	if step, ok := p.Derivation(id); ok {
+		return append([]Lit{}, step.Lits...) // Not nil for the empty clause
+	}
+	if step, ok := p.Deletion(id); ok && step.Lits != nil {
+		return step.Lits
+	}
+	return live[id]
+}
+
+// dimacsLits returns the lits in the DIMACS format, each of them followed by a space.
+func dimacsLits(lits []Lit) string {
+	var sb strings.Builder
+	for _, lit := range lits {
+		fmt.Fprintf(&sb, "%d ", lit.Int())
+	}
+	return sb.String()
+}
@@ -0,0 +1,169 @@
+package Preprocessor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TRACKED CLAUSES
+// Applications encoding domain constraints as clauses need to tell their users what became of each constraint,
+// e.g that it was subsumed by another one, or that a unit made one of its options impossible. Clause IDs change
+// each time a clause is derived again, so a ClauseHandle follows a clause through the provenance of the problem,
+// see Provenance.go: when a clause is deleted, and one of the reasons of the deletion, or the clause derived right
+// before it, was derived from it and is a subset of it, the clause became that one, and is followed on.
+// Otherwise, the clause was deleted. Clauses rewritten into clauses that are not subsets of them, e.g by the
+// substitution of equivalent lits, are reported as deleted, as are clauses removed by the elimination of a var.
+
+// A ClauseFate tells what preprocessing did to a tracked clause.
+type ClauseFate uint8
+
+const (
+	ClauseKept         = ClauseFate(iota) // The clause is still in the problem, unchanged.
+	ClauseStrengthened                    // The clause was replaced by a subset of it, maybe a unit or the empty clause.
+	ClauseDeleted                         // The clause was removed from the problem.
+)
+
+// A ClauseHandle refers to a clause of a problem across preprocessing, see Track.
+type ClauseHandle struct {
+	pb *Problem
+	id int
+}
+
+// A ClauseState is what became of a tracked clause.
+type ClauseState struct {
+	Fate      ClauseFate
+	ID        int    // ID of the clause, or of the clause it became if it was strengthened, or was deleted after.
+	Lits      []Lit  // Lits of that clause, nil if they are unknown.
+	Technique string // Technique that last strengthened the clause, or deleted it.
+	Reasons   []int  // IDs of the clauses that made the clause redundant, if it was deleted.
+}
+
+// Track returns a handle following the clause with the given ID, e.g the ID of an input clause, across preprocessing.
+// The clause may already have been simplified, e.g by the units propagated while parsing.
+// pb.Options.Provenance must have been set since the clause was added, otherwise ErrNoProvenance is returned.
+func (pb *Problem) Track(id int) (*ClauseHandle, error) {
+	if !pb.Options.Provenance {
+		return nil, ErrNoProvenance
+	}
+	if id <= 0 || id > pb.lastID {
+		return nil, fmt.Errorf("no clause with ID %d", id)
+	}
+	return &ClauseHandle{pb: pb, id: id}, nil
+}
+
+// ID returns the ID of the tracked clause, as given to Track.
+func (h *ClauseHandle) ID() int {
+	return h.id
+}
+
+// State returns what became of the tracked clause so far.
+func (h *ClauseHandle) State() ClauseState {
+	p := h.pb.Provenance()
+	state := ClauseState{Fate: ClauseKept, ID: h.id}
+	for {
+		idx, ok := p.deletions[state.ID]
+		if !ok {
+			break
+		}
+		deletion := p.steps[idx]
+		next, ok := p.successor(idx)
+		if !ok {
+			state.Fate, state.Lits = ClauseDeleted, deletion.Lits
+			state.Technique, state.Reasons = deletion.Technique, deletion.Premises
+			return state
+		}
+		state.Fate, state.ID, state.Technique = ClauseStrengthened, next.ID, next.Technique
+	}
+	if step, ok := p.Derivation(state.ID); ok && state.Fate == ClauseStrengthened {
+		state.Lits = append([]Lit{}, step.Lits...) // Not nil for the empty clause
+		return state
+	}
+	for _, c := range h.pb.Clauses {
+		if c.id == state.ID && !c.removed {
+			state.Lits = append([]Lit(nil), c.lits...)
+			return state
+		}
+	}
+	for _, lit := range h.pb.Units {
+		if h.pb.UnitID(lit.Var()) == state.ID {
+			state.Lits = []Lit{lit}
+			break
+		}
+	}
+	return state
+}
+
+// successor returns the derivation of the clause the clause deleted by the step at index idx became, if any, see above.
+func (p *Provenance) successor(idx int) (step Step, ok bool) {
+	deletion := p.steps[idx]
+	candidates := deletion.Premises
+	if idx > 0 && !p.steps[idx-1].Deleted {
+		candidates = append(append([]int(nil), candidates...), p.steps[idx-1].ID)
+	}
+	for i, id := range candidates {
+		step, ok := p.Derivation(id)
+		if !ok || !containsID(step.Premises, deletion.ID) {
+			continue
+		}
+		explicit := i < len(deletion.Premises)
+		if deletion.Lits == nil && explicit || deletion.Lits != nil && subsetOf(step.Lits, deletion.Lits) {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+// containsID returns whether ids contains id.
+func containsID(ids []int, id int) bool {
+	for _, id2 := range ids {
+		if id2 == id {
+			return true
+		}
+	}
+	return false
+}
+
+// subsetOf returns whether all the lits of sub appear in lits.
+func subsetOf(sub, lits []Lit) bool {
+	for _, lit := range sub {
+		found := false
+		for _, lit2 := range lits {
+			if lit2 == lit {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the state for end users, e.g "strengthened to 1 -3 0 by selfsub", or "deleted by subsumption (clause 12)".
+func (s ClauseState) String() string {
+	var sb strings.Builder
+	switch s.Fate {
+	case ClauseKept:
+		sb.WriteString("kept")
+	case ClauseStrengthened:
+		sb.WriteString("strengthened to ")
+		if s.Lits == nil {
+			fmt.Fprintf(&sb, "clause %d", s.ID)
+		} else {
+			for _, lit := range s.Lits {
+				fmt.Fprintf(&sb, "%d ", lit.Int())
+			}
+			sb.WriteString("0")
+		}
+		fmt.Fprintf(&sb, " by %s", s.Technique)
+	case ClauseDeleted:
+		fmt.Fprintf(&sb, "deleted by %s", s.Technique)
+		if len(s.Reasons) == 1 {
+			fmt.Fprintf(&sb, " (clause %d)", s.Reasons[0])
+		} else if len(s.Reasons) > 1 {
+			fmt.Fprintf(&sb, " (clauses %v)", s.Reasons)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,224 @@
+package Preprocessor
+
+import (
+	"log"
+	"math/rand"
+	"sort"
+)
+
+// UNHIDING
+// Implementation of the "unhiding" technique from http://fmv.jku.at/papers/HeuleJarvisaloBiere-SAT11.pdf.
+// A randomized DFS over the binary implication graph (BIG) gives each lit a discovery and a finish time stamp.
+// If dsc[u] < dsc[v] and fin[v] < fin[u], then u implies v, which is checked in constant time.
+// Stamps are used for hidden tautology elimination (UHTE) and hidden literal elimination (UHLE) on all clauses.
+
+// stamps are the discovery and finish times of each lit in a DFS of the BIG.
+type stamps struct {
+	dsc []int
+	fin []int
+}
+
+// implies is true iff u implies v according to the stamps.
+func (s *stamps) implies(u, v Lit) bool {
+	return s.dsc[u] < s.dsc[v] && s.fin[v] < s.fin[u]
+}
+
+// big returns the binary implication graph of the problem: for each lit l, the list of lits implied by l.
+func (pb *Problem) big() [][]Lit {
+	pb.memory().charge(occurrencesSize(pb.NbVars, 2*len(pb.Clauses))) // At most 2 edges per clause
+	big := make([][]Lit, pb.NbVars*2)
+	for _, c := range pb.Clauses {
+		if c.Len() == 2 {
+			l1, l2 := c.Get(0), c.Get(1)
+			big[l1.Negation()] = append(big[l1.Negation()], l2)
+			big[l2.Negation()] = append(big[l2.Negation()], l1)
+		}
+	}
+	return big
+}
+
+// stamp runs a DFS on the BIG, in random order. Roots, i.e lits with no predecessor, are visited first.
+func (pb *Problem) stamp(big [][]Lit, rng *rand.Rand) *stamps {
+	nbLits := pb.NbVars * 2
+	s := &stamps{dsc: make([]int, nbLits), fin: make([]int, nbLits)}
+	hasPred := make([]bool, nbLits)
+	for l := range big {
+		rng.Shuffle(len(big[l]), func(i, j int) { big[l][i], big[l][j] = big[l][j], big[l][i] })
+		for _, l2 := range big[l] {
+			hasPred[l2] = true
+		}
+	}
+	roots := make([]Lit, 0, nbLits)
+	others := make([]Lit, 0, nbLits)
+	for _, l := range rng.Perm(nbLits) {
+		if hasPred[l] {
+			others = append(others, Lit(l))
+		} else {
+			roots = append(roots, Lit(l))
+		}
+	}
+	type frame struct {
+		lit Lit
+		idx int // Index of the next child to visit
+	}
+	time := 0
+	stack := make([]frame, 0)
+	for _, root := range append(roots, others...) {
+		if s.dsc[root] != 0 {
+			continue
+		}
+		time++
+		s.dsc[root] = time
+		stack = append(stack, frame{lit: root})
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.idx < len(big[top.lit]) {
+				child := big[top.lit][top.idx]
+				top.idx++
+				if s.dsc[child] == 0 {
+					time++
+					s.dsc[child] = time
+					stack = append(stack, frame{lit: child})
+				}
+			} else {
+				time++
+				s.fin[top.lit] = time
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return s
+}
+
+// hiddenTautology is true iff c contains two lits l1 and l2 such that -l1 implies l2 (UHTE).
+// Lits of c and the negations of lits of c are scanned by increasing discovery time, in linear time.
+func (c *Clause) hiddenTautology(s *stamps) bool {
+	pos := append([]Lit(nil), c.lits...)
+	neg := make([]Lit, len(c.lits))
+	for i, lit := range c.lits {
+		neg[i] = lit.Negation()
+	}
+	sort.Slice(pos, func(i, j int) bool { return s.dsc[pos[i]] < s.dsc[pos[j]] })
+	sort.Slice(neg, func(i, j int) bool { return s.dsc[neg[i]] < s.dsc[neg[j]] })
+	i, j := 0, 0
+	for {
+		if s.dsc[neg[j]] > s.dsc[pos[i]] {
+			if i++; i == len(pos) {
+				return false
+			}
+		} else if s.fin[neg[j]] < s.fin[pos[i]] {
+			if j++; j == len(neg) {
+				return false
+			}
+		} else {
+			return true
+		}
+	}
+}
+
+// hiddenLits returns the lits of c, minus the hidden lits (UHLE):
+// if l1 implies l2 and both are in c, l1 can be removed from c.
+func (c *Clause) hiddenLits(s *stamps) []Lit {
+	removed := make(map[Lit]bool)
+	lits := append([]Lit(nil), c.lits...)
+	// l1 is removed if it implies a lit discovered after it
+	sort.Slice(lits, func(i, j int) bool { return s.dsc[lits[i]] > s.dsc[lits[j]] })
+	finished := s.fin[lits[0]]
+	for _, lit := range lits[1:] {
+		if s.fin[lit] > finished {
+			removed[lit] = true
+		} else {
+			finished = s.fin[lit]
+		}
+	}
+	// l1 is removed if -l1 is implied by the negation of a lit discovered before it.
+	// Only the remaining lits are considered, so that two equivalent lits are not both removed.
+	kept := lits[:0]
+	for _, lit := range lits {
+		if !removed[lit] {
+			kept = append(kept, lit)
+		}
+	}
+	lits = kept
+	sort.Slice(lits, func(i, j int) bool { return s.dsc[lits[i].Negation()] < s.dsc[lits[j].Negation()] })
+	finished = s.fin[lits[0].Negation()]
+	for _, lit := range lits[1:] {
+		if s.fin[lit.Negation()] < finished {
+			removed[lit] = true
+		} else {
+			finished = s.fin[lit.Negation()]
+		}
+	}
+	if len(removed) == 0 {
+		return c.lits
+	}
+	res := make([]Lit, 0, len(c.lits)-len(removed))
+	for _, lit := range c.lits {
+		if !removed[lit] {
+			res = append(res, lit)
+		}
+	}
+	return res
+}
+
+// Unhide runs hidden tautology and hidden literal elimination, using pb.Options.UnhideRounds randomized stampings.
+func (pb *Problem) Unhide() {
+	if pb.Status != Undetermined || pb.Options.Interpolation || pb.skipped("unhiding") {
+		return
+	}
+	log.Printf("Unhiding... %d clauses currently", len(pb.Clauses))
+	rounds := pb.Options.UnhideRounds
+	if rounds <= 0 {
+		rounds = 1
+	}
+	rng := pb.random()
+	nbTautologies, nbLits := 0, 0
+	for r := 0; r < rounds && pb.Status == Undetermined && !pb.memory().exhausted(); r++ {
+		s := pb.stamp(pb.big(), rng)
+		newUnits := false
+		for _, c := range pb.Clauses {
+			if c.protected {
+				continue
+			}
+			// Binary clauses are the BIG itself: they must not be removed through it.
+			if c.Len() > 2 && c.hiddenTautology(s) {
+				pb.deleted(c, "unhide")
+				pb.markRemoved(c)
+				nbTautologies++
+				continue
+			}
+			if lits := c.hiddenLits(s); len(lits) < c.Len() {
+				nbLits += c.Len() - len(lits)
+				if len(lits) == 1 {
+					pb.derivedUnitRUP(lits[0], "unhide", c.id)
+					pb.deleted(c, "unhide")
+					if pb.Model[lits[0].Var()] == 0 {
+						pb.addUnit(lits[0])
+					} else if (pb.Model[lits[0].Var()] == 1) != lits[0].IsPositive() {
+						pb.Status = Unsat
+					}
+					pb.markRemoved(c)
+					newUnits = true
+					continue
+				}
+				old := &Clause{lits: c.lits, id: c.id}
+				pb.saveClause(c)
+				c.setLits(lits)
+				c.Canonicalize()
+				c.pbData = nil
+				c.origin = Derived
+				pb.derivedRUP(c, "unhide", []*Clause{old}, old.id)
+				pb.deletedID(old.id, old.lits, "unhide", c.id)
+			}
+		}
+		pb.sweep()
+		if pb.Status == Unsat {
+			log.Printf("Inferred UNSAT")
+			return
+		}
+		if newUnits {
+			pb.Simplify2()
+		}
+	}
+	log.Printf("Done. %d hidden tautologies and %d hidden lits removed, %d clauses now", nbTautologies, nbLits, len(pb.Clauses))
+}
@@ -0,0 +1,229 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// COMMUNITY STRUCTURE
+// Industrial problems are made of communities of vars that interact a lot with each other, and little with other vars.
+// The variable interaction graph (VIG) links two vars if they appear in a common clause; as in Ansótegui et al,
+// a clause of k lits adds a weight of 1/(k(k-1)/2) to each of its edges, so that each clause weighs 1 in total.
+// Communities are found with the Louvain method, that greedily maximizes the modularity of the partition:
+// vars are moved to the community of their neighbors as long as it increases the modularity,
+// then each community is merged into a single node, and the process is repeated on the merged graph.
+
+// A VIG is the weighted variable interaction graph of a problem.
+type VIG struct {
+	NbVars  int
+	Weights []map[Var]float64 // For each var, the weight of its edge with each of its neighbors.
+}
+
+// VariableInteractionGraph returns the VIG of the clauses of the problem. Units are ignored.
+// The number of edges is quadratic in the length of clauses.
+func (pb *Problem) VariableInteractionGraph() *VIG {
+	g := &VIG{NbVars: pb.NbVars, Weights: make([]map[Var]float64, pb.NbVars)}
+	for v := range g.Weights {
+		g.Weights[v] = make(map[Var]float64)
+	}
+	for _, c := range pb.Clauses {
+		n := c.Len()
+		w := 2 / float64(n*(n-1))
+		for i, l1 := range c.lits {
+			for _, l2 := range c.lits[i+1:] {
+				if v1, v2 := l1.Var(), l2.Var(); v1 != v2 {
+					g.Weights[v1][v2] += w
+					g.Weights[v2][v1] += w
+				}
+			}
+		}
+	}
+	return g
+}
+
+// neighbors returns the neighbors of v, in increasing order.
+func (g *VIG) neighbors(v Var) []Var {
+	res := make([]Var, 0, len(g.Weights[v]))
+	for v2 := range g.Weights[v] {
+		res = append(res, v2)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// WriteDOT writes the graph in the DOT format of graphviz. Vars are named after their DIMACS number,
+// and vars without neighbors are omitted.
+func (g *VIG) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "graph vig {")
+	for v := range g.Weights {
+		for _, v2 := range g.neighbors(Var(v)) {
+			if Var(v) < v2 {
+				fmt.Fprintf(bw, "  %d -- %d [weight=%g];\n", Var(v).Lit().Int(), v2.Lit().Int(), g.Weights[v][v2])
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteGraphML writes the graph in the GraphML format. Vars are named after their DIMACS number,
+// and vars without neighbors are omitted.
+func (g *VIG) WriteGraphML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>`)
+	fmt.Fprintln(bw, `  <graph id="vig" edgedefault="undirected">`)
+	for v := range g.Weights {
+		if len(g.Weights[v]) > 0 {
+			fmt.Fprintf(bw, "    <node id=\"%d\"/>\n", Var(v).Lit().Int())
+		}
+	}
+	for v := range g.Weights {
+		for _, v2 := range g.neighbors(Var(v)) {
+			if Var(v) < v2 {
+				fmt.Fprintf(bw, "    <edge source=\"%d\" target=\"%d\"><data key=\"weight\">%g</data></edge>\n",
+					Var(v).Lit().Int(), v2.Lit().Int(), g.Weights[v][v2])
+			}
+		}
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// Communities partitions the vars of the graph with the Louvain method. It returns the community of each var,
+// numbered from 0, and the modularity of the partition. Vars without neighbors are alone in their community.
+func (g *VIG) Communities() (communities []int, modularity float64) {
+	// The graph being partitioned: at first, the VIG, then the graph of the communities of the previous level.
+	// Self loops hold the weight of the edges inside a community, counted in both directions.
+	weights := make([]map[int]float64, g.NbVars)
+	for v, ws := range g.Weights {
+		weights[v] = make(map[int]float64, len(ws))
+		for v2, w := range ws {
+			weights[v][int(v2)] = w
+		}
+	}
+	communities = make([]int, g.NbVars)
+	for v := range communities {
+		communities[v] = v
+	}
+	for {
+		level, moved := louvainLevel(weights)
+		if !moved {
+			break
+		}
+		nbComms := 0
+		for _, c := range level {
+			if c >= nbComms {
+				nbComms = c + 1
+			}
+		}
+		merged := make([]map[int]float64, nbComms)
+		for c := range merged {
+			merged[c] = make(map[int]float64)
+		}
+		for n, ws := range weights {
+			for n2, w := range ws {
+				merged[level[n]][level[n2]] += w
+			}
+		}
+		for v, n := range communities {
+			communities[v] = level[n]
+		}
+		weights = merged
+	}
+	return communities, g.modularity(communities)
+}
+
+// louvainEpsilon is the min modularity gain of a move, so that rounding errors do not make nodes move forever.
+const louvainEpsilon = 1e-12
+
+// louvainLevel moves each node of the weighted graph to the community of one of its neighbors
+// while it increases the modularity. It returns the community of each node, numbered from 0 in order of appearance,
+// and whether any node was moved.
+func louvainLevel(weights []map[int]float64) (communities []int, moved bool) {
+	n := len(weights)
+	degrees := make([]float64, n)
+	totals := make([]float64, n) // Sum of the degrees of the nodes of each community
+	total := 0.0                 // Twice the total weight of the graph
+	communities = make([]int, n)
+	for i, ws := range weights {
+		for _, w := range ws {
+			degrees[i] += w
+		}
+		communities[i] = i
+		totals[i] = degrees[i]
+		total += degrees[i]
+	}
+	if total == 0 {
+		return communities, false
+	}
+	for improved := true; improved; {
+		improved = false
+		for i, ws := range weights {
+			// Weight of the edges between i and each neighboring community
+			links := make(map[int]float64)
+			for j, w := range ws {
+				if j != i {
+					links[communities[j]] += w
+				}
+			}
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			old := communities[i]
+			totals[old] -= degrees[i]
+			// Nodes only move for a strictly better community, so that they do not cycle between equivalent ones
+			best, bestGain := old, links[old]-totals[old]*degrees[i]/total
+			for _, c := range candidates {
+				if gain := links[c] - totals[c]*degrees[i]/total; gain > bestGain+louvainEpsilon {
+					best, bestGain = c, gain
+				}
+			}
+			totals[best] += degrees[i]
+			if best != old {
+				communities[i] = best
+				improved, moved = true, true
+			}
+		}
+	}
+	// Renumber communities
+	numbers := make(map[int]int)
+	for i, c := range communities {
+		if _, ok := numbers[c]; !ok {
+			numbers[c] = len(numbers)
+		}
+		communities[i] = numbers[c]
+	}
+	return communities, moved
+}
+
+// modularity returns the modularity of the given partition of the vars of the graph.
+func (g *VIG) modularity(communities []int) float64 {
+	inside := make(map[int]float64)
+	totals := make(map[int]float64)
+	total := 0.0
+	for v, ws := range g.Weights {
+		for v2, w := range ws {
+			totals[communities[v]] += w
+			total += w
+			if communities[v] == communities[v2] {
+				inside[communities[v]] += w
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	res := 0.0
+	for c, t := range totals {
+		res += inside[c]/total - (t/total)*(t/total)
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+package Preprocessor
+
+// INPUT VALIDATION
+// Passes assume that every lit of a problem is a lit of one of its NbVars vars, and would index out of range otherwise.
+// Parsers reject such lits, and AddClause adds the vars it does not know of, but problems built by hand,
+// through NewClause and the exported fields, can break that assumption: Validate checks it, and can repair the problem.
+// Degenerate problems are valid: a problem without vars or without clauses is Sat once preprocessed,
+// and its model is given by its units.
+
+// Validate checks that every lit of the problem, in its clauses, units, gates, cost function and lit weights,
+// is a lit of one of its vars, and that pb.Model has a value for each var, and no more.
+// If extend is true, the vars and values that are missing are added to the problem, as NewVar does.
+// Otherwise, or if the problem cannot be repaired, an error wrapping ErrBadInput describes the first problem met.
+func (pb *Problem) Validate(extend bool) error {
+	if pb.NbVars < 0 {
+		return badInput("invalid number of vars %d", pb.NbVars)
+	}
+	if len(pb.Model) > pb.NbVars {
+		return badInput("model has %d vars, problem has %d", len(pb.Model), pb.NbVars)
+	}
+	nbVars := pb.NbVars
+	var err error
+	check := func(lit Lit, where string) {
+		switch {
+		case err != nil:
+		case lit < 0:
+			err = badInput("invalid lit %d in %s", lit, where)
+		case int(lit.Var()) >= nbVars && extend:
+			nbVars = int(lit.Var()) + 1
+		case int(lit.Var()) >= nbVars:
+			err = badInput("%s contains %d, but the problem has %d vars only", where, lit.Int(), pb.NbVars)
+		}
+	}
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			check(lit, "clause "+c.CNF())
+		}
+	}
+	for _, lit := range pb.Units {
+		check(lit, "units")
+	}
+	for _, g := range pb.Gates {
+		check(g.Out, "gate")
+		for _, lit := range g.In {
+			check(lit, "gate")
+		}
+	}
+	for _, lit := range pb.minLits {
+		check(lit, "cost function")
+	}
+	for lit := range pb.LitWeights {
+		check(lit, "lit weights")
+	}
+	if err != nil {
+		return err
+	}
+	if len(pb.Model) < pb.NbVars && !extend {
+		return badInput("model has %d vars, problem has %d", len(pb.Model), pb.NbVars)
+	}
+	for len(pb.Model) < pb.NbVars {
+		pb.Model = append(pb.Model, 0)
+	}
+	for pb.NbVars < nbVars {
+		pb.NewVar()
+	}
+	return nil
+}
+
+// addVars adds to the problem the vars of the given lits it does not know of yet.
+func (pb *Problem) addVars(lits []Lit) {
+	for _, lit := range lits {
+		for int(lit.Var()) >= pb.NbVars {
+			pb.NewVar()
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package Preprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WEIGHTED MODEL COUNTING
+// The weighted model count of a problem is the sum, over its models, of the product of the weights of their lits.
+// Passes keep the models of the problem, so the count only has to be adjusted for the vars that left the clauses:
+// a var bound by a unit contributes the weight of its true lit to each model, and a var that appears in no clause
+// anymore, e.g after resolution, contributes the sum of the weights of its lits. Their contribution is factored out,
+// so a counter only needs the clauses and the weights of the vars that still appear in them.
+// Techniques that do not keep the models, such as SolveFragment or symmetry breaking, do not keep the count either.
+
+// weight returns the weight of lit, 1 if it has none.
+func (pb *Problem) weight(lit Lit) float64 {
+	if w, ok := pb.LitWeights[lit]; ok {
+		return w
+	}
+	return 1
+}
+
+// inClauses returns, for each var, whether it appears in a clause.
+func (pb *Problem) inClauses() []bool {
+	res := make([]bool, pb.NbVars)
+	for _, c := range pb.Clauses {
+		for _, lit := range c.lits {
+			res[lit.Var()] = true
+		}
+	}
+	return res
+}
+
+// WeightFactor returns the contribution of the vars that do not appear in the clauses to the weighted model count:
+// the count of the original problem is WeightFactor times the count of the clauses,
+// weighted by ResidualWeights, over the vars that appear in them.
+// Without weights, i.e if LitWeights is nil, it is the number of assignments of the vars that were freed.
+// It is 0 if the problem is Unsat.
+func (pb *Problem) WeightFactor() float64 {
+	if pb.Status == Unsat {
+		return 0
+	}
+	res := 1.0
+	inClauses := pb.inClauses()
+	for v, val := range pb.Model {
+		lit := Var(v).Lit()
+		switch {
+		case val == 1:
+			res *= pb.weight(lit)
+		case val == -1:
+			res *= pb.weight(lit.Negation())
+		case !inClauses[v]:
+			res *= pb.weight(lit) + pb.weight(lit.Negation())
+		}
+	}
+	return res
+}
+
+// ResidualWeights returns the weights of the lits of the vars that still appear in the clauses, see WeightFactor.
+func (pb *Problem) ResidualWeights() map[Lit]float64 {
+	res := make(map[Lit]float64)
+	inClauses := pb.inClauses()
+	for lit, w := range pb.LitWeights {
+		if int(lit.Var()) < pb.NbVars && inClauses[lit.Var()] {
+			res[lit] = w
+		}
+	}
+	return res
+}
+
+// WriteWeights writes the residual weights in the "c p weight <lit> <weight> 0" format of the model counting competition,
+// in increasing var order. Lits without weight are omitted.
+func (pb *Problem) WriteWeights(w io.Writer) error {
+	weights := pb.ResidualWeights()
+	lits := make([]Lit, 0, len(weights))
+	for lit := range weights {
+		lits = append(lits, lit)
+	}
+	sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+	bw := bufio.NewWriter(w)
+	for _, lit := range lits {
+		fmt.Fprintf(bw, "c p weight %d %g 0\n", lit.Int(), weights[lit])
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,262 @@
+// Package aiger reads and-inverter graphs in the AIGER format (ascii "aag" and binary "aig")
+// and turns them into preprocessor problems through Tseitin conversion.
+package aiger
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Latch is a state element. Lit is its current state, Next its next state function.
+type Latch struct {
+	Lit  uint
+	Next uint
+}
+
+// An And is an and gate, Lhs <-> Rhs0 & Rhs1.
+type And struct {
+	Lhs  uint
+	Rhs0 uint
+	Rhs1 uint
+}
+
+// A Circuit is the content of an AIGER file.
+// Literals use the AIGER encoding: 2*v for var v, 2*v+1 for its negation, 0 and 1 being the constants false and true.
+type Circuit struct {
+	MaxVar      int
+	Inputs      []uint
+	Latches     []Latch
+	Outputs     []uint
+	Bad         []uint // Bad state properties (AIGER 1.9)
+	Constraints []uint // Invariant constraints (AIGER 1.9)
+	Ands        []And
+}
+
+// ParseAIGER parses an AIGER file, either ascii or binary, and returns the corresponding Problem.
+// Errors wrap Preprocessor.ErrBadInput.
+func ParseAIGER(f io.Reader) (*Preprocessor.Problem, error) {
+	c, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", Preprocessor.ErrBadInput, err)
+	}
+	return c.Problem(), nil
+}
+
+// Read reads a circuit in AIGER format. The format (ascii or binary) is detected from the header.
+func Read(f io.Reader) (*Circuit, error) {
+	r := bufio.NewReader(f)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %v", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 6 || (fields[0] != "aag" && fields[0] != "aig") {
+		return nil, fmt.Errorf("invalid syntax %q in header", line)
+	}
+	var hdr [9]int
+	for i := 1; i < len(fields) && i <= len(hdr); i++ {
+		if hdr[i-1], err = strconv.Atoi(fields[i]); err != nil || hdr[i-1] < 0 {
+			return nil, fmt.Errorf("invalid value %q in header", fields[i])
+		}
+	}
+	if hdr[7] != 0 || hdr[8] != 0 {
+		return nil, fmt.Errorf("justice and fairness properties are not supported")
+	}
+	c := &Circuit{MaxVar: hdr[0]}
+	nbInputs, nbLatches, nbOutputs, nbAnds, nbBad, nbConstraints := hdr[1], hdr[2], hdr[3], hdr[4], hdr[5], hdr[6]
+	if nbInputs+nbLatches+nbAnds > c.MaxVar {
+		return nil, fmt.Errorf("%d vars cannot hold %d inputs, %d latches and %d ands", c.MaxVar, nbInputs, nbLatches, nbAnds)
+	}
+	binary := fields[0] == "aig"
+	c.Inputs = make([]uint, nbInputs)
+	for i := range c.Inputs {
+		if binary {
+			c.Inputs[i] = uint(2 * (i + 1))
+		} else if c.Inputs[i], err = c.readLits(r, 1, "input"); err != nil {
+			return nil, err
+		}
+	}
+	c.Latches = make([]Latch, nbLatches)
+	for i := range c.Latches {
+		var vals []uint
+		if binary {
+			c.Latches[i].Lit = uint(2 * (nbInputs + i + 1))
+			vals, err = c.readLine(r, "latch")
+		} else {
+			vals, err = c.readLine(r, "latch")
+			if err == nil && len(vals) > 0 {
+				c.Latches[i].Lit = vals[0]
+				vals = vals[1:]
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("missing next state for latch #%d", i+1)
+		}
+		c.Latches[i].Next = vals[0] // A reset value, if any, is ignored.
+	}
+	if c.Outputs, err = c.readLitList(r, nbOutputs, "output"); err != nil {
+		return nil, err
+	}
+	if c.Bad, err = c.readLitList(r, nbBad, "bad state"); err != nil {
+		return nil, err
+	}
+	if c.Constraints, err = c.readLitList(r, nbConstraints, "constraint"); err != nil {
+		return nil, err
+	}
+	c.Ands = make([]And, nbAnds)
+	for i := range c.Ands {
+		if binary {
+			c.Ands[i].Lhs = uint(2 * (nbInputs + nbLatches + i + 1))
+			delta0, err := read7(r)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read and gate #%d: %v", i+1, err)
+			}
+			delta1, err := read7(r)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read and gate #%d: %v", i+1, err)
+			}
+			if delta0 > c.Ands[i].Lhs || delta1 > c.Ands[i].Lhs-delta0 {
+				return nil, fmt.Errorf("invalid delta encoding for and gate #%d", i+1)
+			}
+			c.Ands[i].Rhs0 = c.Ands[i].Lhs - delta0
+			c.Ands[i].Rhs1 = c.Ands[i].Rhs0 - delta1
+		} else {
+			vals, err := c.readLine(r, "and gate")
+			if err != nil {
+				return nil, err
+			}
+			if len(vals) != 3 {
+				return nil, fmt.Errorf("and gate #%d must have 3 literals, got %d", i+1, len(vals))
+			}
+			c.Ands[i] = And{Lhs: vals[0], Rhs0: vals[1], Rhs1: vals[2]}
+		}
+		if c.Ands[i].Lhs&1 == 1 || c.Ands[i].Lhs < 2 {
+			return nil, fmt.Errorf("invalid lhs %d for and gate #%d", c.Ands[i].Lhs, i+1)
+		}
+	}
+	// The symbol table and the comment section are ignored.
+	return c, nil
+}
+
+// readLine reads a line of AIGER literals.
+func (c *Circuit) readLine(r *bufio.Reader, what string) ([]uint, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, fmt.Errorf("cannot read %s: %v", what, err)
+	}
+	fields := strings.Fields(line)
+	res := make([]uint, len(fields))
+	for i, field := range fields {
+		val, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s literal %q", what, field)
+		}
+		if val > uint64(2*c.MaxVar+1) {
+			return nil, fmt.Errorf("invalid %s literal %d for circuit with %d vars only", what, val, c.MaxVar)
+		}
+		res[i] = uint(val)
+	}
+	return res, nil
+}
+
+// readLits reads a line made of exactly n literals and returns the first one.
+func (c *Circuit) readLits(r *bufio.Reader, n int, what string) (uint, error) {
+	vals, err := c.readLine(r, what)
+	if err != nil {
+		return 0, err
+	}
+	if len(vals) != n {
+		return 0, fmt.Errorf("expected %d literal(s) for %s, got %d", n, what, len(vals))
+	}
+	return vals[0], nil
+}
+
+// readLitList reads nb lines, each made of a single literal.
+func (c *Circuit) readLitList(r *bufio.Reader, nb int, what string) ([]uint, error) {
+	res := make([]uint, nb)
+	for i := range res {
+		lit, err := c.readLits(r, 1, what)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = lit
+	}
+	return res, nil
+}
+
+// read7 reads a delta-encoded unsigned int from the binary and gate section.
+func read7(r *bufio.Reader) (uint, error) {
+	var res uint
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		res |= uint(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return res, nil
+		}
+	}
+	return 0, fmt.Errorf("delta value too large")
+}
+
+// Problem returns the Tseitin encoding of the circuit.
+// Every output, bad state property and constraint is asserted, so that the problem is satisfiable
+// iff some assignment of the inputs and latches sets all of them to true.
+// Latches are considered as free inputs: next state functions are not encoded.
+// AIGER var v is Preprocessor var v-1. If the constants are used, an extra var, bound to true, represents them.
+// Each and gate is recorded as a Preprocessor.Gate on the problem.
+func (c *Circuit) Problem() *Preprocessor.Problem {
+	nbVars := c.MaxVar
+	if c.usesConstants() {
+		nbVars++
+	}
+	pb := Preprocessor.NewProblem(nbVars)
+	lit := func(l uint) Preprocessor.Lit {
+		if l < 2 { // Constants: true is the extra var, false its negation.
+			return Preprocessor.Var(c.MaxVar).Lit() ^ Preprocessor.Lit(1-l)
+		}
+		return Preprocessor.Lit(l - 2)
+	}
+	if nbVars > c.MaxVar {
+		pb.AddClause([]Preprocessor.Lit{lit(1)})
+	}
+	for _, and := range c.Ands {
+		pb.AddGate(Preprocessor.Gate{
+			Kind: Preprocessor.AndGate,
+			Out:  lit(and.Lhs),
+			In:   []Preprocessor.Lit{lit(and.Rhs0), lit(and.Rhs1)},
+		})
+	}
+	for _, props := range [][]uint{c.Outputs, c.Bad, c.Constraints} {
+		for _, l := range props {
+			pb.AddClause([]Preprocessor.Lit{lit(l)})
+		}
+	}
+	pb.Simplify2()
+	return pb
+}
+
+// usesConstants is true iff the constants true or false appear in the circuit.
+func (c *Circuit) usesConstants() bool {
+	for _, props := range [][]uint{c.Outputs, c.Bad, c.Constraints} {
+		for _, l := range props {
+			if l < 2 {
+				return true
+			}
+		}
+	}
+	for _, and := range c.Ands {
+		if and.Rhs0 < 2 || and.Rhs1 < 2 {
+			return true
+		}
+	}
+	return false
+}
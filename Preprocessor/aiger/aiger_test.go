@@ -0,0 +1,47 @@
+package aiger
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"strings"
+	"testing"
+)
+
+func TestAsciiAndBinary(t *testing.T) {
+	for _, src := range []string{
+		"aag 3 2 0 1 1\n2\n4\n6\n6 2 4\n",
+		"aig 3 2 0 1 1\n6\n\x02\x02",
+	} {
+		pb, err := ParseAIGER(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", src, err)
+		}
+		if pb.NbVars != 3 || len(pb.Gates) != 1 {
+			t.Errorf("expected 3 vars and 1 gate, got %d vars and %d gates", pb.NbVars, len(pb.Gates))
+		}
+		if pb.Status != Preprocessor.Sat || len(pb.Units) != 3 {
+			t.Errorf("asserting the output should bind all vars, got status %d and units %v", pb.Status, pb.Units)
+		}
+	}
+}
+
+func TestConstantOutput(t *testing.T) {
+	pb, err := ParseAIGER(strings.NewReader("aag 1 1 0 1 0\n2\n0\n"))
+	if err != nil {
+		t.Fatalf("could not parse circuit: %v", err)
+	}
+	if pb.Status != Preprocessor.Unsat {
+		t.Errorf("asserting constant false should be Unsat, got status %d", pb.Status)
+	}
+}
+
+func TestInvalidCircuit(t *testing.T) {
+	for _, src := range []string{
+		"aag 1 1 0 1 0\n2\n4\n",        // output out of bounds
+		"aag 2 1 0 1 1\n2\n4\n5 2 2\n", // negated lhs
+		"p cnf 1 1\n1 0\n",
+	} {
+		if _, err := Read(strings.NewReader(src)); err == nil {
+			t.Errorf("expected an error for %q", src)
+		}
+	}
+}
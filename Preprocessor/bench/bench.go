@@ -0,0 +1,218 @@
+// Package bench runs preprocessing pipelines on a directory of CNF files
+// and reports, for each file and each pipeline, the reduction obtained and the time spent by each pass.
+package bench
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"GiniBench/Tools"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Pass is a named preprocessing technique.
+type Pass = Preprocessor.Pass
+
+// Passes lists the passes that can be used in a pipeline, by name.
+var Passes = map[string]Pass{
+	"equivalences":  {Name: "equivalences", Run: (*Preprocessor.Problem).MergeEquivalences},
+	"selfsub":       {Name: "selfsub", Run: (*Preprocessor.Problem).SelfSub},
+	"selfsubbinary": {Name: "selfsubbinary", Run: (*Preprocessor.Problem).SelfSubBinary},
+	"subsumption":   {Name: "subsumption", Run: (*Preprocessor.Problem).Subsumption},
+	"vivify":        {Name: "vivify", Run: (*Preprocessor.Problem).Vivify},
+	"probe":         {Name: "probe", Run: (*Preprocessor.Problem).Probe},
+	"unhide":        {Name: "unhide", Run: (*Preprocessor.Problem).Unhide},
+	"shrinkbig":     {Name: "shrinkbig", Run: (*Preprocessor.Problem).ShrinkClausesViaBIG},
+	"simplify":      {Name: "simplify", Run: (*Preprocessor.Problem).Simplify2},
+	"applyunits":    {Name: "applyunits", Run: func(pb *Preprocessor.Problem) { pb.ApplyUnits() }},
+}
+
+// A Pipeline is a sequence of passes run one after the other.
+type Pipeline struct {
+	Name   string
+	Passes []Pass
+}
+
+// ParsePipeline returns the pipeline described by a comma-separated list of pass names, e.g "selfsub,subsumption".
+func ParsePipeline(spec string) (Pipeline, error) {
+	pl := Pipeline{Name: spec}
+	for _, name := range strings.Split(spec, ",") {
+		pass, ok := Passes[strings.TrimSpace(name)]
+		if !ok {
+			return Pipeline{}, fmt.Errorf("unknown pass %q in pipeline %q", name, spec)
+		}
+		pl.Passes = append(pl.Passes, pass)
+	}
+	return pl, nil
+}
+
+// PassResult is the state of the problem after a pass was run.
+type PassResult struct {
+	Pass        string
+	Clauses     int
+	Lits        int
+	Time        time.Duration
+	ClauseRatio float64 // Clauses after the pass / clauses before the pipeline.
+	LitRatio    float64 // Lits after the pass / lits before the pipeline.
+}
+
+// A Result is the outcome of a pipeline on a file.
+type Result struct {
+	File     string
+	Pipeline string
+	Vars     int
+	Clauses  int // Clauses before the pipeline, units included.
+	Lits     int // Lits before the pipeline, units included.
+	Passes   []PassResult
+	Status   string
+	Time     time.Duration
+	Err      string `json:",omitempty"`
+}
+
+// A Runner runs every pipeline on every CNF file (.cnf, .cnf.gz, .cnf.bz2) found in Dir and its subdirectories.
+type Runner struct {
+	Dir       string
+	Pipelines []Pipeline
+	Verbose   bool // If false, the passes' logs are discarded while Run runs.
+}
+
+// Run runs the benchmark and returns a result per file and pipeline.
+// Files that cannot be parsed have a result with a non-empty Err.
+func (r *Runner) Run() ([]Result, error) {
+	var files []string
+	for _, pattern := range []string{"*.cnf", "*.cnf.gz", "*.cnf.bz2"} {
+		matches, err := Tools.WalkMatch(r.Dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	if !r.Verbose {
+		prev := log.Writer()
+		log.SetOutput(ioutil.Discard)
+		defer log.SetOutput(prev)
+	}
+	var results []Result
+	for _, file := range files {
+		for _, pl := range r.Pipelines {
+			results = append(results, RunFile(file, pl))
+		}
+	}
+	return results, nil
+}
+
+// RunFile runs the given pipeline on the given file.
+func RunFile(path string, pl Pipeline) Result {
+	res := Result{File: path, Pipeline: pl.Name}
+	pb, err := parse(path)
+	if err != nil {
+		res.Err = err.Error()
+		return res
+	}
+	res.Vars = pb.NbVars
+	res.Clauses, res.Lits = size(pb)
+	start := time.Now()
+	for _, pass := range pl.Passes {
+		passStart := time.Now()
+		pass.Run(pb)
+		pr := PassResult{Pass: pass.Name, Time: time.Since(passStart)}
+		pr.Clauses, pr.Lits = size(pb)
+		pr.ClauseRatio = ratio(pr.Clauses, res.Clauses)
+		pr.LitRatio = ratio(pr.Lits, res.Lits)
+		res.Passes = append(res.Passes, pr)
+	}
+	res.Time = time.Since(start)
+	res.Status = status(pb.Status)
+	return res
+}
+
+func parse(path string) (*Preprocessor.Problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %q: %v", path, err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch filepath.Ext(path) {
+	case ".gz":
+		if r, err = gzip.NewReader(f); err != nil {
+			return nil, fmt.Errorf("could not read gzipped file %q: %v", path, err)
+		}
+	case ".bz2":
+		r = bzip2.NewReader(f)
+	}
+	pb, err := Preprocessor.ParseCNF(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse DIMACS file %q: %v", path, err)
+	}
+	return pb, nil
+}
+
+// size returns the number of clauses and lits of the problem, units included.
+func size(pb *Preprocessor.Problem) (nbClauses, nbLits int) {
+	nbLits = len(pb.Units)
+	for _, c := range pb.Clauses {
+		nbLits += c.Len()
+	}
+	return len(pb.Clauses) + len(pb.Units), nbLits
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 1
+	}
+	return float64(a) / float64(b)
+}
+
+func status(s Preprocessor.Status) string {
+	switch s {
+	case Preprocessor.Sat:
+		return "SAT"
+	case Preprocessor.Unsat:
+		return "UNSAT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// WriteCSV writes the results as CSV, with one line per file, pipeline and pass.
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"File", "Pipeline", "Vars", "Clauses", "Lits", "Pass", "Pass Clauses", "Pass Lits",
+		"Pass Time (us)", "Clause Ratio", "Lit Ratio", "Status", "Total Time (us)", "Error"})
+	for _, res := range results {
+		head := []string{res.File, res.Pipeline, strconv.Itoa(res.Vars), strconv.Itoa(res.Clauses), strconv.Itoa(res.Lits)}
+		tail := []string{res.Status, strconv.FormatInt(res.Time.Microseconds(), 10), res.Err}
+		if len(res.Passes) == 0 {
+			cw.Write(append(append(head, "", "", "", "", "", ""), tail...))
+		}
+		for _, pr := range res.Passes {
+			line := append([]string(nil), head...)
+			line = append(line, pr.Pass, strconv.Itoa(pr.Clauses), strconv.Itoa(pr.Lits),
+				strconv.FormatInt(pr.Time.Microseconds(), 10),
+				strconv.FormatFloat(pr.ClauseRatio, 'f', 6, 64), strconv.FormatFloat(pr.LitRatio, 'f', 6, 64))
+			cw.Write(append(line, tail...))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the results as an indented JSON array.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeCorpus writes a few CNF files in dir: a plain one, a gzipped one in a subdirectory and an invalid one.
+func writeCorpus(t *testing.T, dir string) {
+	files := map[string]string{
+		"a.cnf":   "p cnf 3 3\n1 2 0\n1 2 3 0\n-1 3 0\n",
+		"bad.cnf": "p cnf 2 1\n1 x 0\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("p cnf 3 3\n1 2 0\n-1 2 0\n-2 3 0\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "b.cnf.gz"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeCorpus(t, dir)
+	pl, err := ParsePipeline("simplify,subsumption")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&logs)
+	defer log.SetOutput(prev)
+	r := &Runner{Dir: dir, Pipelines: []Pipeline{pl}}
+	results, err := r.Run()
+	if err != nil {
+		t.Fatalf("could not run benchmark: %v", err)
+	}
+	if log.Writer() != &logs {
+		t.Errorf("output of the logger was not restored")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results); err != nil {
+		t.Fatalf("could not write CSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("could not read CSV: %v", err)
+	}
+	a, bad, b := filepath.Join(dir, "a.cnf"), filepath.Join(dir, "bad.cnf"), filepath.Join(dir, "sub", "b.cnf.gz")
+	// Columns: file, pipeline, vars, clauses, lits, pass, pass clauses, pass lits, and the status after the timings
+	expected := [][]string{
+		{a, "simplify,subsumption", "3", "3", "7", "simplify", "3", "7", "UNKNOWN"},
+		{a, "simplify,subsumption", "3", "3", "7", "subsumption", "2", "4", "UNKNOWN"},
+		{bad, "simplify,subsumption", "0", "0", "0", "", "", "", ""},
+		{b, "simplify,subsumption", "3", "3", "6", "simplify", "3", "6", "UNKNOWN"},
+		{b, "simplify,subsumption", "3", "3", "6", "subsumption", "3", "6", "UNKNOWN"},
+	}
+	if len(rows) != len(expected)+1 {
+		t.Fatalf("expected %d rows and a header, got %d", len(expected), len(rows))
+	}
+	for i, row := range rows[1:] {
+		if got := append(row[:8:8], row[11]); !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("row %d: expected %v, got %v", i+1, expected[i], got)
+		}
+	}
+	if rows[3][13] == "" {
+		t.Errorf("no error for invalid file")
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatalf("could not write JSON: %v", err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("could not read JSON: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, results) {
+		t.Errorf("expected %+v, got %+v", results, decoded)
+	}
+}
@@ -0,0 +1,161 @@
+// Package encodings generates problems of standard families: graph coloring, Schur numbers, pigeonhole,
+// random 3-SAT, and random k-SAT with a planted model. They are small to describe but exercise the passes
+// of the preprocessor in different ways: coloring and pigeonhole problems are full of at-most-one constraints
+// and symmetries, random problems have no structure at all. They are meant as inputs for tests and benchmarks.
+package encodings
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"math/rand"
+)
+
+// lit returns the positive lit of var v.
+func lit(v int) Preprocessor.Lit {
+	return Preprocessor.Var(v).Lit()
+}
+
+// exactlyOne adds clauses stating that exactly one of the given vars is true, with the pairwise encoding.
+func exactlyOne(pb *Preprocessor.Problem, vars []int) {
+	lits := make([]Preprocessor.Lit, len(vars))
+	for i, v := range vars {
+		lits[i] = lit(v)
+	}
+	pb.AddClause(lits)
+	atMostOne(pb, vars)
+}
+
+// atMostOne adds clauses stating that at most one of the given vars is true, with the pairwise encoding.
+func atMostOne(pb *Preprocessor.Problem, vars []int) {
+	for i, v := range vars {
+		for _, w := range vars[i+1:] {
+			pb.AddClause([]Preprocessor.Lit{lit(v).Negation(), lit(w).Negation()})
+		}
+	}
+}
+
+// Coloring returns a problem that is Sat iff the graph with nbNodes nodes and the given edges can be colored
+// with k colors, with no edge between two nodes of the same color.
+// Var n*k+c states that node n has color c.
+func Coloring(nbNodes int, edges [][2]int, k int) *Preprocessor.Problem {
+	pb := Preprocessor.NewProblem(nbNodes * k)
+	colors := make([]int, k)
+	for n := 0; n < nbNodes; n++ {
+		for c := range colors {
+			colors[c] = n*k + c
+		}
+		exactlyOne(pb, colors)
+	}
+	for _, e := range edges {
+		for c := 0; c < k; c++ {
+			pb.AddClause([]Preprocessor.Lit{lit(e[0]*k + c).Negation(), lit(e[1]*k + c).Negation()})
+		}
+	}
+	return pb
+}
+
+// Schur returns a problem that is Sat iff the integers from 1 to n can be colored with k colors
+// without any x, y and x+y of the same color, i.e iff n is smaller than the kth Schur number.
+// Var (i-1)*k+c states that integer i has color c.
+func Schur(n, k int) *Preprocessor.Problem {
+	pb := Preprocessor.NewProblem(n * k)
+	colors := make([]int, k)
+	for i := 1; i <= n; i++ {
+		for c := range colors {
+			colors[c] = (i-1)*k + c
+		}
+		exactlyOne(pb, colors)
+	}
+	for x := 1; x <= n; x++ {
+		for y := x; x+y <= n; y++ {
+			for c := 0; c < k; c++ {
+				lits := []Preprocessor.Lit{lit((x-1)*k + c).Negation(), lit((x+y-1)*k + c).Negation()}
+				if y != x {
+					lits = append(lits, lit((y-1)*k+c).Negation())
+				}
+				pb.AddClause(lits)
+			}
+		}
+	}
+	return pb
+}
+
+// Pigeonhole returns the problem of putting n+1 pigeons in n holes, at most one pigeon per hole, which is Unsat.
+// Var p*n+h states that pigeon p is in hole h.
+func Pigeonhole(n int) *Preprocessor.Problem {
+	pb := Preprocessor.NewProblem((n + 1) * n)
+	for p := 0; p <= n; p++ {
+		lits := make([]Preprocessor.Lit, n)
+		for h := range lits {
+			lits[h] = lit(p*n + h)
+		}
+		pb.AddClause(lits)
+	}
+	pigeons := make([]int, n+1)
+	for h := 0; h < n; h++ {
+		for p := range pigeons {
+			pigeons[p] = p*n + h
+		}
+		atMostOne(pb, pigeons)
+	}
+	return pb
+}
+
+// Random3SAT returns a random problem with nbVars vars and nbClauses clauses of 3 lits of distinct vars,
+// drawn uniformly. The same seed gives the same problem. Problems are the hardest around 4.26 clauses per var.
+// nbVars must be at least 3.
+func Random3SAT(nbVars, nbClauses int, seed int64) *Preprocessor.Problem {
+	rng := rand.New(rand.NewSource(seed))
+	pb := Preprocessor.NewProblem(nbVars)
+	for i := 0; i < nbClauses; i++ {
+		pb.AddClause(randomClause(rng, 3, nbVars))
+	}
+	return pb
+}
+
+// PlantedKSAT returns a random problem with nbVars vars and nbClauses clauses of k lits of distinct vars,
+// satisfied by a random model, which is returned too: model[v] is the value of var v.
+// Clauses are drawn uniformly, and the ones the model does not satisfy are drawn again.
+// The same seed gives the same problem. nbVars must be at least k.
+// Since the problem is known to be Sat, the model rebuilt from a model of the preprocessed problem
+// can be checked against its clauses, whatever the number of clauses per var.
+func PlantedKSAT(k, nbVars, nbClauses int, seed int64) (*Preprocessor.Problem, []bool) {
+	rng := rand.New(rand.NewSource(seed))
+	model := make([]bool, nbVars)
+	for v := range model {
+		model[v] = rng.Intn(2) == 0
+	}
+	pb := Preprocessor.NewProblem(nbVars)
+	for i := 0; i < nbClauses; i++ {
+		for {
+			lits := randomClause(rng, k, nbVars)
+			satisfied := false
+			for _, l := range lits {
+				satisfied = satisfied || model[l.Var()] == l.IsPositive()
+			}
+			if satisfied {
+				pb.AddClause(lits)
+				break
+			}
+		}
+	}
+	return pb, model
+}
+
+// randomClause returns k lits of distinct vars among the first nbVars ones, with random signs.
+func randomClause(rng *rand.Rand, k, nbVars int) []Preprocessor.Lit {
+	lits := make([]Preprocessor.Lit, 0, k)
+	for len(lits) < k {
+		l := lit(rng.Intn(nbVars))
+		if rng.Intn(2) == 0 {
+			l = l.Negation()
+		}
+		distinct := true
+		for _, l2 := range lits {
+			distinct = distinct && l2.Var() != l.Var()
+		}
+		if distinct {
+			lits = append(lits, l)
+		}
+	}
+	return lits
+}
@@ -0,0 +1,103 @@
+package encodings
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"GiniBench/Preprocessor/giniconv"
+	"testing"
+
+	"github.com/jaredsofteng/gini"
+)
+
+// sat returns whether pb is Sat, according to gini.
+func sat(pb *Preprocessor.Problem) bool {
+	g := gini.New()
+	giniconv.ToGini(pb, g)
+	return g.Solve() == 1
+}
+
+func TestFamilies(t *testing.T) {
+	triangle := [][2]int{{0, 1}, {1, 2}, {0, 2}}
+	tests := []struct {
+		name string
+		pb   *Preprocessor.Problem
+		sat  bool
+	}{
+		{"2-coloring of a triangle", Coloring(3, triangle, 2), false},
+		{"3-coloring of a triangle", Coloring(3, triangle, 3), true},
+		{"Schur(4, 2)", Schur(4, 2), true},
+		{"Schur(5, 2)", Schur(5, 2), false},
+		{"Schur(13, 3)", Schur(13, 3), true},
+		{"Schur(14, 3)", Schur(14, 3), false},
+		{"Pigeonhole(1)", Pigeonhole(1), false},
+		{"Pigeonhole(5)", Pigeonhole(5), false},
+		{"Random3SAT(20, 40, 1)", Random3SAT(20, 40, 1), true},
+		{"Random3SAT(20, 200, 1)", Random3SAT(20, 200, 1), false},
+	}
+	for _, test := range tests {
+		if got := sat(test.pb); got != test.sat {
+			t.Errorf("%s: expected Sat %t, got %t", test.name, test.sat, got)
+		}
+		simplified := test.pb.Clone()
+		simplified.Preprocess()
+		if err := Preprocessor.CheckEquisat(test.pb, simplified, giniconv.NewSolver()); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestRandom3SAT(t *testing.T) {
+	pb1, pb2 := Random3SAT(10, 30, 42), Random3SAT(10, 30, 42)
+	if pb1.CNF() != pb2.CNF() {
+		t.Errorf("same seed gave different problems")
+	}
+	for _, c := range pb1.Clauses {
+		if c.Len() != 3 {
+			t.Errorf("expected clauses of 3 distinct vars, got %s", c.CNF())
+		}
+	}
+}
+
+// rebuild returns a model of pb, a Sat preprocessed problem, built from models of its components found by gini.
+func rebuild(t *testing.T, pb *Preprocessor.Problem) []bool {
+	comps := pb.Components()
+	models := make([][]bool, len(comps))
+	for i, comp := range comps {
+		g := gini.New()
+		giniconv.ToGini(comp, g)
+		if g.Solve() != 1 {
+			t.Fatalf("component %d of a Sat problem is Unsat", i)
+		}
+		models[i] = make([]bool, comp.NbVars)
+		for v := range models[i] {
+			models[i][v] = g.Value(giniconv.ToZ(Preprocessor.Var(v).Lit()))
+		}
+	}
+	return pb.CombineModels(comps, models)
+}
+
+func TestPlantedKSAT(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		k := 3 + int(seed%3)
+		pb, planted := PlantedKSAT(k, 30, 200, seed)
+		if !sat(pb) {
+			t.Fatalf("seed %d: planted problem is Unsat", seed)
+		}
+		simplified := pb.Clone()
+		simplified.Preprocess()
+		model := rebuild(t, simplified)
+		if model == nil {
+			t.Fatalf("seed %d: preprocessed problem is Unsat", seed)
+		}
+		for _, m := range [][]bool{planted, model} {
+			for _, c := range pb.Clauses {
+				satisfied := false
+				for i := 0; i < c.Len(); i++ {
+					satisfied = satisfied || m[c.Get(i).Var()] == c.Get(i).IsPositive()
+				}
+				if !satisfied {
+					t.Errorf("seed %d: clause %s not satisfied by model %v", seed, c.CNF(), m)
+				}
+			}
+		}
+	}
+}
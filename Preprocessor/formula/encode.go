@@ -0,0 +1,153 @@
+package formula
+
+import "GiniBench/Preprocessor/Preprocessor"
+
+// TSEITIN AND PLAISTED-GREENBAUM ENCODINGS
+// Each compound subformula gets an auxiliary var, defined as a gate over the lits of its subformulas.
+// The Tseitin transformation adds all the clauses of the definition. The Plaisted-Greenbaum transformation
+// only adds the clauses needed for the polarities the subformula occurs with: if it only occurs positively,
+// the auxiliary var only implies the subformula. This gives fewer clauses, but the auxiliary vars are
+// not functionally defined anymore, so only the subformulas occurring with both polarities are recorded as gates.
+// Negations get no var, and the conjuncts of the asserted formulas are asserted one by one.
+
+// Encode returns the Tseitin encoding of the conjunction of fs, which is satisfiable iff fs are.
+// Var v of the formulas is var v of the problem; auxiliary vars come after them. Units are propagated.
+func Encode(fs ...Formula) *Preprocessor.Problem {
+	return encode(false, fs)
+}
+
+// EncodePG is like Encode, but uses the Plaisted-Greenbaum transformation.
+func EncodePG(fs ...Formula) *Preprocessor.Problem {
+	return encode(true, fs)
+}
+
+// An encoding is the auxiliary var of a subformula, and the polarities it was encoded for.
+type encoding struct {
+	lit      Preprocessor.Lit
+	pos, neg bool
+}
+
+type encoder struct {
+	pb       *Preprocessor.Problem
+	pg       bool                  // Whether the Plaisted-Greenbaum transformation is used.
+	encoded  map[Formula]*encoding // Encodings of the compound subformulas met so far.
+	constant *Preprocessor.Lit     // Lit bound to true, used for the constants. Created when first needed.
+}
+
+func encode(pg bool, fs []Formula) *Preprocessor.Problem {
+	n := 0
+	for _, f := range fs {
+		if m := nbVars(f); m > n {
+			n = m
+		}
+	}
+	e := &encoder{pb: Preprocessor.NewProblem(n), pg: pg, encoded: make(map[Formula]*encoding)}
+	for _, f := range fs {
+		e.assert(f)
+	}
+	e.pb.Simplify2()
+	return e.pb
+}
+
+// assert adds clauses stating that f is true.
+func (e *encoder) assert(f Formula) {
+	switch f := f.(type) {
+	case *and:
+		for _, sub := range f.fs {
+			e.assert(sub)
+		}
+	case *or:
+		lits := make([]Preprocessor.Lit, len(f.fs))
+		for i, sub := range f.fs {
+			lits[i] = e.lit(sub, true, false)
+		}
+		e.pb.AddClause(lits)
+	case Const:
+		if !f {
+			e.pb.AddClause(nil)
+		}
+	default:
+		e.pb.AddClause([]Preprocessor.Lit{e.lit(f, true, false)})
+	}
+}
+
+// lit returns the lit representing f. If pos is true, the lit implies f; if neg is true, f implies the lit.
+func (e *encoder) lit(f Formula, pos, neg bool) Preprocessor.Lit {
+	if !e.pg {
+		pos, neg = true, true
+	}
+	switch f := f.(type) {
+	case Var:
+		return Preprocessor.Var(f).Lit()
+	case Const:
+		if e.constant == nil {
+			lit := e.pb.NewVar().Lit()
+			e.pb.AddClause([]Preprocessor.Lit{lit})
+			e.constant = &lit
+		}
+		if f {
+			return *e.constant
+		}
+		return e.constant.Negation()
+	case *not:
+		return e.lit(f.f, neg, pos).Negation()
+	}
+	enc, ok := e.encoded[f]
+	if !ok {
+		enc = &encoding{lit: e.pb.NewVar().Lit()}
+		e.encoded[f] = enc
+	}
+	// Only the polarities not encoded yet need clauses.
+	pos, neg = pos && !enc.pos, neg && !enc.neg
+	if !pos && !neg {
+		return enc.lit
+	}
+	g := e.gate(f, enc.lit, pos, neg)
+	for _, lits := range g.Clauses() {
+		if (pos && contains(lits, enc.lit.Negation())) || (neg && contains(lits, enc.lit)) {
+			e.pb.AddClause(lits)
+		}
+	}
+	enc.pos, enc.neg = enc.pos || pos, enc.neg || neg
+	if enc.pos && enc.neg {
+		e.pb.Gates = append(e.pb.Gates, g)
+	}
+	return enc.lit
+}
+
+// gate returns the gate defining lit as f, a compound formula.
+// Its inputs are encoded with the polarities needed for the given polarities of f.
+func (e *encoder) gate(f Formula, lit Preprocessor.Lit, pos, neg bool) Preprocessor.Gate {
+	lits := func(fs []Formula, pos, neg bool) []Preprocessor.Lit {
+		res := make([]Preprocessor.Lit, len(fs))
+		for i, sub := range fs {
+			res[i] = e.lit(sub, pos, neg)
+		}
+		return res
+	}
+	switch f := f.(type) {
+	case *and:
+		return Preprocessor.Gate{Kind: Preprocessor.AndGate, Out: lit, In: lits(f.fs, pos, neg)}
+	case *or: // lit <-> a | b is !lit <-> !a & !b
+		in := lits(f.fs, pos, neg)
+		for i := range in {
+			in[i] = in[i].Negation()
+		}
+		return Preprocessor.Gate{Kind: Preprocessor.AndGate, Out: lit.Negation(), In: in}
+	case *iff: // lit <-> (a <-> b) is !lit <-> a xor b
+		return Preprocessor.Gate{Kind: Preprocessor.XorGate, Out: lit.Negation(), In: lits([]Formula{f.f1, f.f2}, true, true)}
+	case *ite:
+		in := append(lits([]Formula{f.cond}, true, true), lits([]Formula{f.then, f.els}, pos, neg)...)
+		return Preprocessor.Gate{Kind: Preprocessor.IteGate, Out: lit, In: in}
+	}
+	panic("unknown formula type")
+}
+
+func contains(lits []Preprocessor.Lit, lit Preprocessor.Lit) bool {
+	for _, l := range lits {
+		if l == lit {
+			return true
+		}
+	}
+	return false
+}
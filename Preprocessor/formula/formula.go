@@ -0,0 +1,139 @@
+// Package formula builds Boolean formulas programmatically and turns them into preprocessor problems,
+// through either the Tseitin or the Plaisted-Greenbaum transformation.
+// The definitions of the auxiliary vars are recorded as gates on the problem, so that gate-aware techniques can use them.
+package formula
+
+// A Formula is a Boolean formula over preprocessor vars. Formulas are built with the functions of this package.
+// Subformulas may be shared: a formula used several times is only encoded once.
+type Formula interface {
+	// Eval returns the value of the formula when each var v is bound to model[v].
+	Eval(model []bool) bool
+}
+
+// A Var is a formula made of a single var. Var(v) is var v of the problem, i.e DIMACS var v+1.
+type Var int
+
+// A Const is one of the constants True and False.
+type Const bool
+
+const (
+	True  = Const(true)
+	False = Const(false)
+)
+
+type not struct{ f Formula }
+
+type and struct{ fs []Formula }
+
+type or struct{ fs []Formula }
+
+type iff struct{ f1, f2 Formula }
+
+type ite struct{ cond, then, els Formula }
+
+// Not returns the negation of f.
+func Not(f Formula) Formula {
+	switch f := f.(type) {
+	case Const:
+		return !f
+	case *not:
+		return f.f
+	}
+	return &not{f}
+}
+
+// And returns the conjunction of fs. It is True if fs is empty.
+func And(fs ...Formula) Formula {
+	switch len(fs) {
+	case 0:
+		return True
+	case 1:
+		return fs[0]
+	}
+	return &and{append([]Formula(nil), fs...)}
+}
+
+// Or returns the disjunction of fs. It is False if fs is empty.
+func Or(fs ...Formula) Formula {
+	switch len(fs) {
+	case 0:
+		return False
+	case 1:
+		return fs[0]
+	}
+	return &or{append([]Formula(nil), fs...)}
+}
+
+// Implies returns f1 -> f2, i.e !f1 | f2.
+func Implies(f1, f2 Formula) Formula {
+	return Or(Not(f1), f2)
+}
+
+// Iff returns f1 <-> f2.
+func Iff(f1, f2 Formula) Formula {
+	return &iff{f1, f2}
+}
+
+// ITE returns if cond then f1 else f2, i.e (cond & f1) | (!cond & f2).
+func ITE(cond, f1, f2 Formula) Formula {
+	return &ite{cond, f1, f2}
+}
+
+func (v Var) Eval(model []bool) bool { return model[v] }
+
+func (c Const) Eval(model []bool) bool { return bool(c) }
+
+func (f *not) Eval(model []bool) bool { return !f.f.Eval(model) }
+
+func (f *and) Eval(model []bool) bool {
+	for _, sub := range f.fs {
+		if !sub.Eval(model) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *or) Eval(model []bool) bool {
+	for _, sub := range f.fs {
+		if sub.Eval(model) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *iff) Eval(model []bool) bool { return f.f1.Eval(model) == f.f2.Eval(model) }
+
+func (f *ite) Eval(model []bool) bool {
+	if f.cond.Eval(model) {
+		return f.then.Eval(model)
+	}
+	return f.els.Eval(model)
+}
+
+// nbVars returns the number of vars needed to hold the vars of f, i.e its highest var plus one.
+func nbVars(f Formula) int {
+	var subs []Formula
+	switch f := f.(type) {
+	case Var:
+		return int(f) + 1
+	case *not:
+		subs = []Formula{f.f}
+	case *and:
+		subs = f.fs
+	case *or:
+		subs = f.fs
+	case *iff:
+		subs = []Formula{f.f1, f.f2}
+	case *ite:
+		subs = []Formula{f.cond, f.then, f.els}
+	}
+	res := 0
+	for _, sub := range subs {
+		if n := nbVars(sub); n > res {
+			res = n
+		}
+	}
+	return res
+}
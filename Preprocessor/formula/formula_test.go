@@ -0,0 +1,115 @@
+package formula
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"math/rand"
+	"testing"
+)
+
+// randFormula returns a random formula over nbVars vars.
+func randFormula(rng *rand.Rand, nbVars, depth int) Formula {
+	if depth == 0 || rng.Intn(4) == 0 {
+		if rng.Intn(10) == 0 {
+			return Const(rng.Intn(2) == 0)
+		}
+		return Var(rng.Intn(nbVars))
+	}
+	sub := func() Formula { return randFormula(rng, nbVars, depth-1) }
+	switch rng.Intn(6) {
+	case 0:
+		return Not(sub())
+	case 1:
+		return And(sub(), sub(), sub())
+	case 2:
+		return Or(sub(), sub())
+	case 3:
+		return Implies(sub(), sub())
+	case 4:
+		return Iff(sub(), sub())
+	default:
+		return ITE(sub(), sub(), sub())
+	}
+}
+
+// projections returns, for each assignment of the first nbVars vars, whether it extends to a model of pb.
+func projections(pb *Preprocessor.Problem, nbVars int) []bool {
+	res := make([]bool, 1<<uint(nbVars))
+	model := make([]bool, pb.NbVars)
+	for a := 0; a < 1<<uint(pb.NbVars); a++ {
+		for v := range model {
+			model[v] = a&(1<<uint(v)) != 0
+		}
+		if satisfies(pb, model) {
+			res[a&(1<<uint(nbVars)-1)] = true
+		}
+	}
+	return res
+}
+
+func satisfies(pb *Preprocessor.Problem, model []bool) bool {
+	if pb.Status == Preprocessor.Unsat {
+		return false
+	}
+	value := func(lit Preprocessor.Lit) bool { return model[lit.Var()] == lit.IsPositive() }
+	for _, lit := range pb.Units {
+		if !value(lit) {
+			return false
+		}
+	}
+	for _, c := range pb.Clauses {
+		sat := false
+		for i := 0; i < c.Len() && !sat; i++ {
+			sat = value(c.Get(i))
+		}
+		if !sat {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEncodings(t *testing.T) {
+	const nbVars = 4
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		f := randFormula(rng, nbVars, 3)
+		g := And(f, Or(Var(0), Var(1), Var(2), Var(3))) // So that the formulas use all vars.
+		for _, pb := range []*Preprocessor.Problem{Encode(g), EncodePG(g)} {
+			if pb.NbVars > 16 {
+				continue
+			}
+			models := projections(pb, nbVars)
+			for a, ok := range models {
+				model := make([]bool, nbVars)
+				for v := range model {
+					model[v] = a&(1<<uint(v)) != 0
+				}
+				if g.Eval(model) != ok {
+					t.Fatalf("encoding of formula #%d is wrong for assignment %v", i, model)
+				}
+			}
+		}
+	}
+}
+
+func TestGates(t *testing.T) {
+	x, y, z := Var(0), Var(1), Var(2)
+	f := Or(And(x, y), Iff(y, z), ITE(x, y, z))
+	pb := Encode(f)
+	if len(pb.Gates) != 3 {
+		t.Errorf("expected 3 gates, got %v", pb.Gates)
+	}
+	for _, g := range pb.Gates {
+		if d, ok := pb.Definition(g.Out.Var()); !ok || d.Kind != g.Kind {
+			t.Errorf("gate %v is not the definition of its output", g)
+		}
+	}
+	// With Plaisted-Greenbaum, subformulas occurring positively only are not defined.
+	if pb := EncodePG(f); len(pb.Gates) != 0 {
+		t.Errorf("expected no gates, got %v", pb.Gates)
+	}
+	// The condition of ITE occurs with both polarities.
+	if pb := EncodePG(ITE(Or(x, y), y, z)); len(pb.Gates) != 1 {
+		t.Errorf("expected 1 gate, got %v", pb.Gates)
+	}
+}
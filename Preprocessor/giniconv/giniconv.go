@@ -0,0 +1,125 @@
+// Package giniconv converts preprocessor problems to and from the gini solver,
+// so that gini users do not need to write a DIMACS file as an intermediate step.
+package giniconv
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+
+	"github.com/jaredsofteng/gini"
+	"github.com/jaredsofteng/gini/inter"
+	"github.com/jaredsofteng/gini/z"
+)
+
+// ToZ returns the gini lit equivalent to lit.
+func ToZ(lit Preprocessor.Lit) z.Lit {
+	return z.Dimacs2Lit(int(lit.Int()))
+}
+
+// FromZ returns the preprocessor lit equivalent to m.
+func FromZ(m z.Lit) Preprocessor.Lit {
+	return Preprocessor.IntToLit(Preprocessor.LitInt(m.Dimacs()))
+}
+
+// ToGini adds the units and the clauses of pb to dst, typically a *gini.Gini.
+// If pb is Unsat, the empty clause is added.
+func ToGini(pb *Preprocessor.Problem, dst inter.Adder) {
+	if pb.Status == Preprocessor.Unsat {
+		dst.Add(z.LitNull)
+		return
+	}
+	for _, lit := range pb.Units {
+		dst.Add(ToZ(lit))
+		dst.Add(z.LitNull)
+	}
+	for _, c := range pb.Clauses {
+		for i := 0; i < c.Len(); i++ {
+			dst.Add(ToZ(c.Get(i)))
+		}
+		dst.Add(z.LitNull)
+	}
+}
+
+// FromGini returns a problem made of the clauses added to g so far. Learnt clauses are ignored.
+// gini removes the lits that were already false when a clause was added, so the problem is equisatisfiable,
+// but not identical, to the added clauses.
+func FromGini(g *gini.Gini) *Preprocessor.Problem {
+	b := NewBuilder()
+	cdb := g.ClauseDB()
+	var ms []z.Lit
+	for _, p := range cdb.Added {
+		ms = cdb.Lits(p, ms[:0])
+		for _, m := range ms {
+			b.Add(m)
+		}
+		b.Add(z.LitNull)
+	}
+	return b.Problem()
+}
+
+// A Builder builds a problem through gini's clause-adding interface, inter.Adder:
+// code feeding clauses to a gini solver can feed them to the preprocessor instead.
+type Builder struct {
+	nbVars  int
+	clauses [][]Preprocessor.Lit
+	lits    []Preprocessor.Lit // Lits of the clause being added.
+}
+
+// NewBuilder returns a builder for an empty problem.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Add adds m to the clause being added, or ends it if m is z.LitNull.
+func (b *Builder) Add(m z.Lit) {
+	if m == z.LitNull {
+		b.clauses = append(b.clauses, b.lits)
+		b.lits = nil
+		return
+	}
+	if v := int(m.Var()); v > b.nbVars {
+		b.nbVars = v
+	}
+	b.lits = append(b.lits, FromZ(m))
+}
+
+// Problem returns the problem made of the clauses added so far, with units propagated as ParseCNF does.
+// The clause being added, if any, is ignored.
+func (b *Builder) Problem() *Preprocessor.Problem {
+	pb := Preprocessor.NewProblem(b.nbVars)
+	for _, lits := range b.clauses {
+		pb.AddClause(lits)
+	}
+	pb.Simplify2()
+	return pb
+}
+
+// A Solver is a gini solver usable by the preprocessor, e.g to compute backbones.
+type Solver struct {
+	g *gini.Gini
+}
+
+// NewSolver returns a solver without any clause.
+func NewSolver() *Solver {
+	return &Solver{g: gini.New()}
+}
+
+// Add adds a clause to the solver.
+func (s *Solver) Add(lits []Preprocessor.Lit) {
+	for _, lit := range lits {
+		s.g.Add(ToZ(lit))
+	}
+	s.g.Add(z.LitNull)
+}
+
+// Solve returns whether the clauses added so far are satisfiable under the given assumptions.
+func (s *Solver) Solve(assumptions []Preprocessor.Lit) bool {
+	for _, lit := range assumptions {
+		s.g.Assume(ToZ(lit))
+	}
+	return s.g.Solve() == 1
+}
+
+// Value returns the value of lit in the model found by the last call to Solve.
+func (s *Solver) Value(lit Preprocessor.Lit) bool {
+	return s.g.Value(ToZ(lit))
+}
@@ -0,0 +1,109 @@
+package giniconv
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jaredsofteng/gini"
+	"github.com/jaredsofteng/gini/z"
+)
+
+func TestLits(t *testing.T) {
+	for i := 1; i < 100; i++ {
+		for _, d := range []int{i, -i} {
+			lit := Preprocessor.IntToLit(Preprocessor.LitInt(d))
+			if m := ToZ(lit); m.Dimacs() != d || FromZ(m) != lit {
+				t.Errorf("lit %d converted to %d and back to %d", d, m.Dimacs(), FromZ(m).Int())
+			}
+		}
+	}
+}
+
+func TestToGini(t *testing.T) {
+	for _, test := range []struct {
+		cnf string
+		res int
+	}{
+		{"p cnf 3 3\n1 2 0\n-1 3 0\n-3 0\n", 1},
+		{"p cnf 2 4\n1 2 0\n-1 2 0\n1 -2 0\n-1 -2 0\n", -1},
+		{"p cnf 1 2\n1 0\n-1 0\n", -1},
+	} {
+		pb, err := Preprocessor.ParseCNF(strings.NewReader(test.cnf))
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", test.cnf, err)
+		}
+		g := gini.New()
+		ToGini(pb, g)
+		if res := g.Solve(); res != test.res {
+			t.Errorf("expected %d for %q, got %d", test.res, test.cnf, res)
+		}
+	}
+}
+
+func TestFromGini(t *testing.T) {
+	g := gini.New()
+	for _, c := range [][]int{{1, 2, 3}, {-1, 2}, {-2, -3}, {3}} {
+		for _, d := range c {
+			g.Add(z.Dimacs2Lit(d))
+		}
+		g.Add(z.LitNull)
+	}
+	pb := FromGini(g)
+	if pb.NbVars != 3 {
+		t.Errorf("expected 3 vars, got %d", pb.NbVars)
+	}
+	// 3 is a unit, so -2 and -1 follow.
+	if pb.Status != Preprocessor.Sat || len(pb.Units) != 3 {
+		t.Errorf("expected the problem to be solved by propagation, got status %d and units %v", pb.Status, pb.Units)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder()
+	for _, c := range [][]int{{1, -2}, {2, 3, -4}, {}} {
+		for _, d := range c {
+			b.Add(z.Dimacs2Lit(d))
+		}
+		b.Add(z.LitNull)
+	}
+	if pb := b.Problem(); pb.Status != Preprocessor.Unsat {
+		t.Errorf("expected the empty clause to make the problem Unsat, got status %d", pb.Status)
+	}
+}
+
+func TestBackbones(t *testing.T) {
+	// 1 is implied by the first 4 clauses, but not by propagation; -5 follows by propagation.
+	cnf := "p cnf 5 5\n1 2 3 0\n1 2 -3 0\n1 -2 3 0\n1 -2 -3 0\n-1 -5 0\n"
+	pb, err := Preprocessor.ParseCNF(strings.NewReader(cnf))
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", cnf, err)
+	}
+	pb.Backbones(NewSolver())
+	units := make(map[Preprocessor.LitInt]bool)
+	for _, lit := range pb.Units {
+		units[lit.Int()] = true
+	}
+	if len(units) != 2 || !units[1] || !units[-5] {
+		t.Errorf("expected backbone {1, -5}, got %v", pb.Units)
+	}
+}
+
+func TestCheckEquisat(t *testing.T) {
+	cnf := "p cnf 5 6\n1 2 0\n-1 3 0\n-2 3 0\n-3 4 5 0\n-4 -5 0\n2 -5 0\n"
+	original, err := Preprocessor.ParseCNF(strings.NewReader(cnf))
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", cnf, err)
+	}
+	simplified := original.Clone()
+	simplified.EliminateSingles()
+	if err := Preprocessor.CheckEquisat(original, simplified, NewSolver()); err != nil {
+		t.Errorf("expected the simplification to be sound, got %v", err)
+	}
+	broken := original.Clone()
+	broken.AddClause([]Preprocessor.Lit{Preprocessor.IntToLit(-3)})
+	if err := Preprocessor.CheckEquisat(original, broken, NewSolver()); !errors.Is(err, Preprocessor.ErrNotEquisat) {
+		t.Errorf("expected ErrNotEquisat when a unit made the problem Unsat, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package httpapi serves the preprocessor over HTTP, so that it can be deployed as a service in solving clusters:
+// clients POST a CNF file, possibly gzipped, and get back the simplified CNF along with statistics.
+// The response is a multipart/mixed message: a JSON part holding the Stats, then a part holding the simplified CNF.
+package httpapi
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+)
+
+// A Handler preprocesses the CNF files posted to it. It is safe for concurrent use.
+type Handler struct {
+	Passes    []Preprocessor.Pass  // Passes run until fixpoint, see Problem.Fixpoint. Preprocessor.DefaultPasses if empty.
+	TimeLimit time.Duration        // Max time spent preprocessing a problem, checked between passes. 0 means no limit.
+	MaxBytes  int64                // Max size of the uploaded CNF, once decompressed. 0 means no limit.
+	Options   Preprocessor.Options // Options of every problem. Their writers, e.g DeltaWriter, must be nil.
+}
+
+// Stats describes a preprocessed problem.
+type Stats struct {
+	Vars      int
+	Clauses   int // Clauses before preprocessing, units included.
+	Lits      int // Lits before preprocessing, units included.
+	Status    string
+	Rounds    []Preprocessor.RoundStats
+	Stopped   bool // If true, preprocessing stopped before reaching a fixpoint, e.g because of the time limit.
+	ParseTime time.Duration
+	Time      time.Duration
+}
+
+// ServeHTTP reads the CNF file in the body of a POST request, preprocesses it and writes the response.
+// Gzipped bodies are recognized by their magic number, whatever their Content-Encoding.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "CNF files must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	start := time.Now()
+	body, err := decompress(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not read gzipped body: %v", err), http.StatusBadRequest)
+		return
+	}
+	lr := &limitedReader{r: body, limit: h.MaxBytes}
+	opts := h.Options
+	opts.FixpointTime = h.TimeLimit
+	pb, err := Preprocessor.ParseCNFWithOptions(lr, opts)
+	switch {
+	case lr.exceeded:
+		http.Error(w, fmt.Sprintf("CNF file is larger than %d bytes", h.MaxBytes), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, Preprocessor.ErrBadInput):
+		http.Error(w, fmt.Sprintf("could not parse CNF file: %v", err), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("could not read CNF file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	stats := Stats{Vars: pb.NbVars, ParseTime: time.Since(start)}
+	stats.Clauses, stats.Lits = size(pb)
+	stats.Rounds = pb.Fixpoint(h.Passes...)
+	stats.Stopped = pb.Status == Preprocessor.Undetermined && pb.Dirty()
+	stats.Status = status(pb.Status)
+	stats.Time = time.Since(start)
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	if err := writeParts(mw, pb, &stats); err != nil {
+		panic(http.ErrAbortHandler) // Headers were sent already
+	}
+}
+
+// writeParts writes the stats and the simplified CNF as parts of the response.
+func writeParts(mw *multipart.Writer, pb *Preprocessor.Problem, stats *Stats) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(part).Encode(stats); err != nil {
+		return err
+	}
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        {"text/plain"},
+		"Content-Disposition": {`attachment; filename="Simplified.cnf"`},
+	})
+	if err != nil {
+		return err
+	}
+	if err := pb.WriteCNF(part); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// decompress returns a reader of the decompressed body if it is gzipped, or of the body itself otherwise.
+func decompress(body io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(body)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// A limitedReader reads at most limit bytes from r, or everything if limit is 0, and records whether r had more.
+type limitedReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.limit > 0 && int64(len(p)) > lr.limit-lr.read+1 {
+		p = p[:lr.limit-lr.read+1] // One more byte tells whether the limit is exceeded
+	}
+	n, err := lr.r.Read(p)
+	lr.read += int64(n)
+	if lr.limit > 0 && lr.read > lr.limit {
+		lr.exceeded = true
+		return 0, errors.New("CNF file is too large")
+	}
+	return n, err
+}
+
+// size returns the number of clauses and lits of the problem, units included.
+func size(pb *Preprocessor.Problem) (nbClauses, nbLits int) {
+	nbLits = len(pb.Units)
+	for _, c := range pb.Clauses {
+		nbLits += c.Len()
+	}
+	return len(pb.Clauses) + len(pb.Units), nbLits
+}
+
+func status(s Preprocessor.Status) string {
+	switch s {
+	case Preprocessor.Sat:
+		return "SAT"
+	case Preprocessor.Unsat:
+		return "UNSAT"
+	default:
+		return "UNKNOWN"
+	}
+}
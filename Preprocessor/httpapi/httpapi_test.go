@@ -0,0 +1,78 @@
+package httpapi
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const cnf = "p cnf 4 5\n1 2 0\n-1 2 0\n-2 3 4 0\n-3 4 0\n1 -4 3 0\n"
+
+// post posts body to h and returns the response.
+func post(h http.Handler, body []byte) *http.Response {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	return rec.Result()
+}
+
+func TestGzippedUpload(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(cnf))
+	zw.Close()
+	resp := post(&Handler{}, buf.Bytes())
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected a multipart/mixed response, got %q", resp.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("could not read stats: %v", err)
+	}
+	var stats Stats
+	if err := json.NewDecoder(part).Decode(&stats); err != nil {
+		t.Fatalf("could not decode stats: %v", err)
+	}
+	if stats.Vars != 4 || stats.Clauses != 5 || len(stats.Rounds) == 0 {
+		t.Errorf("expected 4 vars, 5 clauses and some rounds, got %+v", stats)
+	}
+	if part, err = mr.NextPart(); err != nil {
+		t.Fatalf("could not read simplified CNF: %v", err)
+	}
+	simplified, _ := ioutil.ReadAll(part)
+	if _, err := Preprocessor.ParseCNF(bytes.NewReader(simplified)); err != nil {
+		t.Errorf("could not parse simplified CNF %q: %v", simplified, err)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	for _, tc := range []struct {
+		h      *Handler
+		body   string
+		status int
+	}{
+		{&Handler{}, "p cnf 2 1\n1 x 0\n", http.StatusBadRequest},
+		{&Handler{MaxBytes: 10}, cnf, http.StatusRequestEntityTooLarge},
+	} {
+		if resp := post(tc.h, []byte(tc.body)); resp.StatusCode != tc.status {
+			t.Errorf("expected status %d for %q, got %d", tc.status, tc.body, resp.StatusCode)
+		}
+	}
+	rec := httptest.NewRecorder()
+	(&Handler{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", strings.NewReader(cnf)))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", rec.Code)
+	}
+}
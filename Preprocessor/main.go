@@ -1,76 +1,457 @@
 package main
 
 import (
+	"bufio"
 	"GiniBench/Preprocessor/Preprocessor"
+	"GiniBench/Preprocessor/aiger"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the command and returns its exit code, once the logs are flushed.
+// Exit codes are those of the SAT competition: 10 if SAT, 20 if UNSAT, 0 if undecided, 1 on errors.
+func run() int {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		return diff(os.Args[2:])
+	}
 	var (
-		help    bool
+		help     bool
+		fixpoint bool
+		maxMem   int
+		stream   bool
+		mapped   bool
+		onDisk   bool
+		workers  int
+		patterns bool
+		delta    string
+		events   string
+		elimMap  string
+		phases   string
+		extStack string
+		comments bool
+		noUnits  bool
+		card     string
+		cardOnly bool
+		hash     bool
+		cacheDir string
+		dryRun   bool
+		trace    string
 	)
 	flag.BoolVar(&help, "help", false, "displays help")
+	flag.BoolVar(&fixpoint, "fixpoint", false, "repeats pre-processing until the formula does not change anymore")
+	flag.IntVar(&maxMem, "maxmem", 0, "max memory used by pre-processing, in MB (0 means no limit)")
+	flag.BoolVar(&stream, "stream", false, "propagates units while parsing CNF files, so that huge files use less memory")
+	flag.BoolVar(&mapped, "mmap", false, "keeps the clauses of CNF files in a memory-mapped temporary file, for files larger than RAM")
+	flag.BoolVar(&onDisk, "ondisk", false, "only propagates units and removes duplicate clauses of CNF files, through temporary files, for files that do not fit in RAM at all")
+	flag.IntVar(&workers, "workers", 1, "pre-processes independent parts of the formula in parallel, with up to this number of workers")
+	flag.BoolVar(&patterns, "patterns", false, "looks for known UNSAT families, e.g pigeonhole, before pre-processing")
+	flag.StringVar(&delta, "delta", "", "logs every clause added, strengthened or deleted by pre-processing to the given file")
+	flag.StringVar(&events, "events", "", "writes the passes and rounds of pre-processing to the given file, as JSON lines")
+	flag.BoolVar(&comments, "comments", false, "keeps the comments at the beginning of CNF files in the simplified CNF")
+	flag.BoolVar(&noUnits, "nounits", false, "propagates units and leaves them out of the simplified CNF, for tools that reject unit clauses")
+	flag.StringVar(&card, "card", "", "also writes the simplified problem with the cardinality constraints found, in the given format (knf or opb), to Simplified.knf or Simplified.opb")
+	flag.BoolVar(&cardOnly, "cardonly", false, "with -card, writes the cardinality constraints found instead of the clauses encoding them")
+	flag.StringVar(&elimMap, "elimmap", "", "writes the elimination map needed to extend models of the simplified CNF to the given file")
+	flag.StringVar(&extStack, "extension", "", "writes the extension stack needed to extend models of the simplified CNF to the given file, in the format of CaDiCaL")
+	flag.StringVar(&cacheDir, "cache", "", "stores pre-processed formulas in the given directory, and reuses them when the same formula is pre-processed again")
+	flag.BoolVar(&dryRun, "dryrun", false, "only reports what each pass would remove or strengthen, and leaves the formula unchanged")
+	flag.StringVar(&trace, "trace", "", "writes the resolution steps of pre-processing to the given file, as a DOT graph if it ends with .dot, in the TraceCheck format otherwise")
+	flag.BoolVar(&hash, "fingerprint", false, "prints the fingerprint of the formula before and after pre-processing, to key caches of results")
+	flag.StringVar(&phases, "phases", "", "writes the suggested initial phase of the vars of the simplified CNF to the given file")
 	flag.Parse()
 	if !help && len(flag.Args()) != 1 {
 		fmt.Printf("This is GoPreProcessor. Functions taken from Gophersat. Modifications/additions by Michael Behr.\n")
-		fmt.Fprintf(os.Stderr, "Syntax : %s [options] (file.cnf|file.wcnf|file.bf|file.opb)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Syntax : %s [options] (file.cnf|file.icnf|file.gcnf|file.aag|file.aig)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff a.cnf b.cnf\n", os.Args[0])
 		flag.PrintDefaults()
-		os.Exit(1)
+		return 1
 	}
 	if help {
 		fmt.Printf("This is GoPreProcessor version 1.0, a SAT pre-processor by Michael Behr and Jared Lenos.\n")
-		fmt.Printf("Syntax : %s [options] (file.cnf|file.wcnf|file.bf|file.opb)\n", os.Args[0])
+		fmt.Printf("Syntax : %s [options] (file.cnf|file.icnf|file.gcnf|file.aag|file.aig)\n", os.Args[0])
+		fmt.Printf("       %s diff a.cnf b.cnf\n", os.Args[0])
 		flag.PrintDefaults()
-		os.Exit(0)
+		return 0
 	}
 	path := flag.Args()[0]
 	fmt.Printf("c solving %s\n", path)
-	if strings.HasSuffix(path, ".cnf") {
-		if pb, err := parse(flag.Args()[0]); err != nil {
+	exitCode := 0
+	if onDisk && strings.HasSuffix(path, ".cnf") {
+		return rewriteOnDisk(path, maxMem, comments)
+	}
+	if strings.HasSuffix(path, ".cnf") || strings.HasSuffix(path, ".icnf") || strings.HasSuffix(path, ".gcnf") || strings.HasSuffix(path, ".aag") || strings.HasSuffix(path, ".aig") {
+		if pb, cubes, err := parse(flag.Args()[0], stream, mapped, Preprocessor.Options{KeepComments: comments, Provenance: trace != ""}); err != nil {
 			fmt.Fprintf(os.Stderr, "could not parse problem: %v\n", err)
-			os.Exit(1)
+			return 1
 		} else {
 			//fmt.Printf("\nCNF FORMULA:\n\n",pb.CNF())
 			// run pre-processing
-			pb.Preprocess()
+			pb.Options.MaxMemoryMB = maxMem
+			pb.Options.DetectPatterns = patterns
+			pb.Options.OmitUnits = noUnits
+			pb.Options.ReplaceCardinalities = cardOnly
+			pb.Options.CacheDir = cacheDir
+			pb.Options.DryRun = dryRun
+			if hash {
+				fmt.Printf("c input fingerprint: %x\n", pb.Fingerprint())
+			}
+			if delta != "" {
+				deltaFile, err := os.Create(delta)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "could not create delta log: %v\n", err)
+					return 1
+				}
+				defer deltaFile.Close()
+				w := bufio.NewWriter(deltaFile)
+				defer w.Flush()
+				pb.Options.DeltaWriter = w
+			}
+			if events != "" {
+				eventFile, err := os.Create(events)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "could not create event log: %v\n", err)
+					return 1
+				}
+				defer eventFile.Close()
+				w := bufio.NewWriter(eventFile)
+				defer w.Flush()
+				pb.Options.EventWriter = w
+			}
+			if workers > 1 {
+				pb.PreprocessParallel(workers)
+			} else if fixpoint {
+				pb.Fixpoint()
+			} else if res := pb.Preprocess(); res.Termination == Preprocessor.MemoryExhausted {
+				fmt.Println("c pre-processing stopped early: memory budget exhausted")
+			} else if res.Cached {
+				fmt.Println("c pre-processed formula read from cache")
+			}
+			for _, r := range pb.DryRunReports() {
+				fmt.Printf("c dry run of %s: %d clauses removed, %d added, %d strengthened, %d new units, %d vars eliminated\n",
+					r.Pass, r.Removed, r.Added, r.Strengthened, r.NewUnits, r.Eliminated)
+			}
+			if hash {
+				fmt.Printf("c simplified fingerprint: %x\n", pb.Fingerprint())
+			}
+			// Tractable problems are decided on a copy, since the simplified problem must keep all its models
+			decided := pb.Clone()
+			decided.SolveFragment()
+			switch decided.Status {
+			case Preprocessor.Sat:
+				fmt.Println("s SATISFIABLE")
+				decided.WriteModel(os.Stdout)
+				exitCode = 10
+			case Preprocessor.Unsat:
+				fmt.Println("s UNSATISFIABLE")
+				exitCode = 20
+			default:
+				fmt.Println("s UNKNOWN")
+			}
 			//fmt.Printf("Done. %d clauses now", len(pb.Clauses))
 			//fmt.Printf("\nSIMPLIFIED FORMULA,:\n\n",pb.CNF())
 			// write to file
-			file,err := os.Create("Simplified.cnf")
-			if err!= nil{
-				fmt.Println(err)
-				return
+			if strings.HasSuffix(path, ".icnf") {
+				if err := writeICNF(pb, cubes, "Simplified.icnf"); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write simplified iCNF: %v\n", err)
+					return 1
+				}
+				fmt.Println("c iCNF file created successfully!")
+			} else if strings.HasSuffix(path, ".gcnf") {
+				if err := writeGCNF(pb, "Simplified.gcnf"); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write simplified GCNF: %v\n", err)
+					return 1
+				}
+				fmt.Println("c GCNF file created successfully!")
+			} else {
+				file,err := os.Create("Simplified.cnf")
+				if err!= nil{
+					fmt.Fprintf(os.Stderr, "could not create simplified CNF: %v\n", err)
+					return 1
+				}
+				l,err := file.WriteString(pb.CNF())
+				if err2 := file.Close(); err == nil {
+					err = err2
+				}
+				if err!=nil{
+					fmt.Fprintf(os.Stderr, "could not write simplified CNF: %v\n", err)
+					return 1
+				}
+				fmt.Println("c", l, "CNF file created successfully!")
+			}
+			if card != "" {
+				if err := writeCardinalities(pb, card); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write cardinality constraints: %v\n", err)
+					return 1
+				}
+			}
+			if elimMap != "" {
+				if err := writeElimMap(pb, elimMap); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write elimination map: %v\n", err)
+					return 1
+				}
 			}
-			l,err := file.WriteString(pb.CNF())
-			if err!=nil{
-				fmt.Println(err)
-				file.Close()
-				return
+			if extStack != "" {
+				if err := writeExtensionStack(pb, extStack); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write extension stack: %v\n", err)
+					return 1
+				}
+			}
+			if phases != "" {
+				if err := writePhases(pb, phases); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write phase file: %v\n", err)
+					return 1
+				}
+			}
+			if trace != "" {
+				if err := writeTrace(pb, trace); err != nil {
+					fmt.Fprintf(os.Stderr, "could not write resolution trace: %v\n", err)
+					return 1
+				}
 			}
-			fmt.Println(l,"CNF file created successfully!")
-			file.Close()
 		}
 	} else{
-		fmt.Fprintf(os.Stderr, "Could not parse problem. Make sure it is in CNF form.")
+		fmt.Fprintf(os.Stderr, "Could not parse problem. Make sure it is in CNF or AIGER form.")
+		exitCode = 1
+	}
+	return exitCode
+}
+func writeElimMap(pb *Preprocessor.Problem, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pb.WriteElimMap(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeExtensionStack(pb *Preprocessor.Problem, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pb.WriteExtensionStack(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writePhases(pb *Preprocessor.Problem, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pb.WritePhases(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeCardinalities detects the cardinality constraints of the problem, and writes it with them
+// to Simplified.knf or Simplified.opb, depending on format.
+func writeCardinalities(pb *Preprocessor.Problem, format string) error {
+	write := pb.WriteKNF
+	switch format {
+	case "knf":
+	case "opb":
+		write = pb.WriteOPB
+	default:
+		return fmt.Errorf("unknown format %q, expected knf or opb", format)
 	}
+	pb.DetectCardinalities()
+	f, err := os.Create("Simplified." + format)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
+// writeICNF writes the problem and its cubes to the iCNF file at path.
+func writeICNF(pb *Preprocessor.Problem, cubes [][]Preprocessor.Lit, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pb.WriteICNF(f, cubes); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
-func parse(path string) (pb *Preprocessor.Problem, err error) {
+
+// writeGCNF writes the problem to the GCNF file at path.
+func writeGCNF(pb *Preprocessor.Problem, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pb.WriteGCNF(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// diff runs the diff subcommand on its args, i.e two files, and returns the exit status: as with diff(1),
+// 0 if both formulas have the same clauses once normalized, 1 if they differ, 2 on errors.
+// Clauses only in the first file are printed as "- lits 0", clauses only in the second one as "+ lits 0",
+// and clauses of the first file replaced by a subset of them in the second one as "~ lits 0 => lits 0".
+func diff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Syntax : %s diff a.cnf b.cnf\n", os.Args[0])
+		return 2
+	}
+	var pbs [2]*Preprocessor.Problem
+	for i, path := range args {
+		pb, _, err := parse(path, false, false, Preprocessor.Options{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not parse problem: %v\n", err)
+			return 2
+		}
+		pbs[i] = pb
+	}
+	d := Preprocessor.Diff(pbs[0], pbs[1])
+	dimacs := func(lits []Preprocessor.Lit) string {
+		var sb strings.Builder
+		for _, lit := range lits {
+			fmt.Fprintf(&sb, "%d ", lit.Int())
+		}
+		sb.WriteString("0")
+		return sb.String()
+	}
+	w := bufio.NewWriter(os.Stdout)
+	for _, lits := range d.Removed {
+		fmt.Fprintf(w, "- %s\n", dimacs(lits))
+	}
+	for _, lits := range d.Added {
+		fmt.Fprintf(w, "+ %s\n", dimacs(lits))
+	}
+	for _, s := range d.Strengthened {
+		fmt.Fprintf(w, "~ %s => %s\n", dimacs(s.From), dimacs(s.To))
+	}
+	fmt.Fprintf(w, "c %d removed, %d added, %d strengthened\n", len(d.Removed), len(d.Added), len(d.Strengthened))
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write diff: %v\n", err)
+		return 2
+	}
+	if d.Empty() {
+		return 0
+	}
+	return 1
+}
+
+// writeTrace writes the resolution steps of pre-processing to path, as a DOT graph if it ends with .dot,
+// in the TraceCheck format otherwise.
+func writeTrace(pb *Preprocessor.Problem, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := pb.WriteTraceCheck
+	if strings.HasSuffix(path, ".dot") {
+		write = pb.WriteResolutionDOT
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewriteOnDisk rewrites the CNF file at path to Simplified.cnf with RewriteCNFOnDisk, using at most maxMem MB
+// for sorting clauses, and returns the exit code.
+func rewriteOnDisk(path string, maxMem int, comments bool) int {
+	in, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not open %q: %v\n", path, err)
+		return 1
+	}
+	defer in.Close()
+	out, err := os.Create("Simplified.cnf")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not create simplified CNF: %v\n", err)
+		return 1
+	}
+	report, err := Preprocessor.RewriteCNFOnDisk(in, out, Preprocessor.DiskOptions{MaxMemoryMB: maxMem, KeepComments: comments})
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not rewrite %q: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("c %d units found in %d rounds, %d duplicate clauses removed\n", report.NbUnits, report.Rounds, report.Duplicates)
+	fmt.Printf("c %d clauses CNF file created successfully!\n", report.NbClauses)
+	switch report.Status {
+	case Preprocessor.Sat:
+		fmt.Println("s SATISFIABLE")
+		return 10
+	case Preprocessor.Unsat:
+		fmt.Println("s UNSATISFIABLE")
+		return 20
+	}
+	fmt.Println("s UNKNOWN")
+	return 0
+}
+
+// parse parses the problem at path. The cubes of iCNF files are returned with it.
+func parse(path string, stream, mapped bool, opts Preprocessor.Options) (pb *Preprocessor.Problem, cubes [][]Preprocessor.Lit, err error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("could not open %q: %v", path, err)
+		return nil, nil, fmt.Errorf("could not open %q: %v", path, err)
 	}
 	defer f.Close()
 	if strings.HasSuffix(path, ".cnf") {
-		pb, err := Preprocessor.ParseCNF(f)
+		parseCNF := Preprocessor.ParseCNFWithOptions
+		if stream {
+			parseCNF = Preprocessor.ParseCNFStreaming
+		} else if mapped {
+			parseCNF = func(f io.Reader, opts Preprocessor.Options) (*Preprocessor.Problem, error) {
+				return Preprocessor.ParseCNFMapped(f, "", opts)
+			}
+		}
+		pb, err := parseCNF(f, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse DIMACS file %q: %v", path, err)
+		}
+		return pb, nil, nil
+	}
+	if strings.HasSuffix(path, ".icnf") {
+		pb, cubes, err := Preprocessor.ParseICNF(f, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse iCNF file %q: %v", path, err)
+		}
+		return pb, cubes, nil
+	}
+	if strings.HasSuffix(path, ".gcnf") {
+		pb, err := Preprocessor.ParseGCNF(f, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse GCNF file %q: %v", path, err)
+		}
+		return pb, nil, nil
+	}
+	if strings.HasSuffix(path, ".aag") || strings.HasSuffix(path, ".aig") {
+		pb, err := aiger.ParseAIGER(f)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse DIMACS file %q: %v", path, err)
+			return nil, nil, fmt.Errorf("could not parse AIGER file %q: %v", path, err)
 		}
-		return pb,nil
+		return pb, nil, nil
 	}
-	return nil, fmt.Errorf("invalid file format for %q", path)
+	return nil, nil, fmt.Errorf("invalid file format for %q", path)
 }
\ No newline at end of file
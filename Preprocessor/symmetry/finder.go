@@ -0,0 +1,73 @@
+package symmetry
+
+import "strconv"
+
+// A SwapFinder is the embedded Finder. It only looks for symmetries swapping two vars, possibly negating them.
+// Lits are first partitioned by color refinement, and the first positive lit of each class is swapped
+// with each other lit of the class. Interchangeable vars are found this way, but symmetries moving many vars at once,
+// e.g swapping two pigeons of a pigeonhole problem, need a complete engine.
+type SwapFinder struct{}
+
+// Automorphisms returns the candidate swaps of g.
+func (SwapFinder) Automorphisms(g *Graph) [][]int {
+	colors := refine(g)
+	reps := make(map[int]int) // For each color, the first positive lit with this color
+	res := make([][]int, 0)
+	for lit := 0; lit < g.NbLits; lit++ {
+		rep, ok := reps[colors[lit]]
+		if !ok {
+			if lit%2 == 0 {
+				reps[colors[lit]] = lit
+			}
+			continue
+		}
+		if rep/2 == lit/2 {
+			continue
+		}
+		p := make([]int, len(g.Colors))
+		for i := range p {
+			p[i] = i
+		}
+		p[rep], p[lit] = lit, rep
+		p[rep^1], p[lit^1] = lit^1, rep^1
+		res = append(res, p)
+	}
+	return res
+}
+
+// refine returns the coarsest refinement of the colors of g such that nodes with the same color
+// have the same number of neighbors of each color. Automorphisms only map nodes to nodes with the same refined color.
+func refine(g *Graph) []int {
+	colors := append([]int(nil), g.Colors...)
+	nbColors := len(distinct(colors))
+	for {
+		next := make([]int, len(colors))
+		keys := make(map[string]int)
+		neighbors := make([]int, 0)
+		for node, c := range colors {
+			neighbors = neighbors[:0]
+			for _, n := range g.Edges[node] {
+				neighbors = append(neighbors, colors[n])
+			}
+			k := strconv.Itoa(c) + ":" + key(neighbors)
+			if _, ok := keys[k]; !ok {
+				keys[k] = len(keys)
+			}
+			next[node] = keys[k]
+		}
+		// Classes are only split, so they are stable once their number does not change
+		if len(keys) == nbColors {
+			return next
+		}
+		colors, nbColors = next, len(keys)
+	}
+}
+
+// distinct returns the set of the given ints.
+func distinct(ints []int) map[int]bool {
+	res := make(map[int]bool, len(ints))
+	for _, i := range ints {
+		res[i] = true
+	}
+	return res
+}
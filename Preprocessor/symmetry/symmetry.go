@@ -0,0 +1,238 @@
+// Package symmetry breaks the symmetries of preprocessor problems, as BreakID does:
+// symmetries are found as automorphisms of a colored graph of the problem,
+// and lex-leader symmetry-breaking clauses are added for each of them, so that a solver does not explore
+// symmetric parts of the search space. Symmetry-breaking clauses remove models, but keep at least one model
+// of each orbit, so the problem stays equisatisfiable, and its optimum is kept.
+package symmetry
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxSupport is the max number of vars of a symmetry that are ordered by its lex-leader constraint.
+// Longer constraints break more symmetric assignments, but need 3 clauses and an auxiliary var per var.
+var MaxSupport = 50
+
+// A Graph is the colored graph of a problem. Node 2v is the positive lit of var v, node 2v+1 its negative lit,
+// i.e lits are their own node, and the following nodes are the clauses. Each lit is linked to its negation
+// and to the clauses it appears in.
+// Lits get color 0 and clauses color 1. Lits bound by units get color 2 if they are true, 3 if they are false,
+// and cost lits a color for each of their weights, starting at 4, so that symmetries preserve units and the cost function.
+type Graph struct {
+	NbLits int     // Number of lit nodes, i.e twice the number of vars.
+	Colors []int   // Color of each node.
+	Edges  [][]int // For each node, its neighbors.
+}
+
+// A Finder finds automorphisms of a colored graph, e.g by calling a tool such as saucy or bliss.
+type Finder interface {
+	// Automorphisms returns automorphisms of g, as permutations of its nodes: p[i] is the image of node i.
+	// Only the images of lit nodes are used, and they are checked against the problem,
+	// so a Finder may return candidates that are not automorphisms.
+	Automorphisms(g *Graph) [][]int
+}
+
+// NewGraph returns the colored graph of pb.
+func NewGraph(pb *Preprocessor.Problem) *Graph {
+	nbLits := 2 * pb.NbVars
+	g := &Graph{
+		NbLits: nbLits,
+		Colors: make([]int, nbLits+len(pb.Clauses)),
+		Edges:  make([][]int, nbLits+len(pb.Clauses)),
+	}
+	for i := 0; i < nbLits; i += 2 {
+		g.Edges[i] = append(g.Edges[i], i+1)
+		g.Edges[i+1] = append(g.Edges[i+1], i)
+	}
+	for i, c := range pb.Clauses {
+		node := nbLits + i
+		g.Colors[node] = 1
+		for j := 0; j < c.Len(); j++ {
+			lit := int(c.Get(j))
+			g.Edges[node] = append(g.Edges[node], lit)
+			g.Edges[lit] = append(g.Edges[lit], node)
+		}
+	}
+	for v, val := range pb.Model {
+		if val == 1 {
+			g.Colors[2*v], g.Colors[2*v+1] = 2, 3
+		} else if val == -1 {
+			g.Colors[2*v], g.Colors[2*v+1] = 3, 2
+		}
+	}
+	lits, weights := pb.CostLits()
+	colors := make(map[int]int)
+	for i, lit := range lits {
+		w := 1
+		if weights != nil {
+			w = weights[i]
+		}
+		if _, ok := colors[w]; !ok {
+			colors[w] = 4 + len(colors)
+		}
+		g.Colors[lit] = colors[w]
+	}
+	return g
+}
+
+// Break finds the symmetries of pb with finder, or with a SwapFinder if finder is nil,
+// and adds lex-leader symmetry-breaking clauses for each of them. It returns the number of broken symmetries.
+// Symmetry-breaking clauses are not implied by the problem, so they cannot be added while an LRAT proof is written.
+func Break(pb *Preprocessor.Problem, finder Finder) (int, error) {
+	if pb.Options.LRAT != nil {
+		return 0, errors.New("symmetry-breaking clauses cannot be justified in an LRAT proof")
+	}
+	// Clauses must not contain lits bound by units
+	pb.Simplify2()
+	if pb.Status != Preprocessor.Undetermined {
+		return 0, nil
+	}
+	if finder == nil {
+		finder = SwapFinder{}
+	}
+	f := newFormula(pb)
+	nbBroken := 0
+	for _, p := range finder.Automorphisms(NewGraph(pb)) {
+		perm, ok := f.symmetry(p)
+		if !ok {
+			continue
+		}
+		addLexLeader(pb, perm)
+		nbBroken++
+	}
+	log.Printf("%d symmetries broken", nbBroken)
+	pb.Simplify2()
+	return nbBroken, nil
+}
+
+// A formula is the set of clauses of a problem, used to check that a permutation of its lits is a symmetry.
+type formula struct {
+	nbLits  int
+	clauses map[string]bool // Keys of the clauses.
+	occurs  [][]int         // For each lit, the clauses it appears in.
+	lits    [][]int         // Lits of each clause.
+	costs   map[int]int     // Weight of each cost lit.
+	bound   []bool          // For each var, whether it is bound by a unit.
+}
+
+func newFormula(pb *Preprocessor.Problem) *formula {
+	f := &formula{
+		nbLits:  2 * pb.NbVars,
+		clauses: make(map[string]bool, len(pb.Clauses)),
+		occurs:  make([][]int, 2*pb.NbVars),
+		lits:    make([][]int, len(pb.Clauses)),
+		costs:   make(map[int]int),
+		bound:   make([]bool, pb.NbVars),
+	}
+	for v, val := range pb.Model {
+		f.bound[v] = val != 0
+	}
+	for i, c := range pb.Clauses {
+		for j := 0; j < c.Len(); j++ {
+			lit := int(c.Get(j))
+			f.lits[i] = append(f.lits[i], lit)
+			f.occurs[lit] = append(f.occurs[lit], i)
+		}
+		f.clauses[key(f.lits[i])] = true
+	}
+	lits, weights := pb.CostLits()
+	for i, lit := range lits {
+		f.costs[int(lit)] = 1
+		if weights != nil {
+			f.costs[int(lit)] = weights[i]
+		}
+	}
+	return f
+}
+
+// key returns a string identifying the given multiset of ints, e.g the lits of a clause, in any order.
+func key(ints []int) string {
+	sorted := append([]int(nil), ints...)
+	sort.Ints(sorted)
+	var sb strings.Builder
+	for _, i := range sorted {
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// symmetry returns the permutation of lits given by the graph permutation p, and whether it is a symmetry of f:
+// it must commute with negation, fix the vars bound by units, keep the cost of assignments, and map clauses to clauses.
+func (f *formula) symmetry(p []int) ([]int, bool) {
+	if len(p) < f.nbLits {
+		return nil, false
+	}
+	perm := p[:f.nbLits]
+	moved := make(map[int]bool)
+	isImage := make([]bool, f.nbLits)
+	for lit, img := range perm {
+		if img < 0 || img >= f.nbLits || isImage[img] || perm[lit^1] != img^1 || f.costs[lit] != f.costs[img] {
+			return nil, false
+		}
+		isImage[img] = true
+		if img != lit {
+			if f.bound[lit/2] {
+				return nil, false
+			}
+			for _, i := range f.occurs[lit] {
+				moved[i] = true
+			}
+		}
+	}
+	if len(moved) == 0 {
+		return nil, false
+	}
+	// perm is a bijection, so if it maps each clause to a clause, it is a bijection of the set of clauses
+	img := make([]int, 0)
+	for i := range moved {
+		img = img[:0]
+		for _, lit := range f.lits[i] {
+			img = append(img, perm[lit])
+		}
+		if !f.clauses[key(img)] {
+			return nil, false
+		}
+	}
+	return perm, true
+}
+
+// addLexLeader adds clauses forcing the assignment of the vars to be lexicographically smaller than,
+// or equal to, its image by perm, a symmetry of pb. Vars are ordered by index, false < true,
+// and only the first MaxSupport vars moved by perm are considered.
+// eq(i) means that the first i considered vars are equal to their image; eq(0) is true.
+// For the ith var x, with y its image, eq(i-1) implies x <= y, and eq(i) holds when eq(i-1) and x == y.
+func addLexLeader(pb *Preprocessor.Problem, perm []int) {
+	support := make([]Preprocessor.Lit, 0)
+	for lit := 0; lit < len(perm) && len(support) < MaxSupport; lit += 2 {
+		if perm[lit] != lit {
+			support = append(support, Preprocessor.Lit(lit))
+		}
+	}
+	var eq Preprocessor.Lit
+	first := true
+	for i, x := range support {
+		y := Preprocessor.Lit(perm[x])
+		// With eq(i-1) as a premise, written as the lit -eq(i-1), unless it is true
+		clause := func(lits ...Preprocessor.Lit) {
+			if !first {
+				lits = append(lits, eq.Negation())
+			}
+			pb.AddClause(lits)
+		}
+		clause(x.Negation(), y)
+		if y == x.Negation() || i == len(support)-1 {
+			// x and y always differ, or no var is left: the next vars are never compared
+			return
+		}
+		next := pb.NewVar().Lit()
+		clause(x.Negation(), next)
+		clause(y, next)
+		eq, first = next, false
+	}
+}
@@ -0,0 +1,56 @@
+package symmetry
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, cnf string) *Preprocessor.Problem {
+	pb, err := Preprocessor.ParseCNF(strings.NewReader(cnf))
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", cnf, err)
+	}
+	return pb
+}
+
+func TestBreak(t *testing.T) {
+	// 1 and 2 are interchangeable: of the models (1, -2) and (-1, 2), only the lex-leader (-1, 2) is kept.
+	pb := parse(t, "p cnf 2 2\n1 2 0\n-1 -2 0\n")
+	n, err := Break(pb, nil)
+	if err != nil {
+		t.Fatalf("could not break symmetries: %v", err)
+	}
+	if n == 0 {
+		t.Fatalf("expected symmetries to be broken")
+	}
+	if pb.Status == Preprocessor.Unsat {
+		t.Fatalf("problem became Unsat")
+	}
+	pb.AddClause([]Preprocessor.Lit{Preprocessor.IntToLit(1)})
+	pb.Simplify2()
+	if pb.Status != Preprocessor.Unsat {
+		t.Errorf("expected model (1, -2) to be removed")
+	}
+}
+
+func TestBreakCost(t *testing.T) {
+	// Swapping 1 and 2 changes the cost of models, so it is not a symmetry.
+	pb := parse(t, "p cnf 2 2\n1 2 0\n-1 -2 0\n")
+	pb.SetCostFunc([]Preprocessor.Lit{Preprocessor.IntToLit(1), Preprocessor.IntToLit(2)}, []int{1, 2})
+	if n, err := Break(pb, nil); err != nil || n != 0 {
+		t.Errorf("expected no symmetry to be broken, got %d, err %v", n, err)
+	}
+}
+
+func TestBreakLRAT(t *testing.T) {
+	var proof bytes.Buffer
+	pb, err := Preprocessor.ParseCNFWithOptions(strings.NewReader("p cnf 2 2\n1 2 0\n-1 -2 0\n"), Preprocessor.Options{LRAT: &proof})
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+	if _, err := Break(pb, nil); err == nil {
+		t.Errorf("expected an error while an LRAT proof is written")
+	}
+}
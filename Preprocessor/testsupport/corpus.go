@@ -0,0 +1,231 @@
+package testsupport
+
+// corpus holds the bundled cases, as golden files, see ParseCase.
+var corpus = []struct{ name, cnf string }{
+	{"units", `c A chain of implications from a unit: propagation alone solves it.
+c expect sat
+c expect decided
+c expect units 1 2 3 -4
+p cnf 4 4
+1 0
+-1 2 0
+-2 3 0
+-3 -4 0
+`},
+	{"conflict", `c Every assignment of two vars is forbidden.
+c expect unsat
+c expect decided
+p cnf 2 4
+1 2 0
+1 -2 0
+-1 2 0
+-1 -2 0
+`},
+	{"failed-lit", `c 1 implies both 2 and -2, so -1 is a unit: probing, or the elimination of 1, solves the problem.
+c expect sat
+c expect decided
+p cnf 5 5
+-1 2 0
+-1 -2 0
+1 3 4 0
+-3 5 0
+-4 -5 0
+`},
+	{"subsumption", `c 1 2 subsumes 1 2 3 and 1 2 -4, and self-subsumes -1 2 into 2.
+c expect sat
+c expect units 2
+p cnf 5 6
+1 2 0
+1 2 3 0
+1 2 -4 0
+-1 2 0
+-2 3 5 0
+-3 4 -5 0
+`},
+	{"equivalences", `c 1, 2 and 3 are equivalent, 4 is their negation.
+c expect sat
+c expect maxclauses 2
+p cnf 6 10
+-1 2 0
+1 -2 0
+-2 3 0
+2 -3 0
+3 4 0
+-3 -4 0
+1 5 6 0
+-2 -5 6 0
+4 -6 5 0
+-1 -5 -6 0
+`},
+	{"and-gate", `c 3 is defined as 1 and 2: eliminating it leaves the clauses of its uses.
+c expect sat
+c expect maxclauses 3
+p cnf 5 6
+-3 1 0
+-3 2 0
+3 -1 -2 0
+3 4 0
+-3 5 0
+-4 -5 1 0
+`},
+	{"pigeonhole-4-3", `c 4 pigeons cannot sit in 3 holes. Var 3(p-1)+h is pigeon p in hole h.
+c expect unsat
+c expect maxclauses 18
+p cnf 12 22
+1 2 3 0
+4 5 6 0
+7 8 9 0
+10 11 12 0
+-1 -4 0
+-1 -7 0
+-1 -10 0
+-4 -7 0
+-4 -10 0
+-7 -10 0
+-2 -5 0
+-2 -8 0
+-2 -11 0
+-5 -8 0
+-5 -11 0
+-8 -11 0
+-3 -6 0
+-3 -9 0
+-3 -12 0
+-6 -9 0
+-6 -12 0
+-9 -12 0
+`},
+	{"xor-chain", `c 5 = 1 xor 2 and 6 = 3 xor 4, so 5 xor 6 = 1 xor 2 xor 3 xor 4, which must both be odd and even.
+c expect unsat
+c expect maxclauses 16
+p cnf 6 18
+-1 -2 -5 0
+1 2 -5 0
+1 -2 5 0
+-1 2 5 0
+-3 -4 -6 0
+3 4 -6 0
+3 -4 6 0
+-3 4 6 0
+5 6 0
+-5 -6 0
+1 2 3 -4 0
+1 2 -3 4 0
+1 -2 3 4 0
+1 -2 -3 -4 0
+-1 2 3 4 0
+-1 2 -3 -4 0
+-1 -2 3 -4 0
+-1 -2 -3 4 0
+`},
+	{"random-3sat-25", `c A random 3-SAT problem with 25 vars and 105 clauses.
+c expect sat
+c expect maxclauses 95
+p cnf 25 105
+-19 -7 -20 0
+16 3 -25 0
+-9 -4 16 0
+21 6 -8 0
+-6 -7 15 0
+-5 -11 17 0
+-4 6 13 0
+-14 -11 -7 0
+-13 -3 -20 0
+-23 -1 10 0
+3 -22 5 0
+-8 1 21 0
+14 -22 -19 0
+23 4 -12 0
+-24 -9 3 0
+8 -20 10 0
+8 -14 4 0
+-13 -14 17 0
+25 16 3 0
+21 22 19 0
+25 7 24 0
+21 -1 11 0
+-3 8 9 0
+-2 -6 -25 0
+-2 13 17 0
+2 22 -5 0
+-14 -17 -3 0
+6 -5 -17 0
+8 17 -7 0
+16 -1 21 0
+1 -6 3 0
+21 24 5 0
+-25 3 -6 0
+2 -11 25 0
+10 -14 1 0
+-1 -20 -8 0
+-11 5 15 0
+-20 -8 24 0
+-18 25 -2 0
+-17 10 -1 0
+-7 14 -10 0
+-23 -18 17 0
+1 3 19 0
+2 -12 25 0
+-18 -7 -13 0
+-4 9 -22 0
+24 19 -10 0
+-19 -6 22 0
+6 7 3 0
+-13 -24 2 0
+10 -24 7 0
+7 -4 22 0
+9 -14 -25 0
+5 4 -6 0
+4 24 -22 0
+-15 -7 19 0
+-25 -5 -8 0
+12 -13 -20 0
+-9 -2 16 0
+18 22 -25 0
+18 -17 19 0
+3 -4 7 0
+-23 10 -5 0
+-6 4 -8 0
+-4 19 15 0
+-23 17 -6 0
+-9 14 24 0
+-19 14 4 0
+-5 14 3 0
+16 24 -18 0
+-21 6 -10 0
+-7 -18 -19 0
+-18 -19 8 0
+-3 7 -10 0
+20 -18 -14 0
+13 1 -2 0
+-18 -20 -7 0
+-3 21 11 0
+-24 25 22 0
+7 22 -20 0
+-22 -18 8 0
+18 -13 -3 0
+-10 -8 -22 0
+-2 6 13 0
+-1 2 22 0
+-8 15 -11 0
+-6 19 -15 0
+10 1 -13 0
+13 18 -14 0
+-18 7 -1 0
+2 -24 -9 0
+7 -18 13 0
+-20 9 24 0
+7 13 -22 0
+-18 24 -2 0
+-9 6 16 0
+-16 -9 -25 0
+-6 -19 -20 0
+22 18 -17 0
+-13 -9 10 0
+16 -24 -12 0
+-21 22 -18 0
+21 16 5 0
+-23 -13 21 0
+-6 16 -1 0
+`},
+}
@@ -0,0 +1,186 @@
+// Package testsupport checks preprocessing against a corpus of small CNF problems, each with properties
+// preprocessing must give it, so that forks modifying passes can check they did not break soundness, e.g:
+//
+//	func TestCorpus(t *testing.T) {
+//		testsupport.Run(t, testsupport.Corpus(), nil)
+//	}
+//
+// Each case is a golden file: a DIMACS CNF file whose comments tell what is expected of it, one property per line:
+//
+//	c expect sat            The problem is satisfiable, "unsat" if it is not. Required.
+//	c expect decided        Preprocessing must find the problem Sat or Unsat by itself.
+//	c expect units 1 -4     Preprocessing must find these units, unless it finds the problem Unsat.
+//	c expect maxclauses 3   At most 3 clauses, units excluded, are left after preprocessing.
+//
+// Whatever is expected, the simplified problem must keep the invariants of problems, see CheckInvariants,
+// and be a sound simplification of the original one, as checked by CheckEquisat with a gini solver.
+// Clause counts are upper bounds, so that passes can get stronger without breaking the corpus.
+package testsupport
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"GiniBench/Preprocessor/giniconv"
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jaredsofteng/gini"
+)
+
+// A Case is a problem of the corpus, with what preprocessing must give it.
+type Case struct {
+	Name       string
+	CNF        string             // The problem, in the DIMACS CNF format.
+	Sat        bool               // Whether the problem is satisfiable.
+	Decided    bool               // Whether preprocessing must find the problem Sat or Unsat.
+	Units      []Preprocessor.Lit // Units preprocessing must find.
+	MaxClauses int                // Max number of clauses left after preprocessing, units excluded, -1 if there is none.
+}
+
+// Corpus returns the bundled cases.
+func Corpus() []Case {
+	cases := make([]Case, len(corpus))
+	for i, entry := range corpus {
+		c, err := ParseCase(entry.name, strings.NewReader(entry.cnf))
+		if err != nil {
+			panic(err)
+		}
+		cases[i] = c
+	}
+	return cases
+}
+
+// ParseCase reads a golden file, see above, and returns its case, with the given name.
+func ParseCase(name string, r io.Reader) (Case, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Case{}, fmt.Errorf("could not read case %s: %v", name, err)
+	}
+	c := Case{Name: name, CNF: string(data), MaxClauses: -1}
+	status := ""
+	sc := bufio.NewScanner(strings.NewReader(c.CNF))
+	for lineNb := 1; sc.Scan(); lineNb++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 || fields[0] != "c" || fields[1] != "expect" {
+			continue
+		}
+		switch fields[2] {
+		case "sat", "unsat":
+			if status != "" {
+				return Case{}, fmt.Errorf("case %s: line %d: status given twice", name, lineNb)
+			}
+			status = fields[2]
+			c.Sat = status == "sat"
+		case "decided":
+			c.Decided = true
+		case "units":
+			for _, field := range fields[3:] {
+				val, err := strconv.Atoi(field)
+				if err != nil || val == 0 || val > Preprocessor.MaxVar || val < -Preprocessor.MaxVar {
+					return Case{}, fmt.Errorf("case %s: line %d: invalid unit %q", name, lineNb, field)
+				}
+				c.Units = append(c.Units, Preprocessor.IntToLit(Preprocessor.LitInt(val)))
+			}
+		case "maxclauses":
+			if len(fields) != 4 {
+				return Case{}, fmt.Errorf("case %s: line %d: expected a single number of clauses", name, lineNb)
+			}
+			if c.MaxClauses, err = strconv.Atoi(fields[3]); err != nil || c.MaxClauses < 0 {
+				return Case{}, fmt.Errorf("case %s: line %d: invalid number of clauses %q", name, lineNb, fields[3])
+			}
+		default:
+			return Case{}, fmt.Errorf("case %s: line %d: unknown property %q", name, lineNb, fields[2])
+		}
+	}
+	if status == "" {
+		return Case{}, fmt.Errorf("case %s: no \"c expect sat\" or \"c expect unsat\" line", name)
+	}
+	return c, nil
+}
+
+// LoadDir returns the cases of the golden files of dir, i.e its .cnf files, named after them, sorted by name.
+func LoadDir(dir string) ([]Case, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".cnf" {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		c, err := ParseCase(strings.TrimSuffix(info.Name(), ".cnf"), f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Check parses the problem of the case, preprocesses it with preprocess, or with Preprocess if preprocess is nil,
+// and returns an error describing the first expectation the simplified problem does not meet, if any.
+func (c Case) Check(preprocess func(pb *Preprocessor.Problem)) error {
+	pb, err := Preprocessor.ParseCNF(strings.NewReader(c.CNF))
+	if err != nil {
+		return fmt.Errorf("case %s: could not parse problem: %v", c.Name, err)
+	}
+	original := pb.Clone()
+	g := gini.New()
+	giniconv.ToGini(original, g)
+	if sat := g.Solve() == 1; sat != c.Sat {
+		return fmt.Errorf("case %s: expected satisfiable to be %t, solver says %t", c.Name, c.Sat, sat)
+	}
+	if preprocess == nil {
+		preprocess = func(pb *Preprocessor.Problem) { pb.Preprocess() }
+	}
+	preprocess(pb)
+	if err := pb.CheckInvariants(); err != nil {
+		return fmt.Errorf("case %s: invariant broken by preprocessing: %v", c.Name, err)
+	}
+	if err := Preprocessor.CheckEquisat(original, pb, giniconv.NewSolver()); err != nil {
+		return fmt.Errorf("case %s: %v", c.Name, err)
+	}
+	if c.Decided && pb.Status == Preprocessor.Undetermined {
+		return fmt.Errorf("case %s: problem was not decided by preprocessing", c.Name)
+	}
+	if pb.Status == Preprocessor.Unsat {
+		return nil
+	}
+	units := make(map[Preprocessor.Lit]bool, len(pb.Units))
+	for _, lit := range pb.Units {
+		units[lit] = true
+	}
+	for _, lit := range c.Units {
+		if !units[lit] {
+			return fmt.Errorf("case %s: unit %d was not found", c.Name, lit.Int())
+		}
+	}
+	if c.MaxClauses >= 0 && len(pb.Clauses) > c.MaxClauses {
+		return fmt.Errorf("case %s: %d clauses left, expected at most %d", c.Name, len(pb.Clauses), c.MaxClauses)
+	}
+	return nil
+}
+
+// Run checks each case in a subtest of t, named after it, see Check.
+func Run(t *testing.T, cases []Case, preprocess func(pb *Preprocessor.Problem)) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Check(preprocess); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package testsupport
+
+import (
+	"GiniBench/Preprocessor/Preprocessor"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	Run(t, Corpus(), nil)
+}
+
+func TestCorpusFixpoint(t *testing.T) {
+	Run(t, Corpus(), func(pb *Preprocessor.Problem) { pb.Fixpoint() })
+}
+
+func TestParseCase(t *testing.T) {
+	c, err := ParseCase("c", strings.NewReader("c expect unsat\nc expect decided\nc expect units 1 -2\nc expect maxclauses 3\np cnf 2 1\n1 0\n"))
+	if err != nil {
+		t.Fatalf("could not parse case: %v", err)
+	}
+	if c.Sat || !c.Decided || len(c.Units) != 2 || c.Units[1].Int() != -2 || c.MaxClauses != 3 {
+		t.Errorf("invalid case %+v", c)
+	}
+	for _, cnf := range []string{
+		"p cnf 1 1\n1 0\n", // No status
+		"c expect sat\nc expect unsat\np cnf 1 1\n1 0\n", // Two statuses
+		"c expect sat\nc expect units 0\np cnf 1 1\n1 0\n",
+		"c expect sat\nc expect maxclauses\np cnf 1 1\n1 0\n",
+		"c expect sat\nc expect nothing\np cnf 1 1\n1 0\n",
+	} {
+		if _, err := ParseCase("c", strings.NewReader(cnf)); err == nil {
+			t.Errorf("expected an error for %q", cnf)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corpus")
+	if err != nil {
+		t.Fatalf("could not create dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"b.cnf":     "c expect sat\np cnf 1 1\n1 0\n",
+		"a.cnf":     "c expect unsat\np cnf 1 2\n1 0\n-1 0\n",
+		"notes.txt": "c expect nothing\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+	cases, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("could not load cases: %v", err)
+	}
+	if len(cases) != 2 || cases[0].Name != "a" || cases[1].Name != "b" {
+		t.Fatalf("invalid cases %+v", cases)
+	}
+	Run(t, cases, nil)
+}
+
+func TestCheckUnsound(t *testing.T) {
+	c := Corpus()[0]
+	// Adding the negation of a unit makes the problem Unsat, although it is Sat.
+	err := c.Check(func(pb *Preprocessor.Problem) {
+		pb.Preprocess()
+		pb.AddClause([]Preprocessor.Lit{Preprocessor.IntToLit(-1)})
+	})
+	if err == nil {
+		t.Errorf("unsound preprocessing was not detected")
+	}
+	// Doing nothing does not find the unit 2 of the subsumption case.
+	for _, c := range Corpus() {
+		if c.Name == "subsumption" {
+			if err := c.Check(func(pb *Preprocessor.Problem) {}); err == nil {
+				t.Errorf("missing unit was not detected")
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/StackExchange/wmi v0.0.0-20190523213315-cbe66965904d // indirect
+	github.com/crillab/gophersat v1.4.0
 	github.com/go-ole/go-ole v1.2.4 // indirect
 	github.com/gotk3/gotk3 v0.4.0 // indirect
 	github.com/jaredsofteng/gini v1.0.3